@@ -2,52 +2,561 @@ package ast
 
 import (
 	"fmt"
-	"lox/expressions"
+	stm "lox/statement"
+	"lox/tokens"
 	"strings"
 )
 
-type Printer struct{}
-
-// VisitBinaryExpr implements expressions.Visitor.
-func (p *Printer) VisitBinaryExpr(expr expressions.Binary) any {
-	return p.parenthesize(expr.Operator.Lexeme, expr.Left, expr.Right)
+type Printer struct {
+	depth int
 }
 
 func NewPrinter() *Printer {
 	return &Printer{}
 }
 
-// VisitGroupingExpr implements expressions.Visitor.
-func (p *Printer) VisitGroupingExpr(expr expressions.Grouping) any {
+func (p *Printer) Print(expr stm.Expression) string {
+	return expr.Accept(p).(string)
+}
+
+func (p *Printer) PrintProgram(statements []stm.Statement) string {
+	var builder strings.Builder
+
+	for _, stmt := range statements {
+		builder.WriteString(p.stmt(stmt))
+		builder.WriteRune('\n')
+	}
+
+	return builder.String()
+}
+
+func (p *Printer) stmt(stmt stm.Statement) string {
+	return stmt.Accept(p).(string)
+}
+
+// VisitBinaryExpr implements stm.ExprVisitor.
+func (p *Printer) VisitBinaryExpr(expr *stm.Binary) any {
+	return p.parenthesize(expr.Operator.Lexeme, expr.Left, expr.Right)
+}
+
+// VisitGroupingExpr implements stm.ExprVisitor.
+func (p *Printer) VisitGroupingExpr(expr *stm.Grouping) any {
 	return p.parenthesize("group", expr.Expression)
 }
 
-// VisitLiteralExpr implements expressions.Visitor.
-func (p *Printer) VisitLiteralExpr(expr expressions.Literal) any {
-	if expr.Value == nil {
+// VisitLiteralExpr implements stm.ExprVisitor.
+func (p *Printer) VisitLiteralExpr(expr *stm.Literal) any {
+	switch value := expr.Value.(type) {
+	case nil:
 		return "nil"
+	case string:
+		return fmt.Sprintf("%q", value)
 	}
 	return fmt.Sprintf("%v", expr.Value)
 }
 
-func (p *Printer) VisitErrorExpr(expr expressions.Error) any {
-	return expr.Value
+// VisitErrorExpr implements stm.ExprVisitor.
+func (p *Printer) VisitErrorExpr(expr *stm.Error) any {
+	return fmt.Sprintf("(error %q)", expr.Value)
 }
 
-// VisitUnaryExpr implements expressions.Visitor.
-func (p *Printer) VisitUnaryExpr(expr expressions.Unary) any {
+// VisitUnaryExpr implements stm.ExprVisitor.
+func (p *Printer) VisitUnaryExpr(expr *stm.Unary) any {
 	return p.parenthesize(expr.Operator.Lexeme, expr.Right)
 }
 
-func (p *Printer) Print(expr expressions.Expression) string {
-	return expr.Accept(p).(string)
+// VisitTernaryExpr implements stm.ExprVisitor.
+func (p *Printer) VisitTernaryExpr(expr *stm.Ternary) any {
+	return p.parenthesize("?:", expr.Condition, expr.Consequent, expr.Alternative)
+}
+
+// VisitVariableExpr implements stm.ExprVisitor.
+func (p *Printer) VisitVariableExpr(expr *stm.Variable) any {
+	return expr.Name.Lexeme
+}
+
+// VisitAssignExpr implements stm.ExprVisitor.
+func (p *Printer) VisitAssignExpr(expr *stm.Assign) any {
+	return p.parenthesize(assignment(expr.Operator)+" "+expr.Name.Lexeme, expr.Value)
+}
+
+// VisitAssignPatternExpr implements stm.ExprVisitor.
+func (p *Printer) VisitAssignPatternExpr(expr *stm.AssignPattern) any {
+	return p.parenthesize("= "+p.pattern(expr.Pattern), expr.Value)
+}
+
+// VisitLogicalExpr implements stm.ExprVisitor.
+func (p *Printer) VisitLogicalExpr(expr *stm.Logical) any {
+	return p.parenthesize(expr.Operator.Lexeme, expr.Left, expr.Right)
+}
+
+// VisitCallExpr implements stm.ExprVisitor.
+func (p *Printer) VisitCallExpr(expr *stm.Call) any {
+	name := "call"
+
+	if expr.Tail {
+		name = "tailcall"
+	}
+
+	positional := len(expr.Arguments) - len(expr.Names)
+	call := p.parenthesize(name, append([]stm.Expression{expr.Callee}, expr.Arguments[:positional]...)...)
+
+	for i, argument := range expr.Arguments[positional:] {
+		call = call[:len(call)-1] + " " + expr.Names[i].Lexeme + ": " + argument.Accept(p).(string) + ")"
+	}
+
+	return call
+}
+
+// VisitGetExpr implements stm.ExprVisitor.
+func (p *Printer) VisitGetExpr(expr *stm.Get) any {
+	return p.parenthesize("."+expr.Name.Lexeme, expr.Object)
+}
+
+// VisitListExpr implements stm.ExprVisitor.
+func (p *Printer) VisitListExpr(expr *stm.List) any {
+	return p.parenthesize("list", expr.Elements...)
+}
+
+// VisitIndexExpr implements stm.ExprVisitor.
+func (p *Printer) VisitIndexExpr(expr *stm.Index) any {
+	return p.parenthesize("[]", expr.Object, expr.Index)
+}
+
+// VisitMatchExpr implements stm.ExprVisitor.
+func (p *Printer) VisitMatchExpr(expr *stm.Match) any {
+	cases := make([]string, len(expr.Cases))
+
+	for i, matchCase := range expr.Cases {
+		cases[i] = p.matchCase(matchCase, p.Print(matchCase.Value))
+	}
+
+	return "(match " + p.Print(expr.Subject) + " " + strings.Join(cases, " ") + ")"
+}
+
+// VisitAwaitExpr implements stm.ExprVisitor.
+func (p *Printer) VisitAwaitExpr(expr *stm.Await) any {
+	return p.parenthesize("await", expr.Value)
+}
+
+// VisitSetExpr implements stm.ExprVisitor.
+func (p *Printer) VisitSetExpr(expr *stm.Set) any {
+	return p.parenthesize(assignment(expr.Operator)+" ."+expr.Name.Lexeme, expr.Object, expr.Value)
+}
+
+// assignment returns "=" or, for a compound assignment, "+=" and the like.
+func assignment(operator *tokens.Token) string {
+	if operator == nil {
+		return "="
+	}
+	return operator.Lexeme + "="
+}
+
+// VisitThisExpr implements stm.ExprVisitor.
+func (p *Printer) VisitThisExpr(expr *stm.This) any {
+	return "this"
+}
+
+// VisitSuperExpr implements stm.ExprVisitor.
+func (p *Printer) VisitSuperExpr(expr *stm.Super) any {
+	return "super." + expr.Method.Lexeme
+}
+
+// VisitAnonymousFuncExpr implements stm.ExprVisitor.
+func (p *Printer) VisitAnonymousFuncExpr(expr *stm.AnonymousFunction) any {
+	return p.function("fun", expr.Parameters, expr.Body)
+}
+
+// VisitExprStatement implements stm.StmVisitor.
+func (p *Printer) VisitExprStatement(stmt *stm.ExpressionStmt) any {
+	return p.parenthesize(";", stmt.Expression)
+}
+
+// VisitPrintStatement implements stm.StmVisitor.
+func (p *Printer) VisitPrintStatement(stmt *stm.PrintStmt) any {
+	return p.parenthesize("print", stmt.Expression)
+}
+
+// VisitVarStatement implements stm.StmVisitor.
+func (p *Printer) VisitVarStatement(stmt *stm.VarStmt) any {
+	keyword := "var"
+
+	if stmt.Const {
+		keyword = "const"
+	}
+
+	if stmt.Initializer == nil {
+		return "(" + keyword + " " + stmt.Name.Lexeme + ")"
+	}
+	return p.parenthesize(keyword+" "+stmt.Name.Lexeme, stmt.Initializer)
+}
+
+// VisitVarPatternStatement implements stm.StmVisitor.
+func (p *Printer) VisitVarPatternStatement(stmt *stm.VarPatternStmt) any {
+	keyword := "var"
+
+	if stmt.Const {
+		keyword = "const"
+	}
+
+	return p.parenthesize(keyword+" "+p.pattern(stmt.Pattern), stmt.Initializer)
+}
+
+// VisitErrorStatement implements stm.StmVisitor.
+func (p *Printer) VisitErrorStatement(stmt *stm.ErrorStmt) any {
+	return fmt.Sprintf("(error %q)", stmt.Message)
+}
+
+// VisitBlockStatement implements stm.StmVisitor.
+func (p *Printer) VisitBlockStatement(stmt *stm.BlockStmt) any {
+	return "(block" + p.body(stmt.Statements) + ")"
+}
+
+// VisitIfStatement implements stm.StmVisitor.
+func (p *Printer) VisitIfStatement(stmt *stm.IfStmt) any {
+	text := "(if " + p.Print(stmt.Condition) + " " + p.stmt(stmt.ThenBranch)
+
+	if stmt.ElseBranch != nil {
+		text += " " + p.stmt(stmt.ElseBranch)
+	}
+	return text + ")"
+}
+
+// VisitWhileStatement implements stm.StmVisitor.
+func (p *Printer) VisitWhileStatement(stmt *stm.WhileStmt) any {
+	return "(while " + p.Print(stmt.Condition) + " " + p.stmt(stmt.Body) + ")"
+}
+
+// VisitForInStatement implements stm.StmVisitor.
+func (p *Printer) VisitForInStatement(stmt *stm.ForInStmt) any {
+	names := make([]string, len(stmt.Names))
+
+	for i, name := range stmt.Names {
+		names[i] = name.Lexeme
+	}
+
+	return "(for-in (" + strings.Join(names, " ") + ") " + p.Print(stmt.Iterable) + " " + p.stmt(stmt.Body) + ")"
+}
+
+// VisitYieldStatement implements stm.StmVisitor.
+func (p *Printer) VisitYieldStatement(stmt *stm.YieldStmt) any {
+	return p.parenthesize("yield", stmt.Value)
+}
+
+// VisitSpawnStatement implements stm.StmVisitor.
+func (p *Printer) VisitSpawnStatement(stmt *stm.SpawnStmt) any {
+	return p.parenthesize("spawn", stmt.Call)
+}
+
+// VisitSelectStatement implements stm.StmVisitor.
+func (p *Printer) VisitSelectStatement(stmt *stm.SelectStmt) any {
+	cases := make([]string, 0, len(stmt.Cases)+1)
+
+	for _, selectCase := range stmt.Cases {
+		if selectCase.Value != nil {
+			cases = append(cases, "(send "+p.Print(selectCase.Channel)+" "+p.Print(selectCase.Value)+" "+p.stmt(selectCase.Body)+")")
+		} else if selectCase.Name != nil {
+			cases = append(cases, "(recv "+p.Print(selectCase.Channel)+" "+selectCase.Name.Lexeme+" "+p.stmt(selectCase.Body)+")")
+		} else {
+			cases = append(cases, "(recv "+p.Print(selectCase.Channel)+" "+p.stmt(selectCase.Body)+")")
+		}
+	}
+
+	if stmt.Default != nil {
+		cases = append(cases, "(default "+p.stmt(stmt.Default)+")")
+	}
+
+	return "(select " + strings.Join(cases, " ") + ")"
+}
+
+// VisitMatchStatement implements stm.StmVisitor.
+func (p *Printer) VisitMatchStatement(stmt *stm.MatchStmt) any {
+	cases := make([]string, len(stmt.Cases))
+
+	for i, matchCase := range stmt.Cases {
+		cases[i] = p.matchCase(matchCase, p.stmt(matchCase.Body))
+	}
+
+	return "(match " + p.Print(stmt.Subject) + " " + strings.Join(cases, " ") + ")"
+}
+
+func (p *Printer) matchCase(matchCase *stm.MatchCase, result string) string {
+	if matchCase.Guard != nil {
+		return "(case " + p.pattern(matchCase.Pattern) + " (when " + p.Print(matchCase.Guard) + ") " + result + ")"
+	}
+	return "(case " + p.pattern(matchCase.Pattern) + " " + result + ")"
+}
+
+func (p *Printer) pattern(pattern stm.Pattern) string {
+	switch pattern := pattern.(type) {
+	case *stm.LiteralPattern:
+		return p.Print(stm.NewLiteral(pattern.Value))
+	case *stm.WildcardPattern:
+		return "_"
+	case *stm.BindingPattern:
+		return pattern.Name.Lexeme
+	case *stm.ValuePattern:
+		return p.Print(pattern.Value)
+	case *stm.ListPattern:
+		elements := p.patterns(pattern.Elements)
+
+		if pattern.Rest != nil {
+			elements = append(elements, "..."+p.pattern(pattern.Rest))
+		}
+
+		return "[" + strings.Join(elements, " ") + "]"
+	case *stm.MapPattern:
+		entries := make([]string, len(pattern.Keys))
+
+		for i, key := range pattern.Keys {
+			entries[i] = p.Print(stm.NewLiteral(key)) + ": " + p.pattern(pattern.Values[i])
+		}
+
+		return "{" + strings.Join(entries, " ") + "}"
+	case *stm.ClassPattern:
+		fields := p.patterns(pattern.Positional)
+
+		for i, name := range pattern.Names {
+			fields = append(fields, name.Lexeme+": "+p.pattern(pattern.Named[i]))
+		}
+
+		return "(" + strings.Join(append([]string{p.Print(pattern.Class)}, fields...), " ") + ")"
+	}
+
+	return "?"
+}
+
+func (p *Printer) patterns(patterns []stm.Pattern) []string {
+	printed := make([]string, len(patterns))
+
+	for i, pattern := range patterns {
+		printed[i] = p.pattern(pattern)
+	}
+
+	return printed
+}
+
+// VisitBreakStatement implements stm.StmVisitor.
+func (p *Printer) VisitBreakStatement(stmt *stm.BreakStmt) any {
+	return "(break)"
+}
+
+// VisitFunctionStatement implements stm.StmVisitor.
+func (p *Printer) VisitFunctionStatement(stmt *stm.FunctionStm) any {
+	if stmt.Generator {
+		return p.function("fun* "+stmt.Name.Lexeme, stmt.Parameters, stmt.Body)
+	}
+	if stmt.Async {
+		return p.function("async fun "+stmt.Name.Lexeme, stmt.Parameters, stmt.Body)
+	}
+	return p.function("fun "+stmt.Name.Lexeme, stmt.Parameters, stmt.Body)
+}
+
+// VisitReturnStatement implements stm.StmVisitor.
+func (p *Printer) VisitReturnStatement(stmt *stm.ReturnStmt) any {
+	if stmt.Value == nil {
+		return "(return)"
+	}
+	return p.parenthesize("return", stmt.Value)
+}
+
+// VisitClassStatement implements stm.StmVisitor.
+func (p *Printer) VisitClassStatement(stmt *stm.ClassStmt) any {
+	var builder strings.Builder
+
+	builder.WriteString("(class " + stmt.Name.Lexeme)
+
+	if stmt.SuperClass != nil {
+		builder.WriteString(" < " + stmt.SuperClass.Name.Lexeme)
+	}
+
+	if len(stmt.Traits) > 0 {
+		builder.WriteString(" with")
+
+		for _, trait := range stmt.Traits {
+			builder.WriteString(" " + trait.Name.Lexeme)
+		}
+	}
+
+	if len(stmt.Interfaces) > 0 {
+		builder.WriteString(" implements")
+
+		for _, iface := range stmt.Interfaces {
+			builder.WriteString(" " + iface.Name.Lexeme)
+		}
+	}
+
+	p.depth++
+
+	for _, field := range stmt.Fields {
+		builder.WriteString(p.newline() + p.stmt(field))
+	}
+
+	for _, field := range stmt.StaticFields {
+		builder.WriteString(p.newline() + "(static " + p.stmt(field) + ")")
+	}
+
+	for _, method := range stmt.Methods {
+		builder.WriteString(p.newline() + p.stmt(method))
+	}
+
+	for _, method := range stmt.Abstract {
+		builder.WriteString(p.newline() + "(abstract " + p.signature(method) + ")")
+	}
+
+	for _, method := range stmt.StaticMethods {
+		builder.WriteString(p.newline() + "(static " + p.stmt(method) + ")")
+	}
+
+	p.accessors(&builder, "get", stmt.Getters)
+	p.accessors(&builder, "set", stmt.Setters)
+	p.accessors(&builder, "static get", stmt.StaticGetters)
+	p.accessors(&builder, "static set", stmt.StaticSetters)
+
+	p.depth--
+
+	builder.WriteRune(')')
+
+	return builder.String()
+}
+
+// VisitTraitStatement implements stm.StmVisitor.
+func (p *Printer) VisitTraitStatement(stmt *stm.TraitStmt) any {
+	var builder strings.Builder
+
+	builder.WriteString("(trait " + stmt.Name.Lexeme)
+
+	p.depth++
+
+	for _, method := range stmt.Methods {
+		builder.WriteString(p.newline() + p.stmt(method))
+	}
+
+	p.depth--
+
+	builder.WriteRune(')')
+
+	return builder.String()
+}
+
+// VisitInterfaceStatement implements stm.StmVisitor.
+func (p *Printer) VisitInterfaceStatement(stmt *stm.InterfaceStmt) any {
+	var builder strings.Builder
+
+	builder.WriteString("(interface " + stmt.Name.Lexeme)
+
+	p.depth++
+
+	for _, method := range stmt.Methods {
+		builder.WriteString(p.newline() + p.signature(method))
+	}
+
+	p.depth--
+
+	builder.WriteRune(')')
+
+	return builder.String()
+}
+
+// VisitImportStatement implements stm.StmVisitor.
+func (p *Printer) VisitImportStatement(stmt *stm.ImportStmt) any {
+	if stmt.Alias != nil {
+		return fmt.Sprintf("(import %q as %s)", stmt.Path, stmt.Alias.Lexeme)
+	}
+
+	names := make([]string, len(stmt.Names))
+
+	for i, name := range stmt.Names {
+		names[i] = name.Lexeme
+	}
+
+	return fmt.Sprintf("(from %q import %s)", stmt.Path, strings.Join(names, " "))
+}
+
+// VisitExportStatement implements stm.StmVisitor.
+func (p *Printer) VisitExportStatement(stmt *stm.ExportStmt) any {
+	return "(export " + p.stmt(stmt.Declaration) + ")"
+}
+
+func (p *Printer) accessors(builder *strings.Builder, kind string, accessors []*stm.FunctionStm) {
+	for _, accessor := range accessors {
+		builder.WriteString(p.newline() + "(" + kind + " " + p.stmt(accessor) + ")")
+	}
+}
+
+// signature prints a method without a body, like "(area ())".
+// VisitEnumStatement implements stm.StmVisitor.
+func (p *Printer) VisitEnumStatement(stmt *stm.EnumStmt) any {
+	var builder strings.Builder
+
+	builder.WriteString("(enum " + stmt.Name.Lexeme)
+
+	p.depth++
+
+	for _, variant := range stmt.Variants {
+		params := make([]string, len(variant.Params))
+
+		for i, param := range variant.Params {
+			params[i] = param.Lexeme
+		}
+
+		builder.WriteString(p.newline() + "(" + variant.Name.Lexeme + " (" + strings.Join(params, " ") + "))")
+	}
+
+	p.depth--
+
+	builder.WriteRune(')')
+
+	return builder.String()
+}
+
+func (p *Printer) signature(method *stm.FunctionStm) string {
+	names := make([]string, len(method.Params))
+
+	for i, param := range method.Params {
+		names[i] = param.Lexeme
+	}
+
+	return "(" + method.Name.Lexeme + " (" + strings.Join(names, " ") + "))"
+}
+
+func (p *Printer) function(name string, parameters stm.Parameters, body []stm.Statement) string {
+	names := make([]string, len(parameters.Params))
+
+	for i, param := range parameters.Params {
+		names[i] = param.Lexeme
+
+		if parameters.Defaults[i] != nil {
+			names[i] = p.parenthesize("= "+param.Lexeme, parameters.Defaults[i])
+		}
+	}
+
+	if parameters.Variadic {
+		names[len(names)-1] = "..." + names[len(names)-1]
+	}
+
+	return "(" + name + " (" + strings.Join(names, " ") + ")" + p.body(body) + ")"
+}
+
+func (p *Printer) body(statements []stm.Statement) string {
+	var builder strings.Builder
+
+	p.depth++
+
+	for _, stmt := range statements {
+		builder.WriteString(p.newline() + p.stmt(stmt))
+	}
+
+	p.depth--
+
+	return builder.String()
 }
 
-func (p *Printer) VisitTernaryExpr(expr expressions.Ternary) any {
-	return p.parenthesize("ternary", expr.Condition, expr.Consequent, expr.Alternative)
+func (p *Printer) newline() string {
+	return "\n" + strings.Repeat("  ", p.depth)
 }
 
-func (p *Printer) parenthesize(name string, exprs ...expressions.Expression) string {
+func (p *Printer) parenthesize(name string, exprs ...stm.Expression) string {
 	var builder strings.Builder
 
 	builder.WriteRune('(')
@@ -0,0 +1,192 @@
+package bytecode
+
+type OpCode byte
+
+const (
+	OP_CONSTANT OpCode = iota
+	OP_NIL
+	OP_TRUE
+	OP_FALSE
+	OP_POP
+	OP_GET_LOCAL
+	OP_SET_LOCAL
+	OP_GET_GLOBAL
+	OP_DEFINE_GLOBAL
+	OP_SET_GLOBAL
+	OP_GET_UPVALUE
+	OP_SET_UPVALUE
+	OP_GET_PROPERTY
+	OP_SET_PROPERTY
+	OP_GET_SUPER
+	OP_EQUAL
+	OP_GREATER
+	OP_LESS
+	OP_ADD
+	OP_SUBTRACT
+	OP_MULTIPLY
+	OP_DIVIDE
+	OP_NOT
+	OP_NEGATE
+	OP_PRINT
+	OP_JUMP
+	OP_JUMP_IF_FALSE
+	OP_LOOP
+	OP_CALL
+	OP_INVOKE
+	OP_SUPER_INVOKE
+	OP_CLOSURE
+	OP_CLOSE_UPVALUE
+	OP_RETURN
+	OP_CLASS
+	OP_INHERIT
+	OP_METHOD
+	OP_STATIC_METHOD
+	OP_GREATER_EQUAL
+	OP_LESS_EQUAL
+	OP_NOT_EQUAL
+	OP_TAIL_CALL
+)
+
+var opNames = [...]string{
+	OP_CONSTANT:      "OP_CONSTANT",
+	OP_NIL:           "OP_NIL",
+	OP_TRUE:          "OP_TRUE",
+	OP_FALSE:         "OP_FALSE",
+	OP_POP:           "OP_POP",
+	OP_GET_LOCAL:     "OP_GET_LOCAL",
+	OP_SET_LOCAL:     "OP_SET_LOCAL",
+	OP_GET_GLOBAL:    "OP_GET_GLOBAL",
+	OP_DEFINE_GLOBAL: "OP_DEFINE_GLOBAL",
+	OP_SET_GLOBAL:    "OP_SET_GLOBAL",
+	OP_GET_UPVALUE:   "OP_GET_UPVALUE",
+	OP_SET_UPVALUE:   "OP_SET_UPVALUE",
+	OP_GET_PROPERTY:  "OP_GET_PROPERTY",
+	OP_SET_PROPERTY:  "OP_SET_PROPERTY",
+	OP_GET_SUPER:     "OP_GET_SUPER",
+	OP_EQUAL:         "OP_EQUAL",
+	OP_GREATER:       "OP_GREATER",
+	OP_LESS:          "OP_LESS",
+	OP_ADD:           "OP_ADD",
+	OP_SUBTRACT:      "OP_SUBTRACT",
+	OP_MULTIPLY:      "OP_MULTIPLY",
+	OP_DIVIDE:        "OP_DIVIDE",
+	OP_NOT:           "OP_NOT",
+	OP_NEGATE:        "OP_NEGATE",
+	OP_PRINT:         "OP_PRINT",
+	OP_JUMP:          "OP_JUMP",
+	OP_JUMP_IF_FALSE: "OP_JUMP_IF_FALSE",
+	OP_LOOP:          "OP_LOOP",
+	OP_CALL:          "OP_CALL",
+	OP_INVOKE:        "OP_INVOKE",
+	OP_SUPER_INVOKE:  "OP_SUPER_INVOKE",
+	OP_CLOSURE:       "OP_CLOSURE",
+	OP_CLOSE_UPVALUE: "OP_CLOSE_UPVALUE",
+	OP_RETURN:        "OP_RETURN",
+	OP_CLASS:         "OP_CLASS",
+	OP_INHERIT:       "OP_INHERIT",
+	OP_METHOD:        "OP_METHOD",
+	OP_STATIC_METHOD: "OP_STATIC_METHOD",
+	OP_GREATER_EQUAL: "OP_GREATER_EQUAL",
+	OP_LESS_EQUAL:    "OP_LESS_EQUAL",
+	OP_NOT_EQUAL:     "OP_NOT_EQUAL",
+	OP_TAIL_CALL:     "OP_TAIL_CALL",
+}
+
+// operandWidth is the number of bytes following op, not counting the
+// upvalue pairs of OP_CLOSURE. It is -1 for an unknown opcode.
+func (op OpCode) operandWidth() int {
+	switch op {
+	case OP_CONSTANT, OP_GET_GLOBAL, OP_DEFINE_GLOBAL, OP_SET_GLOBAL, OP_GET_PROPERTY, OP_SET_PROPERTY,
+		OP_GET_SUPER, OP_CLASS, OP_METHOD, OP_STATIC_METHOD, OP_JUMP, OP_JUMP_IF_FALSE, OP_LOOP, OP_CLOSURE:
+		return 2
+	case OP_GET_LOCAL, OP_SET_LOCAL, OP_GET_UPVALUE, OP_SET_UPVALUE, OP_CALL, OP_TAIL_CALL:
+		return 1
+	case OP_INVOKE, OP_SUPER_INVOKE:
+		return 3
+	case OP_NIL, OP_TRUE, OP_FALSE, OP_POP, OP_EQUAL, OP_GREATER, OP_LESS,
+		OP_ADD, OP_SUBTRACT, OP_MULTIPLY, OP_DIVIDE, OP_NOT, OP_NEGATE,
+		OP_PRINT, OP_CLOSE_UPVALUE, OP_RETURN, OP_INHERIT,
+		OP_GREATER_EQUAL, OP_LESS_EQUAL, OP_NOT_EQUAL:
+		return 0
+	}
+	return -1
+}
+
+func (op OpCode) String() string {
+	if int(op) < len(opNames) {
+		return opNames[op]
+	}
+	return "OP_UNKNOWN"
+}
+
+// Chunk holds the code of a single function. Constants are float64, string,
+// bool, nil or *Function for nested function prototypes.
+type Chunk struct {
+	Code      []byte
+	Lines     []int
+	Constants []any
+}
+
+func NewChunk() *Chunk {
+	return &Chunk{
+		Code:      make([]byte, 0),
+		Lines:     make([]int, 0),
+		Constants: make([]any, 0),
+	}
+}
+
+func (c *Chunk) Write(b byte, line int) {
+	c.Code = append(c.Code, b)
+	c.Lines = append(c.Lines, line)
+}
+
+func (c *Chunk) WriteOp(op OpCode, line int) {
+	c.Write(byte(op), line)
+}
+
+func (c *Chunk) WriteShort(value uint16, line int) {
+	c.Write(byte(value>>8), line)
+	c.Write(byte(value), line)
+}
+
+func (c *Chunk) AddConstant(value any) int {
+	c.Constants = append(c.Constants, value)
+	return len(c.Constants) - 1
+}
+
+func (c *Chunk) ReadShort(offset int) uint16 {
+	return uint16(c.Code[offset])<<8 | uint16(c.Code[offset+1])
+}
+
+// Function is a compiled function prototype. The top-level script is a
+// Function with an empty name, and so is a lambda, which has Anonymous set.
+// SlotCount counts the receiver slot, the parameters and every local; the
+// chunk starts by pushing nil for each local beyond the parameters.
+type Function struct {
+	Name         string
+	Arity        int
+	SlotCount    int
+	UpvalueCount int
+	Anonymous    bool
+	Chunk        *Chunk
+}
+
+func NewFunction(name string, arity int) *Function {
+	return &Function{
+		Name:      name,
+		Arity:     arity,
+		SlotCount: 1 + arity,
+		Chunk:     NewChunk(),
+	}
+}
+
+func (f *Function) String() string {
+	if f.Anonymous {
+		return "< anonymous function >"
+	}
+
+	if f.Name == "" {
+		return "<script>"
+	}
+	return "<fn " + f.Name + ">"
+}
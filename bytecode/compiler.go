@@ -0,0 +1,705 @@
+package bytecode
+
+import (
+	"errors"
+	"fmt"
+	stm "lox/statement"
+	"lox/tokens"
+	"math"
+)
+
+const MAX_LOCALS = math.MaxUint8 + 1
+
+// Compiler translates a resolved program into bytecode. It covers the core
+// of the language: variables, functions and closures, control flow and
+// printing. Programs using anything else, such as classes or modules, are
+// rejected and have to be run from source.
+//
+// Every local of a function gets its own slot, which isn't reused when its
+// block ends, and captured variables are only closed when the function
+// returns, so closures see the same variables as they do in the interpreter.
+type Compiler struct {
+	enclosing *Compiler
+	function  *Function
+	scopes    []map[string]int
+	slots     int
+	upvalues  []upvalue
+	names     map[string]int
+	loops     [][]int
+	line      int
+}
+
+type upvalue struct {
+	index   byte
+	isLocal bool
+}
+
+// compileError unwinds the compiler when it meets something it can't compile.
+type compileError struct {
+	message string
+}
+
+func newCompiler(enclosing *Compiler, function *Function, line int) *Compiler {
+	return &Compiler{
+		enclosing: enclosing,
+		function:  function,
+		scopes:    make([]map[string]int, 0),
+		slots:     1,
+		upvalues:  make([]upvalue, 0),
+		names:     make(map[string]int),
+		line:      line,
+	}
+}
+
+// Compile returns the top-level script of statements, or an error naming the
+// first construct bytecode doesn't support.
+func Compile(statements []stm.Statement) (script *Function, err error) {
+	c := newCompiler(nil, NewFunction("", 0), 1)
+
+	defer func() {
+		if r := recover(); r != nil {
+			failure, ok := r.(compileError)
+
+			if !ok {
+				panic(r)
+			}
+
+			script, err = nil, errors.New(failure.message)
+		}
+	}()
+
+	c.statements(statements)
+	c.emitReturn()
+	c.reserveSlots()
+
+	return c.function, nil
+}
+
+func (c *Compiler) unsupported(what string) {
+	panic(compileError{message: fmt.Sprintf("[line %d] %s can't be compiled to bytecode.", c.line, what)})
+}
+
+func (c *Compiler) statements(statements []stm.Statement) {
+	for _, stmt := range statements {
+		stmt.Accept(c)
+	}
+}
+
+func (c *Compiler) expr(expr stm.Expression) {
+	expr.Accept(c)
+}
+
+func (c *Compiler) chunk() *Chunk {
+	return c.function.Chunk
+}
+
+func (c *Compiler) emit(op OpCode) {
+	c.chunk().WriteOp(op, c.line)
+}
+
+func (c *Compiler) emitByte(op OpCode, operand int) {
+	c.emit(op)
+	c.chunk().Write(byte(operand), c.line)
+}
+
+func (c *Compiler) emitShort(op OpCode, operand int) {
+	c.emit(op)
+	c.chunk().WriteShort(uint16(operand), c.line)
+}
+
+func (c *Compiler) emitReturn() {
+	c.emit(OP_NIL)
+	c.emit(OP_RETURN)
+}
+
+func (c *Compiler) emitJump(op OpCode) int {
+	c.emitShort(op, math.MaxUint16)
+	return len(c.chunk().Code) - 2
+}
+
+func (c *Compiler) patchJump(at int) {
+	jump := len(c.chunk().Code) - at - 2
+
+	if jump > math.MaxUint16 {
+		c.unsupported("A jump this long")
+	}
+
+	c.chunk().Code[at] = byte(jump >> 8)
+	c.chunk().Code[at+1] = byte(jump)
+}
+
+func (c *Compiler) emitLoop(start int) {
+	jump := len(c.chunk().Code) + 3 - start
+
+	if jump > math.MaxUint16 {
+		c.unsupported("A loop body this long")
+	}
+
+	c.emitShort(OP_LOOP, jump)
+}
+
+func (c *Compiler) constant(value any) int {
+	if len(c.chunk().Constants) > math.MaxUint16 {
+		c.unsupported("A function with this many constants")
+	}
+	return c.chunk().AddConstant(value)
+}
+
+func (c *Compiler) name(name string) int {
+	if index, ok := c.names[name]; ok {
+		return index
+	}
+
+	index := c.constant(name)
+	c.names[name] = index
+	return index
+}
+
+// reserveSlots makes a call push nil for every local of the function before
+// its code runs. Jumps are relative, so prepending code keeps them valid.
+func (c *Compiler) reserveSlots() {
+	chunk := c.chunk()
+	count := c.slots - 1 - c.function.Arity
+	code := make([]byte, count)
+	lines := make([]int, count)
+
+	for index := range code {
+		code[index] = byte(OP_NIL)
+		lines[index] = chunk.Lines[0]
+	}
+
+	chunk.Code = append(code, chunk.Code...)
+	chunk.Lines = append(lines, chunk.Lines...)
+	c.function.SlotCount = c.slots
+}
+
+func (c *Compiler) beginScope() {
+	c.scopes = append(c.scopes, make(map[string]int))
+}
+
+func (c *Compiler) endScope() {
+	c.scopes = c.scopes[:len(c.scopes)-1]
+}
+
+func (c *Compiler) isGlobal() bool {
+	return c.enclosing == nil && len(c.scopes) == 0
+}
+
+// declare gives name a new slot in the innermost scope.
+func (c *Compiler) declare(name tokens.Token) int {
+	if c.slots == MAX_LOCALS {
+		c.unsupported("A function with this many local variables")
+	}
+
+	slot := c.slots
+	c.slots++
+	c.scopes[len(c.scopes)-1][name.Lexeme] = slot
+	return slot
+}
+
+func (c *Compiler) resolveLocal(name string) (int, bool) {
+	for depth := len(c.scopes) - 1; depth >= 0; depth-- {
+		if slot, ok := c.scopes[depth][name]; ok {
+			return slot, true
+		}
+	}
+	return 0, false
+}
+
+func (c *Compiler) resolveUpvalue(name string) (int, bool) {
+	if c.enclosing == nil {
+		return 0, false
+	}
+
+	if slot, ok := c.enclosing.resolveLocal(name); ok {
+		return c.addUpvalue(slot, true), true
+	}
+
+	if index, ok := c.enclosing.resolveUpvalue(name); ok {
+		return c.addUpvalue(index, false), true
+	}
+	return 0, false
+}
+
+func (c *Compiler) addUpvalue(index int, isLocal bool) int {
+	captured := upvalue{index: byte(index), isLocal: isLocal}
+
+	for existing, other := range c.upvalues {
+		if other == captured {
+			return existing
+		}
+	}
+
+	if len(c.upvalues) == math.MaxUint8+1 {
+		c.unsupported("A function capturing this many variables")
+	}
+
+	c.upvalues = append(c.upvalues, captured)
+	return len(c.upvalues) - 1
+}
+
+func (c *Compiler) getVariable(name tokens.Token) {
+	c.line = name.Line
+
+	if slot, ok := c.resolveLocal(name.Lexeme); ok {
+		c.emitByte(OP_GET_LOCAL, slot)
+	} else if index, ok := c.resolveUpvalue(name.Lexeme); ok {
+		c.emitByte(OP_GET_UPVALUE, index)
+	} else {
+		c.emitShort(OP_GET_GLOBAL, c.name(name.Lexeme))
+	}
+}
+
+func (c *Compiler) setVariable(name tokens.Token) {
+	c.line = name.Line
+
+	if slot, ok := c.resolveLocal(name.Lexeme); ok {
+		c.emitByte(OP_SET_LOCAL, slot)
+	} else if index, ok := c.resolveUpvalue(name.Lexeme); ok {
+		c.emitByte(OP_SET_UPVALUE, index)
+	} else {
+		c.emitShort(OP_SET_GLOBAL, c.name(name.Lexeme))
+	}
+}
+
+// define stores the value on top of the stack in the variable name declares.
+func (c *Compiler) define(name tokens.Token, slot int) {
+	c.line = name.Line
+
+	if c.isGlobal() {
+		c.emitShort(OP_DEFINE_GLOBAL, c.name(name.Lexeme))
+		return
+	}
+
+	c.emitByte(OP_SET_LOCAL, slot)
+	c.emit(OP_POP)
+}
+
+// closure compiles a function body and emits the closure that creates it.
+func (c *Compiler) closure(name string, parameters stm.Parameters, body []stm.Statement, line int) *Function {
+	if parameters.Variadic || parameters.Required() < len(parameters.Params) {
+		c.unsupported("A function with default or rest parameters")
+	}
+
+	if len(parameters.Params) >= MAX_LOCALS-1 {
+		c.unsupported("A function with this many parameters")
+	}
+
+	compiler := newCompiler(c, NewFunction(name, len(parameters.Params)), line)
+	compiler.beginScope()
+
+	for _, param := range parameters.Params {
+		compiler.declare(param)
+	}
+
+	compiler.statements(body)
+	compiler.emitReturn()
+	compiler.reserveSlots()
+
+	function := compiler.function
+	function.UpvalueCount = len(compiler.upvalues)
+
+	c.line = line
+	c.emitShort(OP_CLOSURE, c.constant(function))
+
+	for _, captured := range compiler.upvalues {
+		isLocal := 0
+
+		if captured.isLocal {
+			isLocal = 1
+		}
+
+		c.chunk().Write(byte(isLocal), c.line)
+		c.chunk().Write(captured.index, c.line)
+	}
+
+	return function
+}
+
+func (c *Compiler) VisitExprStatement(stmt *stm.ExpressionStmt) any {
+	c.expr(stmt.Expression)
+	c.emit(OP_POP)
+	return nil
+}
+
+func (c *Compiler) VisitPrintStatement(stmt *stm.PrintStmt) any {
+	c.expr(stmt.Expression)
+	c.emit(OP_PRINT)
+	return nil
+}
+
+func (c *Compiler) VisitVarStatement(stmt *stm.VarStmt) any {
+	if stmt.Const && c.isGlobal() {
+		c.unsupported("A global constant")
+	}
+
+	c.line = stmt.Name.Line
+
+	if stmt.Initializer != nil {
+		c.expr(stmt.Initializer)
+	} else {
+		c.emit(OP_NIL)
+	}
+
+	slot := 0
+
+	if !c.isGlobal() {
+		slot = c.declare(stmt.Name)
+	}
+
+	c.define(stmt.Name, slot)
+	return nil
+}
+
+func (c *Compiler) VisitVarPatternStatement(stmt *stm.VarPatternStmt) any {
+	c.unsupported("A destructuring declaration")
+	return nil
+}
+
+func (c *Compiler) VisitErrorStatement(stmt *stm.ErrorStmt) any {
+	c.unsupported("A statement with errors")
+	return nil
+}
+
+func (c *Compiler) VisitBlockStatement(stmt *stm.BlockStmt) any {
+	c.beginScope()
+	c.statements(stmt.Statements)
+	c.endScope()
+	return nil
+}
+
+func (c *Compiler) VisitIfStatement(stmt *stm.IfStmt) any {
+	c.expr(stmt.Condition)
+
+	thenJump := c.emitJump(OP_JUMP_IF_FALSE)
+	c.emit(OP_POP)
+	stmt.ThenBranch.Accept(c)
+
+	elseJump := c.emitJump(OP_JUMP)
+	c.patchJump(thenJump)
+	c.emit(OP_POP)
+
+	if stmt.ElseBranch != nil {
+		stmt.ElseBranch.Accept(c)
+	}
+
+	c.patchJump(elseJump)
+	return nil
+}
+
+func (c *Compiler) VisitWhileStatement(stmt *stm.WhileStmt) any {
+	start := len(c.chunk().Code)
+	c.expr(stmt.Condition)
+
+	exitJump := c.emitJump(OP_JUMP_IF_FALSE)
+	c.emit(OP_POP)
+
+	c.loops = append(c.loops, make([]int, 0))
+	stmt.Body.Accept(c)
+	c.emitLoop(start)
+
+	c.patchJump(exitJump)
+	c.emit(OP_POP)
+
+	for _, breakJump := range c.loops[len(c.loops)-1] {
+		c.patchJump(breakJump)
+	}
+
+	c.loops = c.loops[:len(c.loops)-1]
+	return nil
+}
+
+func (c *Compiler) VisitForInStatement(stmt *stm.ForInStmt) any {
+	c.unsupported("A for-in loop")
+	return nil
+}
+
+func (c *Compiler) VisitYieldStatement(stmt *stm.YieldStmt) any {
+	c.unsupported("A yield statement")
+	return nil
+}
+
+func (c *Compiler) VisitSpawnStatement(stmt *stm.SpawnStmt) any {
+	c.unsupported("A spawn statement")
+	return nil
+}
+
+func (c *Compiler) VisitSelectStatement(stmt *stm.SelectStmt) any {
+	c.unsupported("A select statement")
+	return nil
+}
+
+func (c *Compiler) VisitMatchStatement(stmt *stm.MatchStmt) any {
+	c.unsupported("A match statement")
+	return nil
+}
+
+// VisitBreakStatement jumps past the end of the loop. Locals stay in their
+// slots, so there is nothing to pop on the way out.
+func (c *Compiler) VisitBreakStatement(stmt *stm.BreakStmt) any {
+	if len(c.loops) == 0 {
+		c.unsupported("A break outside of a loop")
+	}
+
+	innermost := len(c.loops) - 1
+	c.loops[innermost] = append(c.loops[innermost], c.emitJump(OP_JUMP))
+	return nil
+}
+
+func (c *Compiler) VisitFunctionStatement(stmt *stm.FunctionStm) any {
+	if stmt.Generator || stmt.Async {
+		c.unsupported("A generator or async function")
+	}
+
+	slot := 0
+
+	if !c.isGlobal() {
+		slot = c.declare(stmt.Name)
+	}
+
+	c.closure(stmt.Name.Lexeme, stmt.Parameters, stmt.Body, stmt.Name.Line)
+	c.define(stmt.Name, slot)
+	return nil
+}
+
+func (c *Compiler) VisitReturnStatement(stmt *stm.ReturnStmt) any {
+	if c.enclosing == nil {
+		c.unsupported("A return outside of a function")
+	}
+
+	if stmt.Value != nil {
+		c.expr(stmt.Value)
+	} else {
+		c.emit(OP_NIL)
+	}
+
+	c.line = stmt.Keyword.Line
+	c.emit(OP_RETURN)
+	return nil
+}
+
+func (c *Compiler) VisitClassStatement(stmt *stm.ClassStmt) any {
+	c.unsupported("A class")
+	return nil
+}
+
+func (c *Compiler) VisitImportStatement(stmt *stm.ImportStmt) any {
+	c.unsupported("An import")
+	return nil
+}
+
+func (c *Compiler) VisitExportStatement(stmt *stm.ExportStmt) any {
+	c.unsupported("An export")
+	return nil
+}
+
+func (c *Compiler) VisitTraitStatement(stmt *stm.TraitStmt) any {
+	c.unsupported("A trait")
+	return nil
+}
+
+func (c *Compiler) VisitInterfaceStatement(stmt *stm.InterfaceStmt) any {
+	c.unsupported("An interface")
+	return nil
+}
+
+func (c *Compiler) VisitEnumStatement(stmt *stm.EnumStmt) any {
+	c.unsupported("An enum")
+	return nil
+}
+
+var binaryOps = map[tokens.TokenType]OpCode{
+	tokens.PLUS:          OP_ADD,
+	tokens.MINUS:         OP_SUBTRACT,
+	tokens.STAR:          OP_MULTIPLY,
+	tokens.SLASH:         OP_DIVIDE,
+	tokens.GREATER:       OP_GREATER,
+	tokens.GREATER_EQUAL: OP_GREATER_EQUAL,
+	tokens.LESS:          OP_LESS,
+	tokens.LESS_EQUAL:    OP_LESS_EQUAL,
+	tokens.EQUAL_EQUAL:   OP_EQUAL,
+	tokens.BANG_EQUAL:    OP_NOT_EQUAL,
+}
+
+func (c *Compiler) binary(operator tokens.Token) {
+	op, ok := binaryOps[operator.TokenType]
+
+	if !ok {
+		c.unsupported(fmt.Sprintf("The operator %s", operator.Lexeme))
+	}
+
+	c.line = operator.Line
+	c.emit(op)
+}
+
+func (c *Compiler) VisitBinaryExpr(expr *stm.Binary) any {
+	c.expr(expr.Left)
+	c.expr(expr.Right)
+	c.binary(expr.Operator)
+	return nil
+}
+
+func (c *Compiler) VisitGroupingExpr(expr *stm.Grouping) any {
+	c.expr(expr.Expression)
+	return nil
+}
+
+func (c *Compiler) VisitLiteralExpr(expr *stm.Literal) any {
+	switch value := expr.Value.(type) {
+	case nil:
+		c.emit(OP_NIL)
+	case bool:
+		if value {
+			c.emit(OP_TRUE)
+		} else {
+			c.emit(OP_FALSE)
+		}
+	case float64, string:
+		c.emitShort(OP_CONSTANT, c.constant(value))
+	default:
+		c.unsupported(fmt.Sprintf("A literal of type %T", value))
+	}
+	return nil
+}
+
+func (c *Compiler) VisitUnaryExpr(expr *stm.Unary) any {
+	c.expr(expr.Right)
+	c.line = expr.Operator.Line
+
+	switch expr.Operator.TokenType {
+	case tokens.MINUS:
+		c.emit(OP_NEGATE)
+	case tokens.BANG:
+		c.emit(OP_NOT)
+	default:
+		c.unsupported(fmt.Sprintf("The operator %s", expr.Operator.Lexeme))
+	}
+	return nil
+}
+
+func (c *Compiler) VisitErrorExpr(expr *stm.Error) any {
+	c.unsupported("An expression with errors")
+	return nil
+}
+
+func (c *Compiler) VisitTernaryExpr(expr *stm.Ternary) any {
+	c.unsupported("A conditional expression")
+	return nil
+}
+
+func (c *Compiler) VisitVariableExpr(expr *stm.Variable) any {
+	c.getVariable(expr.Name)
+	return nil
+}
+
+// VisitAssignExpr reads the variable before evaluating the value of a
+// compound assignment, as the interpreter does.
+func (c *Compiler) VisitAssignExpr(expr *stm.Assign) any {
+	if expr.Operator != nil {
+		c.getVariable(expr.Name)
+		c.expr(expr.Value)
+		c.binary(*expr.Operator)
+	} else {
+		c.expr(expr.Value)
+	}
+
+	c.setVariable(expr.Name)
+	return nil
+}
+
+func (c *Compiler) VisitAssignPatternExpr(expr *stm.AssignPattern) any {
+	c.unsupported("A destructuring assignment")
+	return nil
+}
+
+func (c *Compiler) VisitLogicalExpr(expr *stm.Logical) any {
+	c.expr(expr.Left)
+	c.line = expr.Operator.Line
+
+	if expr.Operator.TokenType == tokens.OR {
+		elseJump := c.emitJump(OP_JUMP_IF_FALSE)
+		endJump := c.emitJump(OP_JUMP)
+
+		c.patchJump(elseJump)
+		c.emit(OP_POP)
+		c.expr(expr.Right)
+		c.patchJump(endJump)
+		return nil
+	}
+
+	endJump := c.emitJump(OP_JUMP_IF_FALSE)
+	c.emit(OP_POP)
+	c.expr(expr.Right)
+	c.patchJump(endJump)
+	return nil
+}
+
+func (c *Compiler) VisitCallExpr(expr *stm.Call) any {
+	if len(expr.Names) > 0 {
+		c.unsupported("A call with named arguments")
+	}
+
+	if len(expr.Arguments) > math.MaxUint8 {
+		c.unsupported("A call with this many arguments")
+	}
+
+	c.expr(expr.Callee)
+
+	for _, argument := range expr.Arguments {
+		c.expr(argument)
+	}
+
+	c.line = expr.Paren.Line
+
+	if expr.Tail {
+		c.emitByte(OP_TAIL_CALL, len(expr.Arguments))
+	} else {
+		c.emitByte(OP_CALL, len(expr.Arguments))
+	}
+	return nil
+}
+
+func (c *Compiler) VisitGetExpr(expr *stm.Get) any {
+	c.unsupported("A property access")
+	return nil
+}
+
+func (c *Compiler) VisitIndexExpr(expr *stm.Index) any {
+	c.unsupported("An index expression")
+	return nil
+}
+
+func (c *Compiler) VisitAwaitExpr(expr *stm.Await) any {
+	c.unsupported("An await expression")
+	return nil
+}
+
+func (c *Compiler) VisitMatchExpr(expr *stm.Match) any {
+	c.unsupported("A match expression")
+	return nil
+}
+
+func (c *Compiler) VisitListExpr(expr *stm.List) any {
+	c.unsupported("A list literal")
+	return nil
+}
+
+func (c *Compiler) VisitSetExpr(expr *stm.Set) any {
+	c.unsupported("A property assignment")
+	return nil
+}
+
+func (c *Compiler) VisitThisExpr(expr *stm.This) any {
+	c.unsupported("\"this\"")
+	return nil
+}
+
+func (c *Compiler) VisitSuperExpr(expr *stm.Super) any {
+	c.unsupported("\"super\"")
+	return nil
+}
+
+func (c *Compiler) VisitAnonymousFuncExpr(expr *stm.AnonymousFunction) any {
+	c.closure("", expr.Parameters, expr.Body, c.line).Anonymous = true
+	return nil
+}
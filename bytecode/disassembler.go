@@ -0,0 +1,152 @@
+package bytecode
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Disassemble prints the instruction listing of fn followed by the listings
+// of every function prototype nested in its constants.
+func Disassemble(w io.Writer, fn *Function) {
+	DisassembleChunk(w, fn.Chunk, fn.String())
+
+	for _, constant := range fn.Chunk.Constants {
+		if nested, ok := constant.(*Function); ok {
+			fmt.Fprintln(w)
+			Disassemble(w, nested)
+		}
+	}
+}
+
+func DisassembleChunk(w io.Writer, chunk *Chunk, name string) {
+	fmt.Fprintf(w, "== %s ==\n", name)
+
+	for offset := 0; offset < len(chunk.Code); {
+		offset = DisassembleInstruction(w, chunk, offset)
+	}
+}
+
+func DisassembleInstruction(w io.Writer, chunk *Chunk, offset int) int {
+	fmt.Fprintf(w, "%04d ", offset)
+
+	if offset > 0 && chunk.Lines[offset] == chunk.Lines[offset-1] {
+		fmt.Fprint(w, "   | ")
+	} else {
+		fmt.Fprintf(w, "%4d ", chunk.Lines[offset])
+	}
+
+	op := OpCode(chunk.Code[offset])
+
+	if width := op.operandWidth(); width >= 0 && offset+1+width > len(chunk.Code) {
+		fmt.Fprintf(w, "%-16s <bad operand>\n", op)
+		return len(chunk.Code)
+	}
+
+	switch op {
+	case OP_CONSTANT, OP_GET_GLOBAL, OP_DEFINE_GLOBAL, OP_SET_GLOBAL,
+		OP_GET_PROPERTY, OP_SET_PROPERTY, OP_GET_SUPER, OP_CLASS, OP_METHOD, OP_STATIC_METHOD:
+		return constantInstruction(w, op, chunk, offset)
+
+	case OP_GET_LOCAL, OP_SET_LOCAL, OP_GET_UPVALUE, OP_SET_UPVALUE, OP_CALL, OP_TAIL_CALL:
+		return byteInstruction(w, op, chunk, offset)
+
+	case OP_JUMP, OP_JUMP_IF_FALSE:
+		return jumpInstruction(w, op, 1, chunk, offset)
+
+	case OP_LOOP:
+		return jumpInstruction(w, op, -1, chunk, offset)
+
+	case OP_INVOKE, OP_SUPER_INVOKE:
+		return invokeInstruction(w, op, chunk, offset)
+
+	case OP_CLOSURE:
+		return closureInstruction(w, chunk, offset)
+
+	case OP_NIL, OP_TRUE, OP_FALSE, OP_POP, OP_EQUAL, OP_GREATER, OP_LESS,
+		OP_ADD, OP_SUBTRACT, OP_MULTIPLY, OP_DIVIDE, OP_NOT, OP_NEGATE,
+		OP_PRINT, OP_CLOSE_UPVALUE, OP_RETURN, OP_INHERIT,
+		OP_GREATER_EQUAL, OP_LESS_EQUAL, OP_NOT_EQUAL:
+		fmt.Fprintln(w, op)
+		return offset + 1
+	}
+
+	fmt.Fprintf(w, "Unknown opcode %d\n", op)
+	return offset + 1
+}
+
+func constantInstruction(w io.Writer, op OpCode, chunk *Chunk, offset int) int {
+	index := chunk.ReadShort(offset + 1)
+	fmt.Fprintf(w, "%-16s %4d '%s'\n", op, index, constantAt(chunk, index))
+	return offset + 3
+}
+
+func byteInstruction(w io.Writer, op OpCode, chunk *Chunk, offset int) int {
+	fmt.Fprintf(w, "%-16s %4d\n", op, chunk.Code[offset+1])
+	return offset + 2
+}
+
+func jumpInstruction(w io.Writer, op OpCode, sign int, chunk *Chunk, offset int) int {
+	jump := int(chunk.ReadShort(offset + 1))
+	fmt.Fprintf(w, "%-16s %4d -> %d\n", op, offset, offset+3+sign*jump)
+	return offset + 3
+}
+
+func invokeInstruction(w io.Writer, op OpCode, chunk *Chunk, offset int) int {
+	index := chunk.ReadShort(offset + 1)
+	argCount := chunk.Code[offset+3]
+	fmt.Fprintf(w, "%-16s (%d args) %4d '%s'\n", op, argCount, index, constantAt(chunk, index))
+	return offset + 4
+}
+
+func closureInstruction(w io.Writer, chunk *Chunk, offset int) int {
+	index := chunk.ReadShort(offset + 1)
+	fmt.Fprintf(w, "%-16s %4d %s\n", OP_CLOSURE, index, constantAt(chunk, index))
+	offset += 3
+
+	if int(index) >= len(chunk.Constants) {
+		return offset
+	}
+
+	fn, ok := chunk.Constants[index].(*Function)
+
+	if !ok {
+		return offset
+	}
+
+	for i := 0; i < fn.UpvalueCount; i++ {
+		if offset+1 >= len(chunk.Code) {
+			fmt.Fprintf(w, "%04d    |                     <bad operand>\n", offset)
+			return len(chunk.Code)
+		}
+
+		kind := "upvalue"
+
+		if chunk.Code[offset] == 1 {
+			kind = "local"
+		}
+
+		fmt.Fprintf(w, "%04d    |                     %s %d\n", offset, kind, chunk.Code[offset+1])
+		offset += 2
+	}
+
+	return offset
+}
+
+func constantAt(chunk *Chunk, index uint16) string {
+	if int(index) >= len(chunk.Constants) {
+		return "<bad operand>"
+	}
+	return formatConstant(chunk.Constants[index])
+}
+
+func formatConstant(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "nil"
+	case float64:
+		return strings.TrimSuffix(fmt.Sprintf("%v", v), ".0")
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
@@ -0,0 +1,459 @@
+package bytecode
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const FormatVersion uint16 = 3
+
+var magic = [4]byte{'L', 'O', 'X', 'C'}
+
+var (
+	ErrBadMagic        = errors.New("not a compiled lox chunk")
+	ErrVersionMismatch = errors.New("compiled chunk version mismatch")
+	ErrStaleCache      = errors.New("compiled chunk does not match source")
+	ErrCorrupt         = errors.New("corrupt compiled chunk")
+)
+
+const (
+	constNil byte = iota
+	constFalse
+	constTrue
+	constNumber
+	constString
+	constFunction
+)
+
+// Header is stored in front of every serialized script. SourceHash is the
+// sha256 of the source the script was compiled from, so a cache file can be
+// checked against the script it sits next to.
+type Header struct {
+	Version    uint16
+	SourceHash [sha256.Size]byte
+}
+
+func HashSource(source []byte) [sha256.Size]byte {
+	return sha256.Sum256(source)
+}
+
+func Encode(w io.Writer, script *Function, sourceHash [sha256.Size]byte) error {
+	bw := bufio.NewWriter(w)
+	enc := encoder{w: bw}
+
+	enc.bytes(magic[:])
+	enc.uint16(FormatVersion)
+	enc.bytes(sourceHash[:])
+	enc.function(script)
+
+	if enc.err != nil {
+		return enc.err
+	}
+	return bw.Flush()
+}
+
+func Decode(r io.Reader) (*Function, Header, error) {
+	dec := decoder{r: bufio.NewReader(r)}
+	header := Header{}
+
+	var m [4]byte
+	dec.bytes(m[:])
+
+	if dec.err != nil || m != magic {
+		return nil, header, ErrBadMagic
+	}
+
+	header.Version = dec.uint16()
+
+	if dec.err == nil && header.Version != FormatVersion {
+		return nil, header, fmt.Errorf("%w: file has %d, expected %d", ErrVersionMismatch, header.Version, FormatVersion)
+	}
+
+	dec.bytes(header.SourceHash[:])
+	script := dec.function()
+
+	if dec.err != nil {
+		return nil, header, dec.err
+	}
+
+	return script, header, nil
+}
+
+func WriteFile(path string, script *Function, source []byte) error {
+	file, err := os.Create(path)
+
+	if err != nil {
+		return err
+	}
+
+	if err := Encode(file, script, HashSource(source)); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+func ReadFile(path string) (*Function, Header, error) {
+	file, err := os.Open(path)
+
+	if err != nil {
+		return nil, Header{}, err
+	}
+
+	defer file.Close()
+
+	return Decode(file)
+}
+
+// CachePath is where the compiled form of the script at path is kept: next
+// to it, with the extension .loxc.
+func CachePath(path string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path)) + ".loxc"
+}
+
+// LoadCache returns the script stored in cachePath if it was compiled from
+// source with the current format version.
+func LoadCache(cachePath string, source []byte) (*Function, error) {
+	script, header, err := ReadFile(cachePath)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if header.SourceHash != HashSource(source) {
+		return nil, ErrStaleCache
+	}
+
+	return script, nil
+}
+
+type encoder struct {
+	w   io.Writer
+	err error
+}
+
+func (e *encoder) bytes(b []byte) {
+	if e.err != nil {
+		return
+	}
+	_, e.err = e.w.Write(b)
+}
+
+func (e *encoder) byte(b byte) {
+	e.bytes([]byte{b})
+}
+
+func (e *encoder) uint16(v uint16) {
+	var buf [2]byte
+	binary.BigEndian.PutUint16(buf[:], v)
+	e.bytes(buf[:])
+}
+
+func (e *encoder) uvarint(v uint64) {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], v)
+	e.bytes(buf[:n])
+}
+
+func (e *encoder) string(s string) {
+	e.uvarint(uint64(len(s)))
+	e.bytes([]byte(s))
+}
+
+func (e *encoder) function(f *Function) {
+	e.string(f.Name)
+	e.uvarint(uint64(f.Arity))
+	e.uvarint(uint64(f.SlotCount))
+	e.uvarint(uint64(f.UpvalueCount))
+
+	if f.Anonymous {
+		e.byte(1)
+	} else {
+		e.byte(0)
+	}
+
+	e.chunk(f.Chunk)
+}
+
+func (e *encoder) chunk(c *Chunk) {
+	e.uvarint(uint64(len(c.Code)))
+	e.bytes(c.Code)
+
+	// Lines are run-length encoded as (line, count) pairs.
+	for i := 0; i < len(c.Lines); {
+		j := i
+		for j < len(c.Lines) && c.Lines[j] == c.Lines[i] {
+			j++
+		}
+		e.uvarint(uint64(c.Lines[i]))
+		e.uvarint(uint64(j - i))
+		i = j
+	}
+
+	e.uvarint(uint64(len(c.Constants)))
+
+	for _, constant := range c.Constants {
+		e.constant(constant)
+	}
+}
+
+func (e *encoder) constant(value any) {
+	switch v := value.(type) {
+	case nil:
+		e.byte(constNil)
+	case bool:
+		if v {
+			e.byte(constTrue)
+		} else {
+			e.byte(constFalse)
+		}
+	case float64:
+		e.byte(constNumber)
+		var buf [8]byte
+		binary.BigEndian.PutUint64(buf[:], math.Float64bits(v))
+		e.bytes(buf[:])
+	case string:
+		e.byte(constString)
+		e.string(v)
+	case *Function:
+		e.byte(constFunction)
+		e.function(v)
+	default:
+		if e.err == nil {
+			e.err = fmt.Errorf("cannot serialize constant of type %T", value)
+		}
+	}
+}
+
+type decoder struct {
+	r   *bufio.Reader
+	err error
+}
+
+func (d *decoder) bytes(b []byte) {
+	if d.err != nil {
+		return
+	}
+	_, d.err = io.ReadFull(d.r, b)
+}
+
+func (d *decoder) byte() byte {
+	var buf [1]byte
+	d.bytes(buf[:])
+	return buf[0]
+}
+
+func (d *decoder) uint16() uint16 {
+	var buf [2]byte
+	d.bytes(buf[:])
+	return binary.BigEndian.Uint16(buf[:])
+}
+
+func (d *decoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, err := binary.ReadUvarint(d.r)
+	d.err = err
+	return v
+}
+
+func (d *decoder) length() int {
+	n := d.uvarint()
+
+	if d.err == nil && n > math.MaxInt32 {
+		d.err = fmt.Errorf("%w: length out of range", ErrCorrupt)
+	}
+
+	if d.err != nil {
+		return 0
+	}
+	return int(n)
+}
+
+func (d *decoder) string() string {
+	buf := make([]byte, d.length())
+	d.bytes(buf)
+	return string(buf)
+}
+
+func (d *decoder) function() *Function {
+	f := &Function{}
+	f.Name = d.string()
+	f.Arity = d.length()
+	f.SlotCount = d.length()
+	f.UpvalueCount = d.length()
+	f.Anonymous = d.byte() == 1
+	f.Chunk = d.chunk()
+
+	if d.err == nil {
+		d.err = validate(f)
+	}
+	return f
+}
+
+func (d *decoder) chunk() *Chunk {
+	c := NewChunk()
+	c.Code = make([]byte, d.length())
+	d.bytes(c.Code)
+
+	for len(c.Lines) < len(c.Code) && d.err == nil {
+		line := d.length()
+		count := d.length()
+
+		if count == 0 || len(c.Lines)+count > len(c.Code) {
+			d.err = fmt.Errorf("%w: bad line table", ErrCorrupt)
+			break
+		}
+
+		for i := 0; i < count; i++ {
+			c.Lines = append(c.Lines, line)
+		}
+	}
+
+	constants := d.length()
+
+	for i := 0; i < constants && d.err == nil; i++ {
+		c.Constants = append(c.Constants, d.constant())
+	}
+
+	return c
+}
+
+func (d *decoder) constant() any {
+	switch tag := d.byte(); tag {
+	case constNil:
+		return nil
+	case constFalse:
+		return false
+	case constTrue:
+		return true
+	case constNumber:
+		var buf [8]byte
+		d.bytes(buf[:])
+		return math.Float64frombits(binary.BigEndian.Uint64(buf[:]))
+	case constString:
+		return d.string()
+	case constFunction:
+		return d.function()
+	default:
+		if d.err == nil {
+			d.err = fmt.Errorf("%w: unknown constant tag %d", ErrCorrupt, tag)
+		}
+		return nil
+	}
+}
+
+// validate checks that every instruction of f is complete and that its
+// operands refer to constants, locals, upvalues and offsets that exist, so the
+// disassembler and the VM can trust a decoded chunk.
+func validate(f *Function) error {
+	chunk := f.Chunk
+	reserved := f.SlotCount - 1 - f.Arity
+
+	if reserved < 0 || f.SlotCount > MAX_LOCALS || reserved > len(chunk.Code) {
+		return fmt.Errorf("%w: %s has %d slots for %d parameters", ErrCorrupt, f, f.SlotCount, f.Arity)
+	}
+
+	// The VM only has a slot for a local once the leading OP_NILs have pushed
+	// it, so they are part of the function's signature rather than its code.
+	for offset := 0; offset < reserved; offset++ {
+		if OpCode(chunk.Code[offset]) != OP_NIL {
+			return fmt.Errorf("%w: %s doesn't reserve its %d locals", ErrCorrupt, f, reserved)
+		}
+	}
+
+	for offset := 0; offset < len(chunk.Code); {
+		op := OpCode(chunk.Code[offset])
+		length := op.operandWidth()
+
+		if length < 0 {
+			return fmt.Errorf("%w: unknown opcode %d at %04d", ErrCorrupt, op, offset)
+		}
+
+		if offset+1+length > len(chunk.Code) {
+			return fmt.Errorf("%w: truncated %s at %04d", ErrCorrupt, op, offset)
+		}
+
+		next := offset + 1 + length
+
+		switch op {
+		case OP_CONSTANT:
+			if int(chunk.ReadShort(offset+1)) >= len(chunk.Constants) {
+				return fmt.Errorf("%w: %s at %04d has no constant %d", ErrCorrupt, op, offset, chunk.ReadShort(offset+1))
+			}
+		case OP_GET_GLOBAL, OP_DEFINE_GLOBAL, OP_SET_GLOBAL, OP_GET_PROPERTY, OP_SET_PROPERTY,
+			OP_GET_SUPER, OP_CLASS, OP_METHOD, OP_STATIC_METHOD, OP_INVOKE, OP_SUPER_INVOKE:
+			index := int(chunk.ReadShort(offset + 1))
+
+			if index >= len(chunk.Constants) {
+				return fmt.Errorf("%w: %s at %04d has no constant %d", ErrCorrupt, op, offset, index)
+			}
+
+			if _, ok := chunk.Constants[index].(string); !ok {
+				return fmt.Errorf("%w: %s at %04d needs a name", ErrCorrupt, op, offset)
+			}
+		case OP_GET_LOCAL, OP_SET_LOCAL:
+			if int(chunk.Code[offset+1]) >= f.SlotCount {
+				return fmt.Errorf("%w: %s at %04d has no local %d", ErrCorrupt, op, offset, chunk.Code[offset+1])
+			}
+		case OP_GET_UPVALUE, OP_SET_UPVALUE:
+			if int(chunk.Code[offset+1]) >= f.UpvalueCount {
+				return fmt.Errorf("%w: %s at %04d has no upvalue %d", ErrCorrupt, op, offset, chunk.Code[offset+1])
+			}
+		case OP_JUMP, OP_JUMP_IF_FALSE, OP_LOOP:
+			target := next + int(chunk.ReadShort(offset+1))
+
+			if op == OP_LOOP {
+				target = next - int(chunk.ReadShort(offset+1))
+			}
+
+			if target < 0 || target > len(chunk.Code) {
+				return fmt.Errorf("%w: %s at %04d jumps out of the chunk", ErrCorrupt, op, offset)
+			}
+		case OP_CLOSURE:
+			index := int(chunk.ReadShort(offset + 1))
+
+			if index >= len(chunk.Constants) {
+				return fmt.Errorf("%w: %s at %04d has no constant %d", ErrCorrupt, op, offset, index)
+			}
+
+			nested, ok := chunk.Constants[index].(*Function)
+
+			if !ok {
+				return fmt.Errorf("%w: %s at %04d needs a function", ErrCorrupt, op, offset)
+			}
+
+			next += 2 * nested.UpvalueCount
+
+			if next > len(chunk.Code) {
+				return fmt.Errorf("%w: truncated %s at %04d", ErrCorrupt, op, offset)
+			}
+
+			for capture := offset + 3; capture < next; capture += 2 {
+				isLocal, index := chunk.Code[capture], int(chunk.Code[capture+1])
+
+				if isLocal > 1 || (isLocal == 0 && index >= f.UpvalueCount) {
+					return fmt.Errorf("%w: %s at %04d captures a missing upvalue", ErrCorrupt, op, offset)
+				}
+
+				if isLocal == 1 && index >= f.SlotCount {
+					return fmt.Errorf("%w: %s at %04d captures a missing local", ErrCorrupt, op, offset)
+				}
+			}
+		}
+
+		offset = next
+	}
+
+	return nil
+}
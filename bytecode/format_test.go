@@ -0,0 +1,179 @@
+package bytecode
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// function builds a function whose code is all on line 1.
+func function(arity, slots, upvalues int, constants []any, code ...byte) *Function {
+	f := NewFunction("f", arity)
+	f.SlotCount = slots
+	f.UpvalueCount = upvalues
+	f.Chunk.Constants = append(f.Chunk.Constants, constants...)
+
+	for _, b := range code {
+		f.Chunk.Write(b, 1)
+	}
+
+	return f
+}
+
+func encode(t *testing.T, f *Function) []byte {
+	t.Helper()
+
+	var buffer bytes.Buffer
+
+	if err := Encode(&buffer, f, HashSource([]byte("source"))); err != nil {
+		t.Fatal(err)
+	}
+
+	return buffer.Bytes()
+}
+
+func TestFormatRoundTrip(t *testing.T) {
+	nested := function(1, 3, 1, nil,
+		byte(OP_NIL),
+		byte(OP_GET_LOCAL), 1,
+		byte(OP_SET_LOCAL), 2,
+		byte(OP_GET_UPVALUE), 0,
+		byte(OP_RETURN))
+	nested.Chunk.Lines[len(nested.Chunk.Lines)-1] = 2
+
+	tests := []struct {
+		name   string
+		script *Function
+	}{
+		{"empty", function(0, 1, 0, nil, byte(OP_NIL), byte(OP_RETURN))},
+		{"constants", function(0, 1, 0, []any{1.5, "name", true, nil},
+			byte(OP_CONSTANT), 0, 0,
+			byte(OP_DEFINE_GLOBAL), 0, 1,
+			byte(OP_CONSTANT), 0, 2,
+			byte(OP_CONSTANT), 0, 3,
+			byte(OP_EQUAL),
+			byte(OP_PRINT),
+			byte(OP_NIL),
+			byte(OP_RETURN))},
+		{"closure", function(0, 2, 0, []any{nested},
+			byte(OP_NIL),
+			byte(OP_CLOSURE), 0, 0, 1, 1,
+			byte(OP_SET_LOCAL), 1,
+			byte(OP_JUMP), 0, 1,
+			byte(OP_POP),
+			byte(OP_NIL),
+			byte(OP_RETURN))},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			script, header, err := Decode(bytes.NewReader(encode(t, test.script)))
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if header.Version != FormatVersion || header.SourceHash != HashSource([]byte("source")) {
+				t.Errorf("header %+v", header)
+			}
+
+			if !reflect.DeepEqual(script, test.script) {
+				t.Errorf("decoded %+v, want %+v", script, test.script)
+			}
+		})
+	}
+}
+
+func TestDecodeRejectsCorruptChunks(t *testing.T) {
+	capturing := function(0, 1, 1, nil, byte(OP_NIL), byte(OP_RETURN))
+
+	tests := []struct {
+		name   string
+		script *Function
+	}{
+		{"unknown opcode", function(0, 1, 0, nil, 200)},
+		{"truncated operand", function(0, 1, 0, []any{1.0}, byte(OP_CONSTANT), 0)},
+		{"missing constant", function(0, 1, 0, nil, byte(OP_CONSTANT), 0, 0)},
+		{"name not a string", function(0, 1, 0, []any{1.0}, byte(OP_GET_GLOBAL), 0, 0)},
+		{"missing local", function(1, 3, 0, nil, byte(OP_NIL), byte(OP_GET_LOCAL), 3)},
+		{"missing local on set", function(0, 1, 0, nil, byte(OP_SET_LOCAL), 1)},
+		{"locals not reserved", function(0, 2, 0, nil, byte(OP_TRUE), byte(OP_GET_LOCAL), 1)},
+		{"fewer slots than parameters", function(2, 2, 0, nil, byte(OP_NIL), byte(OP_RETURN))},
+		{"too many slots", function(0, MAX_LOCALS+1, 0, nil)},
+		{"missing upvalue", function(0, 1, 1, nil, byte(OP_GET_UPVALUE), 1)},
+		{"jump out of chunk", function(0, 1, 0, nil, byte(OP_JUMP), 0, 9)},
+		{"loop out of chunk", function(0, 1, 0, nil, byte(OP_LOOP), 0, 9)},
+		{"closure of a number", function(0, 1, 0, []any{1.0}, byte(OP_CLOSURE), 0, 0)},
+		{"truncated captures", function(0, 1, 0, []any{capturing}, byte(OP_CLOSURE), 0, 0, 1)},
+		{"capture of a missing local", function(0, 1, 0, []any{capturing}, byte(OP_CLOSURE), 0, 0, 1, 1)},
+		{"capture of a missing upvalue", function(0, 1, 0, []any{capturing}, byte(OP_CLOSURE), 0, 0, 0, 0)},
+		{"corrupt nested function", function(0, 1, 0, []any{function(0, 1, 0, nil, 200)}, byte(OP_NIL))},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, _, err := Decode(bytes.NewReader(encode(t, test.script)))
+
+			if !errors.Is(err, ErrCorrupt) {
+				t.Errorf("got %v, want %v", err, ErrCorrupt)
+			}
+		})
+	}
+}
+
+func TestDecodeRejectsBadHeaders(t *testing.T) {
+	valid := encode(t, function(0, 1, 0, nil, byte(OP_NIL), byte(OP_RETURN)))
+
+	oldVersion := append([]byte{}, valid...)
+	oldVersion[5] = byte(FormatVersion - 1)
+
+	// want is nil where any error will do.
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"empty file", nil, ErrBadMagic},
+		{"not a chunk", []byte("print 1;"), ErrBadMagic},
+		{"old version", oldVersion, ErrVersionMismatch},
+		{"truncated hash", valid[:len(magic)+2+sha256.Size/2], nil},
+		{"truncated code", valid[:len(valid)-3], nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, _, err := Decode(bytes.NewReader(test.data))
+
+			if err == nil || (test.want != nil && !errors.Is(err, test.want)) {
+				t.Errorf("got %v, want %v", err, test.want)
+			}
+		})
+	}
+}
+
+func TestLoadCache(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "script.lox")
+	source := []byte("print 1;")
+	script := function(0, 1, 0, []any{1.0}, byte(OP_CONSTANT), 0, 0, byte(OP_PRINT), byte(OP_NIL), byte(OP_RETURN))
+
+	if _, err := LoadCache(CachePath(path), source); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("without a cache got %v, want %v", err, os.ErrNotExist)
+	}
+
+	if err := WriteFile(CachePath(path), script, source); err != nil {
+		t.Fatal(err)
+	}
+
+	if cached, err := LoadCache(CachePath(path), source); err != nil || !reflect.DeepEqual(cached, script) {
+		t.Errorf("got %+v, %v, want the script", cached, err)
+	}
+
+	if _, err := LoadCache(CachePath(path), []byte("print 2;")); !errors.Is(err, ErrStaleCache) {
+		t.Errorf("after an edit got %v, want %v", err, ErrStaleCache)
+	}
+}
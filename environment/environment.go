@@ -7,6 +7,7 @@ import (
 
 type Environment struct {
 	Values    map[string]any
+	Constants map[string]bool
 	Enclosing *Environment
 }
 
@@ -20,14 +21,26 @@ func NewEnvironment(enclosing ...*Environment) *Environment {
 
 	return &Environment{
 		Values:    values,
+		Constants: make(map[string]bool),
 		Enclosing: env,
 	}
 }
 
 func (e *Environment) Define(name string, value any) {
+	if e.Constants[name] {
+		panic(fmt.Sprintf("Can't redeclare constant %s.", name))
+	}
+
 	e.Values[name] = value
 }
 
+// DefineConstant defines a variable that can't be assigned to or declared
+// again.
+func (e *Environment) DefineConstant(name string, value any) {
+	e.Define(name, value)
+	e.Constants[name] = true
+}
+
 func (e *Environment) Get(name tokens.Token) any {
 	value, ok := e.Values[name.Lexeme]
 	if ok {
@@ -64,6 +77,10 @@ func (e *Environment) ancestor(depth int) *Environment {
 
 func (e *Environment) Assign(name tokens.Token, value any) {
 	if _, ok := e.Values[name.Lexeme]; ok {
+		if e.Constants[name.Lexeme] {
+			panic(fmt.Sprintf("Can't assign to constant %s.", name.Lexeme))
+		}
+
 		e.Values[name.Lexeme] = value
 		return
 	}
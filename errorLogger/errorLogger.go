@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"lox/lox"
 	"lox/tokens"
+	"os"
 )
 
 type ErrorLogger struct {
@@ -34,6 +35,12 @@ func (el ErrorLogger) ErrorForToken(token tokens.Token, message string) error {
 	return errors.New("ParseError")
 }
 
+// Warning reports a likely mistake that doesn't stop the program from
+// running. Warnings go to stderr so they don't mix with the program's output.
+func (el ErrorLogger) Warning(token tokens.Token, message string) {
+	fmt.Fprintf(os.Stderr, "[Line: %d] Warning at '%s': %s\n", token.Line, token.Lexeme, message)
+}
+
 func (el ErrorLogger) RuntimeError(message string) {
 	fmt.Println(message)
 	el.Lox.HadRuntimeError = true
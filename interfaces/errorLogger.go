@@ -7,4 +7,5 @@ type ErrorLogger interface {
 	Report(line int, where string, message string)
 	ErrorForToken(token tokens.Token, message string) error
 	RuntimeError(message string)
+	Warning(token tokens.Token, message string)
 }
@@ -1,16 +1,16 @@
 package interpreter
 
 import (
-	env "lox/environment"
 	stm "lox/statement"
 )
 
 type AnonymousFunction struct {
 	Declaration stm.AnonymousFunction
-	Closure     *env.Environment
+	Closure     *Frame
+	body        compiledStmt
 }
 
-func NewAnonymousFunction(declaration stm.AnonymousFunction, closure *env.Environment) *AnonymousFunction {
+func NewAnonymousFunction(declaration stm.AnonymousFunction, closure *Frame) *AnonymousFunction {
 	return &AnonymousFunction{
 		Declaration: declaration,
 		Closure:     closure,
@@ -18,25 +18,41 @@ func NewAnonymousFunction(declaration stm.AnonymousFunction, closure *env.Enviro
 }
 
 func (l AnonymousFunction) Call(interpreter *Interpreter, args []any) (result any) {
+	frame := NewFrame(l.Declaration.Slots, l.Closure, interpreter)
+	copy(frame.slots, frameArguments(l.Declaration.Parameters, args))
+
+	previous := interpreter.frame
+	interpreter.frame = frame
 
 	defer func() {
-		result = recover()
-	}()
+		interpreter.frame = previous
+		value := recover()
 
-	environment := env.NewEnvironment(l.Closure)
+		if value == nil {
+			return
+		}
 
-	for i, param := range l.Declaration.Params {
-		index := interpreter.locals[param]
-		interpreter.LocalVariables[index] = args[i]
-	}
+		returnValue, ok := value.(ReturnValue)
+
+		if !ok {
+			panic(value)
+		}
 
-	interpreter.executeBlock(l.Declaration.Body, environment)
+		result = returnValue.Value
+	}()
+
+	if l.body != nil {
+		l.body(frame)
+	} else {
+		interpreter.fillDefaults(l.Declaration.Parameters)
+		interpreter.executeBlock(l.Declaration.Body)
+	}
 
 	return
 }
 
-func (l AnonymousFunction) Arity() int {
-	return len(l.Declaration.Params)
+func (l AnonymousFunction) Arity() Arity {
+	return ParameterArity(l.Declaration.Parameters)
 }
 
 func (l AnonymousFunction) String() string {
@@ -0,0 +1,164 @@
+package interpreter
+
+import (
+	"fmt"
+	stm "lox/statement"
+	"lox/tokens"
+)
+
+// missing fills the place of a parameter that a call skipped by naming a
+// later one. The function gives it its default value.
+type missing struct{}
+
+// ParameterArity is the arity of a function declared with parameters.
+func ParameterArity(parameters stm.Parameters) Arity {
+	if parameters.Variadic {
+		return atLeast(parameters.Required())
+	}
+	return between(parameters.Required(), len(parameters.Params))
+}
+
+// frameArguments returns the values of the parameters of a function called
+// with args: the arguments, missing for the parameters they don't reach, and
+// a list of the remaining arguments for a rest parameter.
+func frameArguments(parameters stm.Parameters, args []any) []any {
+	count := len(parameters.Params)
+
+	if len(args) == count && !parameters.Variadic {
+		return args
+	}
+
+	if parameters.Variadic {
+		count--
+	}
+
+	values := make([]any, len(parameters.Params))
+
+	for index := 0; index < count; index++ {
+		if index < len(args) {
+			values[index] = args[index]
+		} else {
+			values[index] = missing{}
+		}
+	}
+
+	if parameters.Variadic {
+		remaining := []any{}
+
+		if len(args) > count {
+			remaining = append(remaining, args[count:]...)
+		}
+
+		values[count] = NewLoxList(remaining)
+	}
+
+	return values
+}
+
+// fillDefaults evaluates the default values of the parameters the current
+// call left out, in order, in the frame of the function.
+func (i *Interpreter) fillDefaults(parameters stm.Parameters) {
+	for index, value := range parameters.Defaults {
+		if value == nil {
+			continue
+		}
+
+		slot := i.locals[parameters.Params[index]].index
+
+		if _, ok := i.frame.slots[slot].(missing); ok {
+			i.frame.slots[slot] = i.evaluate(value)
+		}
+	}
+}
+
+// signature returns the names of the parameters of callee that arguments
+// can be passed to by name, and how many of them are required.
+func signature(callee any) ([]string, int, bool) {
+	var parameters stm.Parameters
+
+	switch function := callee.(type) {
+	case *LoxFunction:
+		parameters = function.Declaration.Parameters
+	case *BoundMethod:
+		parameters = function.Method.Declaration.Parameters
+	case *AnonymousFunction:
+		parameters = function.Declaration.Parameters
+	case *LoxClass:
+		if function.initializer != nil {
+			parameters = function.initializer.Declaration.Parameters
+		}
+	case *LoxInstance:
+		if _, method, ok := specialMethod(function, CALL_METHOD); ok {
+			parameters = method.Declaration.Parameters
+		} else {
+			return nil, 0, false
+		}
+	case *enumVariant:
+		return function.params, len(function.params), true
+	default:
+		return nil, 0, false
+	}
+
+	names := make([]string, 0, len(parameters.Params))
+
+	for index, param := range parameters.Params {
+		if !parameters.Variadic || index < len(parameters.Params)-1 {
+			names = append(names, param.Lexeme)
+		}
+	}
+
+	return names, parameters.Required(), true
+}
+
+// arrange puts the named arguments of a call, the last len(names) of
+// arguments, in the places of the parameters they name.
+func (i *Interpreter) arrange(callee any, arguments []any, names []tokens.Token, paren tokens.Token) []any {
+	if len(names) == 0 {
+		return arguments
+	}
+
+	params, required, ok := signature(callee)
+
+	if !ok {
+		panic(fmt.Sprintf("%s doesn't take named arguments.\n [line %d]", i.stringify(callee), paren.Line))
+	}
+
+	positional := len(arguments) - len(names)
+	arranged := append([]any{}, arguments[:positional]...)
+
+	for index, name := range names {
+		at := -1
+
+		for candidate, param := range params {
+			if param == name.Lexeme {
+				at = candidate
+			}
+		}
+
+		if at < 0 {
+			panic(fmt.Sprintf("%s has no parameter named \"%s\".\n [line %d]", i.stringify(callee), name.Lexeme, name.Line))
+		}
+
+		if at < positional {
+			panic(fmt.Sprintf("Argument \"%s\" was already passed by position.\n [line %d]", name.Lexeme, name.Line))
+		}
+
+		for len(arranged) <= at {
+			arranged = append(arranged, missing{})
+		}
+
+		arranged[at] = arguments[positional+index]
+	}
+
+	for index := 0; index < required; index++ {
+		if index >= len(arranged) {
+			panic(fmt.Sprintf("Missing argument for parameter \"%s\".\n [line %d]", params[index], paren.Line))
+		}
+
+		if _, ok := arranged[index].(missing); ok {
+			panic(fmt.Sprintf("Missing argument for parameter \"%s\".\n [line %d]", params[index], paren.Line))
+		}
+	}
+
+	return arranged
+}
@@ -1,14 +1,60 @@
 package interpreter
 
+import "fmt"
+
+// VARIADIC is the Max of the arity of a callable taking any number of
+// arguments beyond Min.
+const VARIADIC = -1
+
+// Arity is the range of argument counts a callable accepts.
+type Arity struct {
+	Min int
+	Max int
+}
+
+func exactly(count int) Arity {
+	return Arity{Min: count, Max: count}
+}
+
+func between(min, max int) Arity {
+	return Arity{Min: min, Max: max}
+}
+
+func atLeast(min int) Arity {
+	return Arity{Min: min, Max: VARIADIC}
+}
+
+func (a Arity) accepts(count int) bool {
+	return count >= a.Min && (a.Max == VARIADIC || count <= a.Max)
+}
+
+// Covers reports whether a accepts every argument count other accepts.
+func (a Arity) Covers(other Arity) bool {
+	if a.Max == VARIADIC {
+		return a.Min <= other.Min
+	}
+	return a.Min <= other.Min && other.Max != VARIADIC && other.Max <= a.Max
+}
+
+func (a Arity) String() string {
+	switch {
+	case a.Max == VARIADIC:
+		return fmt.Sprintf("at least %d", a.Min)
+	case a.Min == a.Max:
+		return fmt.Sprintf("%d", a.Min)
+	}
+	return fmt.Sprintf("%d to %d", a.Min, a.Max)
+}
+
 type NativeFunctionCallable struct {
-	arityFn func() int
-	callFn  func(interpreter *Interpreter, args []any) any
+	arity  Arity
+	callFn func(interpreter *Interpreter, args []any) any
 }
 
-func NewNativeFnCallable(arityFn func() int, callFn func(interpreter *Interpreter, args []any) any) *NativeFunctionCallable {
+func NewNativeFnCallable(arity Arity, callFn func(interpreter *Interpreter, args []any) any) *NativeFunctionCallable {
 	return &NativeFunctionCallable{
-		arityFn: arityFn,
-		callFn:  callFn,
+		arity:  arity,
+		callFn: callFn,
 	}
 }
 
@@ -16,11 +62,15 @@ func (f NativeFunctionCallable) Call(interpreter *Interpreter, args []any) any {
 	return f.callFn(interpreter, args)
 }
 
-func (f NativeFunctionCallable) Arity() int {
-	return f.arityFn()
+func (f NativeFunctionCallable) Arity() Arity {
+	return f.arity
+}
+
+func (f NativeFunctionCallable) String() string {
+	return "<native fn>"
 }
 
 type Callable interface {
 	Call(interpreter *Interpreter, args []any) any
-	Arity() int
+	Arity() Arity
 }
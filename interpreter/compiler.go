@@ -0,0 +1,1159 @@
+package interpreter
+
+import (
+	"fmt"
+	stm "lox/statement"
+	"lox/tokens"
+)
+
+type compiledExpr func(frame *Frame) any
+type compiledStmt func(frame *Frame)
+
+// Compiler turns statements into trees of Go closures once, so evaluating
+// them skips the visitor dispatch and the locals map lookups.
+type Compiler struct {
+	interpreter *Interpreter
+	loopDepth   int
+}
+
+func NewCompiler(interpreter *Interpreter) *Compiler {
+	return &Compiler{
+		interpreter: interpreter,
+	}
+}
+
+func (c *Compiler) Compile(statements []stm.Statement) compiledStmt {
+	return c.block(statements)
+}
+
+func (c *Compiler) expr(expr stm.Expression) compiledExpr {
+	return expr.Accept(c).(compiledExpr)
+}
+
+func (c *Compiler) stmt(stmt stm.Statement) compiledStmt {
+	return stmt.Accept(c).(compiledStmt)
+}
+
+func (c *Compiler) block(statements []stm.Statement) compiledStmt {
+	compiled := make([]compiledStmt, len(statements))
+
+	for index, stmt := range statements {
+		compiled[index] = c.stmt(stmt)
+	}
+
+	return func(frame *Frame) {
+		for _, stmt := range compiled {
+			if frame.interpreter.breaking {
+				return
+			}
+			stmt(frame)
+		}
+	}
+}
+
+// function compiles the body of a function, preceded by the default values
+// of the parameters a call leaves out.
+func (c *Compiler) function(parameters stm.Parameters, body []stm.Statement) compiledStmt {
+	enclosingLoopDepth := c.loopDepth
+	c.loopDepth = 0
+
+	compiled := c.block(body)
+	slots := make([]int, 0)
+	defaults := make([]compiledExpr, 0)
+
+	for index, value := range parameters.Defaults {
+		if value != nil {
+			variable, _ := c.slot(parameters.Params[index])
+			slots = append(slots, variable.index)
+			defaults = append(defaults, c.expr(value))
+		}
+	}
+
+	c.loopDepth = enclosingLoopDepth
+
+	if len(defaults) == 0 {
+		return compiled
+	}
+
+	return compiledStmt(func(frame *Frame) {
+		for index, value := range defaults {
+			if _, ok := frame.slots[slots[index]].(missing); ok {
+				frame.slots[slots[index]] = value(frame)
+			}
+		}
+
+		compiled(frame)
+	})
+}
+
+func (c *Compiler) slot(name tokens.Token) (slot, bool) {
+	local, ok := c.interpreter.locals[name]
+	return local, ok
+}
+
+// VisitAnonymousFuncExpr implements stm.ExprVisitor.
+func (c *Compiler) VisitAnonymousFuncExpr(expr *stm.AnonymousFunction) any {
+	body := c.function(expr.Parameters, expr.Body)
+
+	return compiledExpr(func(frame *Frame) any {
+		function := NewAnonymousFunction(*expr, frame)
+		function.body = body
+		return function
+	})
+}
+
+// VisitAssignExpr implements stm.ExprVisitor.
+func (c *Compiler) VisitAssignExpr(expr *stm.Assign) any {
+	value := c.expr(expr.Value)
+	name := expr.Name
+
+	if expr.Operator != nil {
+		value = c.compound(*expr.Operator, c.variable(name), value)
+	}
+
+	if local, ok := c.slot(name); ok {
+		depth, index := local.depth, local.index
+
+		return compiledExpr(func(frame *Frame) any {
+			v := value(frame)
+			frame.ancestor(depth).slots[index] = v
+			return v
+		})
+	}
+
+	return compiledExpr(func(frame *Frame) any {
+		v := value(frame)
+		frame.globals.Assign(name, v)
+		return v
+	})
+}
+
+// compound compiles the value a compound assignment stores: the operator
+// applied to the current value and the assigned one.
+func (c *Compiler) compound(operator tokens.Token, current, value compiledExpr) compiledExpr {
+	return func(frame *Frame) any {
+		return frame.interpreter.binary(operator, current(frame), value(frame))
+	}
+}
+
+// VisitAssignPatternExpr implements stm.ExprVisitor.
+func (c *Compiler) VisitAssignPatternExpr(expr *stm.AssignPattern) any {
+	value := c.expr(expr.Value)
+	destructure := c.destructure(expr.Pattern, false)
+
+	return compiledExpr(func(frame *Frame) any {
+		v := value(frame)
+		destructure(frame, v)
+		return v
+	})
+}
+
+// VisitBinaryExpr implements stm.ExprVisitor.
+func (c *Compiler) VisitBinaryExpr(expr *stm.Binary) any {
+	left := c.expr(expr.Left)
+	right := c.expr(expr.Right)
+	operator := expr.Operator
+
+	var fast func(a, b float64) any
+
+	switch operator.TokenType {
+	case tokens.MINUS:
+		fast = func(a, b float64) any { return a - b }
+	case tokens.SLASH:
+		fast = func(a, b float64) any { return a / b }
+	case tokens.STAR:
+		fast = func(a, b float64) any { return a * b }
+	case tokens.PLUS:
+		fast = func(a, b float64) any { return a + b }
+	case tokens.GREATER:
+		fast = func(a, b float64) any { return a > b }
+	case tokens.GREATER_EQUAL:
+		fast = func(a, b float64) any { return a >= b }
+	case tokens.LESS:
+		fast = func(a, b float64) any { return a < b }
+	case tokens.LESS_EQUAL:
+		fast = func(a, b float64) any { return a <= b }
+	}
+
+	if fast == nil {
+		return compiledExpr(func(frame *Frame) any {
+			return frame.interpreter.binary(operator, left(frame), right(frame))
+		})
+	}
+
+	return compiledExpr(func(frame *Frame) any {
+		l := left(frame)
+		r := right(frame)
+
+		if a, ok := l.(float64); ok {
+			if b, ok := r.(float64); ok {
+				return fast(a, b)
+			}
+		}
+
+		return frame.interpreter.binary(operator, l, r)
+	})
+}
+
+// VisitCallExpr implements stm.ExprVisitor.
+func (c *Compiler) VisitCallExpr(expr *stm.Call) any {
+	arguments := make([]compiledExpr, len(expr.Arguments))
+	paren := expr.Paren
+
+	for index, arg := range expr.Arguments {
+		arguments[index] = c.expr(arg)
+	}
+
+	tail := expr.Tail
+	names := expr.Names
+
+	evaluate := func(frame *Frame, function any) []any {
+		values := make([]any, len(arguments))
+
+		for index, arg := range arguments {
+			values[index] = arg(frame)
+		}
+		return frame.interpreter.arrange(function, values, names, paren)
+	}
+
+	call := func(frame *Frame, function any) any {
+		values := evaluate(frame, function)
+
+		if tail {
+			frame.interpreter.tailCall(function, values, paren)
+		}
+
+		return frame.interpreter.call(function, values, paren)
+	}
+
+	get, ok := expr.Callee.(*stm.Get)
+
+	if !ok {
+		callee := c.expr(expr.Callee)
+
+		return compiledExpr(func(frame *Frame) any {
+			return call(frame, callee(frame))
+		})
+	}
+
+	object := c.expr(get.Object)
+	name := get.Name
+	cache := &expr.Cache
+
+	return compiledExpr(func(frame *Frame) any {
+		i := frame.interpreter
+		value := object(frame)
+		instance, ok := value.(*LoxInstance)
+
+		if !ok {
+			return call(frame, i.getProperty(value, name))
+		}
+
+		field, method := i.findProperty(instance, name, cache)
+
+		if method == nil {
+			return call(frame, field)
+		}
+
+		values := evaluate(frame, method)
+		i.checkArity(method, values, paren)
+
+		if tail {
+			panic(TailCall{Function: method, This: instance, Arguments: values})
+		}
+
+		return method.CallMethod(i, instance, values)
+	})
+}
+
+// VisitErrorExpr implements stm.ExprVisitor.
+func (c *Compiler) VisitErrorExpr(expr *stm.Error) any {
+	value := expr.Value
+
+	return compiledExpr(func(frame *Frame) any {
+		return value
+	})
+}
+
+// VisitMatchExpr implements stm.ExprVisitor.
+func (c *Compiler) VisitMatchExpr(expr *stm.Match) any {
+	subject := c.expr(expr.Subject)
+	match := c.matchCases(expr.Cases)
+	values := make([]compiledExpr, len(expr.Cases))
+	keyword := expr.Keyword
+
+	for index, matchCase := range expr.Cases {
+		values[index] = c.expr(matchCase.Value)
+	}
+
+	return compiledExpr(func(frame *Frame) any {
+		value := subject(frame)
+		index := match(frame, value)
+
+		if index < 0 {
+			frame.interpreter.noMatch(value, keyword)
+		}
+
+		return values[index](frame)
+	})
+}
+
+// VisitAwaitExpr implements stm.ExprVisitor.
+func (c *Compiler) VisitAwaitExpr(expr *stm.Await) any {
+	value := c.expr(expr.Value)
+
+	return compiledExpr(func(frame *Frame) any {
+		return frame.interpreter.await(value(frame))
+	})
+}
+
+// VisitIndexExpr implements stm.ExprVisitor.
+func (c *Compiler) VisitIndexExpr(expr *stm.Index) any {
+	object := c.expr(expr.Object)
+	index := c.expr(expr.Index)
+	bracket := expr.Bracket
+
+	return compiledExpr(func(frame *Frame) any {
+		value := object(frame)
+		return frame.interpreter.index(value, index(frame), bracket)
+	})
+}
+
+// VisitListExpr implements stm.ExprVisitor.
+func (c *Compiler) VisitListExpr(expr *stm.List) any {
+	elements := make([]compiledExpr, len(expr.Elements))
+
+	for index, element := range expr.Elements {
+		elements[index] = c.expr(element)
+	}
+
+	return compiledExpr(func(frame *Frame) any {
+		values := make([]any, len(elements))
+
+		for index, element := range elements {
+			values[index] = element(frame)
+		}
+
+		return NewLoxList(values)
+	})
+}
+
+// VisitGetExpr implements stm.ExprVisitor.
+func (c *Compiler) VisitGetExpr(expr *stm.Get) any {
+	object := c.expr(expr.Object)
+
+	return compiledExpr(func(frame *Frame) any {
+		return frame.interpreter.getCached(object(frame), expr)
+	})
+}
+
+// VisitGroupingExpr implements stm.ExprVisitor.
+func (c *Compiler) VisitGroupingExpr(expr *stm.Grouping) any {
+	return c.expr(expr.Expression)
+}
+
+// VisitLiteralExpr implements stm.ExprVisitor.
+func (c *Compiler) VisitLiteralExpr(expr *stm.Literal) any {
+	value := expr.Value
+
+	return compiledExpr(func(frame *Frame) any {
+		return value
+	})
+}
+
+// VisitLogicalExpr implements stm.ExprVisitor.
+func (c *Compiler) VisitLogicalExpr(expr *stm.Logical) any {
+	left := c.expr(expr.Left)
+	right := c.expr(expr.Right)
+
+	if expr.Operator.TokenType == tokens.OR {
+		return compiledExpr(func(frame *Frame) any {
+			value := left(frame)
+
+			if frame.interpreter.isTruthy(value) {
+				return value
+			}
+			return right(frame)
+		})
+	}
+
+	return compiledExpr(func(frame *Frame) any {
+		value := left(frame)
+
+		if !frame.interpreter.isTruthy(value) {
+			return value
+		}
+		return right(frame)
+	})
+}
+
+// VisitSetExpr implements stm.ExprVisitor.
+func (c *Compiler) VisitSetExpr(expr *stm.Set) any {
+	object := c.expr(expr.Object)
+	value := c.expr(expr.Value)
+	name := expr.Name
+
+	if expr.Operator != nil {
+		operator := *expr.Operator
+
+		return compiledExpr(func(frame *Frame) any {
+			i := frame.interpreter
+			instance := object(frame)
+			current := i.getProperty(instance, name)
+			return i.setProperty(instance, name, i.binary(operator, current, value(frame)))
+		})
+	}
+
+	return compiledExpr(func(frame *Frame) any {
+		instance := object(frame)
+		return frame.interpreter.setProperty(instance, name, value(frame))
+	})
+}
+
+// VisitSuperExpr implements stm.ExprVisitor.
+func (c *Compiler) VisitSuperExpr(expr *stm.Super) any {
+	class := c.variable(expr.Keyword)
+
+	return compiledExpr(func(frame *Frame) any {
+		return frame.interpreter.superMethod(class(frame).(*LoxClass), expr)
+	})
+}
+
+// VisitTernaryExpr implements stm.ExprVisitor.
+func (c *Compiler) VisitTernaryExpr(expr *stm.Ternary) any {
+	condition := c.expr(expr.Condition)
+	consequent := c.expr(expr.Consequent)
+	alternative := c.expr(expr.Alternative)
+	operator := expr.Operator
+
+	return compiledExpr(func(frame *Frame) any {
+		value := condition(frame)
+
+		frame.interpreter.checkBoolOperands(operator, value)
+
+		if value.(bool) {
+			return consequent(frame)
+		}
+		return alternative(frame)
+	})
+}
+
+// VisitThisExpr implements stm.ExprVisitor.
+func (c *Compiler) VisitThisExpr(expr *stm.This) any {
+	return c.variable(expr.Keyword)
+}
+
+// VisitUnaryExpr implements stm.ExprVisitor.
+func (c *Compiler) VisitUnaryExpr(expr *stm.Unary) any {
+	right := c.expr(expr.Right)
+	operator := expr.Operator
+
+	if operator.TokenType == tokens.BANG {
+		return compiledExpr(func(frame *Frame) any {
+			return !frame.interpreter.isTruthy(right(frame))
+		})
+	}
+
+	return compiledExpr(func(frame *Frame) any {
+		value := right(frame)
+
+		if number, ok := value.(float64); ok && operator.TokenType == tokens.MINUS {
+			return -number
+		}
+		return frame.interpreter.unary(operator, value)
+	})
+}
+
+// VisitVariableExpr implements stm.ExprVisitor.
+func (c *Compiler) VisitVariableExpr(expr *stm.Variable) any {
+	return c.variable(expr.Name)
+}
+
+func (c *Compiler) variable(name tokens.Token) compiledExpr {
+	if local, ok := c.slot(name); ok {
+		depth, index := local.depth, local.index
+
+		if depth == 0 {
+			return func(frame *Frame) any {
+				return frame.slots[index]
+			}
+		}
+
+		return func(frame *Frame) any {
+			return frame.ancestor(depth).slots[index]
+		}
+	}
+
+	return func(frame *Frame) any {
+		return frame.globals.Get(name)
+	}
+}
+
+// VisitBlockStatement implements stm.StmVisitor.
+func (c *Compiler) VisitBlockStatement(stmt *stm.BlockStmt) any {
+	return c.block(stmt.Statements)
+}
+
+// VisitBreakStatement implements stm.StmVisitor.
+func (c *Compiler) VisitBreakStatement(stmt *stm.BreakStmt) any {
+	if c.loopDepth == 0 {
+		return compiledStmt(func(frame *Frame) {
+			panic("Break not in loop")
+		})
+	}
+
+	return compiledStmt(func(frame *Frame) {
+		frame.interpreter.breaking = true
+	})
+}
+
+// VisitClassStatement implements stm.StmVisitor.
+func (c *Compiler) VisitClassStatement(stmt *stm.ClassStmt) any {
+	var superClass compiledExpr = nil
+
+	if stmt.SuperClass != nil {
+		superClass = c.expr(stmt.SuperClass)
+	}
+
+	traits := make([]compiledExpr, len(stmt.Traits))
+
+	for index, trait := range stmt.Traits {
+		traits[index] = c.expr(trait)
+	}
+
+	interfaces := make([]compiledExpr, len(stmt.Interfaces))
+
+	for index, iface := range stmt.Interfaces {
+		interfaces[index] = c.expr(iface)
+	}
+
+	methods := make(map[*stm.FunctionStm]compiledStmt)
+
+	for _, method := range stmt.Methods {
+		methods[method] = c.function(method.Parameters, method.Body)
+	}
+
+	for _, method := range stmt.StaticMethods {
+		methods[method] = c.function(method.Parameters, method.Body)
+	}
+
+	for _, accessor := range stmt.Accessors() {
+		methods[accessor] = c.function(accessor.Parameters, accessor.Body)
+	}
+
+	fields := c.initializers(stmt.Fields)
+	staticFields := c.initializers(stmt.StaticFields)
+	variable, local := c.slot(stmt.Name)
+
+	return compiledStmt(func(frame *Frame) {
+		i := frame.interpreter
+		var super *LoxClass = nil
+
+		if superClass != nil {
+			value, ok := superClass(frame).(*LoxClass)
+
+			if !ok {
+				panic("Superclass must be a class.")
+			}
+
+			super = value
+		}
+
+		included := make([]any, len(traits))
+
+		for index, trait := range traits {
+			included[index] = trait(frame)
+		}
+
+		implemented := make([]any, len(interfaces))
+
+		for index, iface := range interfaces {
+			implemented[index] = iface(frame)
+		}
+
+		if !local {
+			frame.globals.Define(stmt.Name.Lexeme, nil)
+		}
+
+		class := i.newClass(stmt, super, toTraits(included), toInterfaces(implemented))
+
+		for index, field := range class.fields {
+			field.value = fields[index]
+		}
+
+		// Methods copied from traits were compiled with the trait.
+		for _, functions := range []map[string]*LoxFunction{class.Methods, class.StaticMethods, class.Getters, class.Setters, class.StaticGetters, class.StaticSetters} {
+			for _, function := range functions {
+				if body, ok := methods[function.Declaration]; ok {
+					function.body = body
+				}
+			}
+		}
+
+		if local {
+			frame.slots[variable.index] = class
+		} else {
+			frame.globals.Assign(stmt.Name, class)
+		}
+
+		i.initializeStaticFields(class, stmt, staticFields)
+	})
+}
+
+// VisitEnumStatement implements stm.StmVisitor.
+func (c *Compiler) VisitEnumStatement(stmt *stm.EnumStmt) any {
+	variable, local := c.slot(stmt.Name)
+
+	return compiledStmt(func(frame *Frame) {
+		enum := newEnum(stmt)
+
+		if local {
+			frame.slots[variable.index] = enum
+		} else {
+			frame.globals.Define(stmt.Name.Lexeme, enum)
+		}
+	})
+}
+
+// VisitInterfaceStatement implements stm.StmVisitor.
+func (c *Compiler) VisitInterfaceStatement(stmt *stm.InterfaceStmt) any {
+	variable, local := c.slot(stmt.Name)
+
+	return compiledStmt(func(frame *Frame) {
+		iface := frame.interpreter.newInterface(stmt)
+
+		if local {
+			frame.slots[variable.index] = iface
+		} else {
+			frame.globals.Define(stmt.Name.Lexeme, iface)
+		}
+	})
+}
+
+// VisitTraitStatement implements stm.StmVisitor.
+func (c *Compiler) VisitTraitStatement(stmt *stm.TraitStmt) any {
+	methods := make(map[*stm.FunctionStm]compiledStmt)
+
+	for _, method := range stmt.Methods {
+		methods[method] = c.function(method.Parameters, method.Body)
+	}
+
+	variable, local := c.slot(stmt.Name)
+
+	return compiledStmt(func(frame *Frame) {
+		trait := frame.interpreter.newTrait(stmt)
+
+		for _, function := range trait.Methods {
+			function.body = methods[function.Declaration]
+		}
+
+		if local {
+			frame.slots[variable.index] = trait
+		} else {
+			frame.globals.Define(stmt.Name.Lexeme, trait)
+		}
+	})
+}
+
+// initializers compiles the initializers of declared fields. Fields without
+// one get nil.
+func (c *Compiler) initializers(fields []*stm.VarStmt) []compiledExpr {
+	compiled := make([]compiledExpr, len(fields))
+
+	for index, field := range fields {
+		if field.Initializer != nil {
+			compiled[index] = c.expr(field.Initializer)
+		}
+	}
+
+	return compiled
+}
+
+// VisitErrorStatement implements stm.StmVisitor.
+func (c *Compiler) VisitErrorStatement(stmt *stm.ErrorStmt) any {
+	return compiledStmt(func(frame *Frame) {})
+}
+
+// VisitExprStatement implements stm.StmVisitor.
+func (c *Compiler) VisitExprStatement(stmt *stm.ExpressionStmt) any {
+	expr := c.expr(stmt.Expression)
+
+	return compiledStmt(func(frame *Frame) {
+		expr(frame)
+	})
+}
+
+// VisitFunctionStatement implements stm.StmVisitor.
+func (c *Compiler) VisitFunctionStatement(stmt *stm.FunctionStm) any {
+	body := c.function(stmt.Parameters, stmt.Body)
+	variable, local := c.slot(stmt.Name)
+
+	return compiledStmt(func(frame *Frame) {
+		function := NewLoxFunction(stmt, frame, false)
+		function.body = body
+
+		if local {
+			frame.slots[variable.index] = function
+		} else {
+			frame.globals.Define(stmt.Name.Lexeme, function)
+		}
+	})
+}
+
+// VisitIfStatement implements stm.StmVisitor.
+func (c *Compiler) VisitIfStatement(stmt *stm.IfStmt) any {
+	condition := c.expr(stmt.Condition)
+	thenBranch := c.stmt(stmt.ThenBranch)
+	elseBranch := compiledStmt(func(frame *Frame) {})
+
+	if stmt.ElseBranch != nil {
+		elseBranch = c.stmt(stmt.ElseBranch)
+	}
+
+	return compiledStmt(func(frame *Frame) {
+		if frame.interpreter.isTruthy(condition(frame)) {
+			thenBranch(frame)
+		} else {
+			elseBranch(frame)
+		}
+	})
+}
+
+// VisitImportStatement implements stm.StmVisitor.
+func (c *Compiler) VisitImportStatement(stmt *stm.ImportStmt) any {
+	return compiledStmt(func(frame *Frame) {
+		frame.interpreter.VisitImportStatement(stmt)
+	})
+}
+
+// VisitExportStatement implements stm.StmVisitor.
+func (c *Compiler) VisitExportStatement(stmt *stm.ExportStmt) any {
+	declaration := c.stmt(stmt.Declaration)
+
+	return compiledStmt(func(frame *Frame) {
+		declaration(frame)
+		frame.interpreter.export(stmt)
+	})
+}
+
+// VisitPrintStatement implements stm.StmVisitor.
+func (c *Compiler) VisitPrintStatement(stmt *stm.PrintStmt) any {
+	expr := c.expr(stmt.Expression)
+
+	return compiledStmt(func(frame *Frame) {
+		fmt.Println(frame.interpreter.stringify(expr(frame)))
+	})
+}
+
+// VisitReturnStatement implements stm.StmVisitor.
+func (c *Compiler) VisitReturnStatement(stmt *stm.ReturnStmt) any {
+	if stmt.Value == nil {
+		return compiledStmt(func(frame *Frame) {
+			panic(ReturnValue{Value: nil})
+		})
+	}
+
+	value := c.expr(stmt.Value)
+
+	return compiledStmt(func(frame *Frame) {
+		panic(ReturnValue{Value: value(frame)})
+	})
+}
+
+// VisitVarStatement implements stm.StmVisitor.
+func (c *Compiler) VisitVarStatement(stmt *stm.VarStmt) any {
+	initializer := compiledExpr(func(frame *Frame) any { return nil })
+
+	if stmt.Initializer != nil {
+		initializer = c.expr(stmt.Initializer)
+	}
+
+	if stmt.Local {
+		variable, _ := c.slot(stmt.Name)
+		index := variable.index
+
+		return compiledStmt(func(frame *Frame) {
+			frame.slots[index] = initializer(frame)
+		})
+	}
+
+	name := stmt.Name.Lexeme
+
+	if stmt.Const {
+		return compiledStmt(func(frame *Frame) {
+			frame.globals.DefineConstant(name, initializer(frame))
+		})
+	}
+
+	return compiledStmt(func(frame *Frame) {
+		frame.globals.Define(name, initializer(frame))
+	})
+}
+
+// VisitVarPatternStatement implements stm.StmVisitor.
+func (c *Compiler) VisitVarPatternStatement(stmt *stm.VarPatternStmt) any {
+	initializer := c.expr(stmt.Initializer)
+	destructure := c.destructure(stmt.Pattern, true)
+
+	if stmt.Const {
+		destructure = c.destructureConstants(stmt.Pattern)
+	}
+
+	return compiledStmt(func(frame *Frame) {
+		destructure(frame, initializer(frame))
+	})
+}
+
+// binders resolves the names a pattern binds to the slots or globals they
+// are stored in once, when a destructuring is compiled. declaring chooses
+// between defining and assigning globals.
+func (c *Compiler) binders(pattern stm.Pattern, declaring bool) map[tokens.Token]func(frame *Frame, value any) {
+	setters := make(map[tokens.Token]func(frame *Frame, value any))
+
+	for _, name := range stm.Bindings(pattern) {
+		name := name
+
+		if local, ok := c.slot(name); ok {
+			depth, index := local.depth, local.index
+
+			setters[name] = func(frame *Frame, value any) {
+				frame.ancestor(depth).slots[index] = value
+			}
+		} else if declaring {
+			setters[name] = func(frame *Frame, value any) {
+				frame.globals.Define(name.Lexeme, value)
+			}
+		} else {
+			setters[name] = func(frame *Frame, value any) {
+				frame.globals.Assign(name, value)
+			}
+		}
+	}
+
+	return setters
+}
+
+// destructureConstants compiles a const destructuring declaration of
+// pattern.
+func (c *Compiler) destructureConstants(pattern stm.Pattern) func(frame *Frame, value any) {
+	setters := c.binders(pattern, true)
+
+	for _, name := range stm.Bindings(pattern) {
+		name := name
+
+		if _, ok := c.slot(name); !ok {
+			setters[name] = func(frame *Frame, value any) {
+				frame.globals.DefineConstant(name.Lexeme, value)
+			}
+		}
+	}
+
+	return func(frame *Frame, value any) {
+		frame.interpreter.destructure(pattern, value, func(name tokens.Token, value any) {
+			setters[name](frame, value)
+		})
+	}
+}
+
+// destructure compiles a destructuring declaration or assignment of pattern.
+func (c *Compiler) destructure(pattern stm.Pattern, declaring bool) func(frame *Frame, value any) {
+	setters := c.binders(pattern, declaring)
+
+	return func(frame *Frame, value any) {
+		frame.interpreter.destructure(pattern, value, func(name tokens.Token, value any) {
+			setters[name](frame, value)
+		})
+	}
+}
+
+// VisitForInStatement implements stm.StmVisitor.
+func (c *Compiler) VisitForInStatement(stmt *stm.ForInStmt) any {
+	iterable := c.expr(stmt.Iterable)
+	slots := make([]int, len(stmt.Names))
+
+	for index, name := range stmt.Names {
+		variable, _ := c.slot(name)
+		slots[index] = variable.index
+	}
+
+	c.loopDepth++
+	body := c.stmt(stmt.Body)
+	c.loopDepth--
+
+	return compiledStmt(func(frame *Frame) {
+		i := frame.interpreter
+		next, stop := i.iterate(iterable(frame), stmt.Keyword)
+		defer stop()
+
+		for {
+			value, ok := next()
+
+			if !ok {
+				break
+			}
+
+			for index, value := range unpack(value, len(slots), stmt.Keyword) {
+				frame.slots[slots[index]] = value
+			}
+
+			body(frame)
+
+			if i.breaking {
+				i.breaking = false
+				break
+			}
+		}
+	})
+}
+
+// VisitYieldStatement implements stm.StmVisitor.
+func (c *Compiler) VisitYieldStatement(stmt *stm.YieldStmt) any {
+	value := c.expr(stmt.Value)
+
+	return compiledStmt(func(frame *Frame) {
+		frame.interpreter.yield(value(frame))
+	})
+}
+
+// VisitSpawnStatement implements stm.StmVisitor.
+func (c *Compiler) VisitSpawnStatement(stmt *stm.SpawnStmt) any {
+	callee := c.expr(stmt.Call.Callee)
+	arguments := make([]compiledExpr, len(stmt.Call.Arguments))
+	paren := stmt.Call.Paren
+
+	for index, arg := range stmt.Call.Arguments {
+		arguments[index] = c.expr(arg)
+	}
+
+	return compiledStmt(func(frame *Frame) {
+		function := callee(frame)
+		values := make([]any, len(arguments))
+
+		for index, arg := range arguments {
+			values[index] = arg(frame)
+		}
+
+		frame.interpreter.spawn(function, values, paren)
+	})
+}
+
+// VisitSelectStatement implements stm.StmVisitor.
+func (c *Compiler) VisitSelectStatement(stmt *stm.SelectStmt) any {
+	channels := make([]compiledExpr, len(stmt.Cases))
+	values := make([]compiledExpr, len(stmt.Cases))
+	slots := make([]int, len(stmt.Cases))
+	bodies := make([]compiledStmt, len(stmt.Cases))
+
+	for index, selectCase := range stmt.Cases {
+		channels[index] = c.expr(selectCase.Channel)
+		slots[index] = -1
+
+		if selectCase.Value != nil {
+			values[index] = c.expr(selectCase.Value)
+		}
+
+		if selectCase.Name != nil {
+			variable, _ := c.slot(*selectCase.Name)
+			slots[index] = variable.index
+		}
+
+		bodies[index] = c.stmt(selectCase.Body)
+	}
+
+	var defaultCase compiledStmt
+
+	if stmt.Default != nil {
+		defaultCase = c.stmt(stmt.Default)
+	}
+
+	return compiledStmt(func(frame *Frame) {
+		ops := make([]channelOp, len(stmt.Cases))
+
+		for index, selectCase := range stmt.Cases {
+			var value any
+
+			if values[index] != nil {
+				value = values[index](frame)
+			}
+
+			ops[index] = newChannelOp(channels[index](frame), selectCase, value)
+		}
+
+		chosen, value, _ := frame.interpreter.selectOp(ops, defaultCase == nil)
+
+		if chosen < 0 {
+			defaultCase(frame)
+			return
+		}
+
+		if slots[chosen] >= 0 {
+			frame.slots[slots[chosen]] = value
+		}
+
+		bodies[chosen](frame)
+	})
+}
+
+// VisitMatchStatement implements stm.StmVisitor.
+func (c *Compiler) VisitMatchStatement(stmt *stm.MatchStmt) any {
+	subject := c.expr(stmt.Subject)
+	match := c.matchCases(stmt.Cases)
+	bodies := make([]compiledStmt, len(stmt.Cases))
+
+	for index, matchCase := range stmt.Cases {
+		bodies[index] = c.stmt(matchCase.Body)
+	}
+
+	return compiledStmt(func(frame *Frame) {
+		if index := match(frame, subject(frame)); index >= 0 {
+			bodies[index](frame)
+		}
+	})
+}
+
+// matchCases compiles the patterns and guards of a match into a function
+// returning the index of the first case that matches, or -1.
+func (c *Compiler) matchCases(cases []*stm.MatchCase) func(frame *Frame, value any) int {
+	patterns := make([]compiledPattern, len(cases))
+	guards := make([]compiledExpr, len(cases))
+
+	for index, matchCase := range cases {
+		patterns[index] = c.pattern(matchCase.Pattern)
+
+		if matchCase.Guard != nil {
+			guards[index] = c.expr(matchCase.Guard)
+		}
+	}
+
+	return func(frame *Frame, value any) int {
+		for index, pattern := range patterns {
+			if !pattern(frame, value) {
+				continue
+			}
+
+			if guards[index] == nil || frame.interpreter.isTruthy(guards[index](frame)) {
+				return index
+			}
+		}
+		return -1
+	}
+}
+
+// compiledPattern reports whether value matches, binding names in frame as
+// it goes.
+type compiledPattern func(frame *Frame, value any) bool
+
+func (c *Compiler) pattern(pattern stm.Pattern) compiledPattern {
+	switch p := pattern.(type) {
+	case *stm.LiteralPattern:
+		literal := p.Value
+
+		return func(frame *Frame, value any) bool {
+			return frame.interpreter.equals(value, literal)
+		}
+	case *stm.BindingPattern:
+		variable, _ := c.slot(p.Name)
+		index := variable.index
+
+		return func(frame *Frame, value any) bool {
+			frame.slots[index] = value
+			return true
+		}
+	case *stm.ValuePattern:
+		expected := c.expr(p.Value)
+
+		return func(frame *Frame, value any) bool {
+			return frame.interpreter.equals(value, expected(frame))
+		}
+	case *stm.ListPattern:
+		elements := c.patterns(p.Elements)
+		var restPattern compiledPattern
+
+		if p.Rest != nil {
+			restPattern = c.pattern(p.Rest)
+		}
+
+		return func(frame *Frame, value any) bool {
+			values, ok := listOfLength(value, len(elements), restPattern != nil)
+
+			if !ok {
+				return false
+			}
+
+			for index, element := range elements {
+				if !element(frame, values[index]) {
+					return false
+				}
+			}
+			return restPattern == nil || restPattern(frame, rest(values, len(elements)))
+		}
+	case *stm.MapPattern:
+		keys := p.Keys
+		values := c.patterns(p.Values)
+
+		return func(frame *Frame, value any) bool {
+			for index, key := range keys {
+				member, ok := frame.interpreter.member(value, key)
+
+				if !ok || !values[index](frame, member) {
+					return false
+				}
+			}
+			return true
+		}
+	case *stm.ClassPattern:
+		class := c.expr(p.Class)
+		patterns := append(c.patterns(p.Positional), c.patterns(p.Named)...)
+
+		return func(frame *Frame, value any) bool {
+			fields, ok := frame.interpreter.classFields(value, class(frame), p)
+
+			if !ok {
+				return false
+			}
+
+			for index, field := range fields {
+				if !patterns[index](frame, field) {
+					return false
+				}
+			}
+			return true
+		}
+	}
+
+	return func(frame *Frame, value any) bool {
+		return true
+	}
+}
+
+func (c *Compiler) patterns(patterns []stm.Pattern) []compiledPattern {
+	compiled := make([]compiledPattern, len(patterns))
+
+	for index, pattern := range patterns {
+		compiled[index] = c.pattern(pattern)
+	}
+
+	return compiled
+}
+
+// VisitWhileStatement implements stm.StmVisitor.
+func (c *Compiler) VisitWhileStatement(stmt *stm.WhileStmt) any {
+	condition := c.expr(stmt.Condition)
+
+	c.loopDepth++
+	body := c.stmt(stmt.Body)
+	c.loopDepth--
+
+	return compiledStmt(func(frame *Frame) {
+		i := frame.interpreter
+
+		for i.isTruthy(condition(frame)) {
+			body(frame)
+
+			if i.breaking {
+				i.breaking = false
+				break
+			}
+		}
+	})
+}
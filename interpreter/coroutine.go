@@ -0,0 +1,74 @@
+package interpreter
+
+// coroutine runs the body of a generator or an async function on a goroutine
+// of its own. The body and whoever resumes it take turns: one waits while
+// the other runs, so the body runs as part of the resuming fiber.
+type coroutine struct {
+	resume  chan bool
+	steps   chan coroutineStep
+	started bool
+	done    bool
+}
+
+// coroutineStep is what the body produced when it stopped: the value it
+// suspended with, or its result once it finished, possibly with a runtime
+// error.
+type coroutineStep struct {
+	value any
+	done  bool
+	err   any
+}
+
+// coroutineExit unwinds the body of a coroutine stopped before it finished.
+type coroutineExit struct{}
+
+// start runs body on its own goroutine once the coroutine is first resumed.
+func (c *coroutine) start(body func() any) {
+	c.resume = make(chan bool)
+	c.steps = make(chan coroutineStep)
+	c.started = true
+
+	go func() {
+		var result any
+
+		defer func() {
+			switch value := recover().(type) {
+			case nil:
+				c.steps <- coroutineStep{value: result, done: true}
+			case coroutineExit:
+				c.steps <- coroutineStep{done: true}
+			default:
+				c.steps <- coroutineStep{done: true, err: value}
+			}
+		}()
+
+		if !<-c.resume {
+			panic(coroutineExit{})
+		}
+
+		result = body()
+	}()
+}
+
+// step resumes the body, or stops it when resume is false, and waits until
+// it suspends or finishes.
+func (c *coroutine) step(resume bool) coroutineStep {
+	c.resume <- resume
+	step := <-c.steps
+
+	if step.done {
+		c.done = true
+	}
+
+	return step
+}
+
+// suspend is called by the body to hand value to the resuming fiber. It
+// returns once the body is resumed, and unwinds the body if it is stopped.
+func (c *coroutine) suspend(value any) {
+	c.steps <- coroutineStep{value: value}
+
+	if !<-c.resume {
+		panic(coroutineExit{})
+	}
+}
@@ -0,0 +1,78 @@
+package interpreter
+
+import (
+	"fmt"
+	stm "lox/statement"
+	"lox/tokens"
+)
+
+// binder stores the value of a name a destructuring pattern binds.
+type binder func(name tokens.Token, value any)
+
+// destructure binds the names of a list or map pattern to the parts of
+// value. Unlike matching, a value that doesn't fit the pattern is an error.
+func (i *Interpreter) destructure(pattern stm.Pattern, value any, bind binder) {
+	switch p := pattern.(type) {
+	case *stm.BindingPattern:
+		bind(p.Name, value)
+	case *stm.ListPattern:
+		elements, ok := listOfLength(value, len(p.Elements), p.Rest != nil)
+
+		if !ok {
+			if p.Rest != nil {
+				panic(fmt.Sprintf("Can't destructure %s into at least %d elements.\n [line %d]", i.stringify(value), len(p.Elements), p.Bracket.Line))
+			}
+			panic(fmt.Sprintf("Can't destructure %s into %d elements.\n [line %d]", i.stringify(value), len(p.Elements), p.Bracket.Line))
+		}
+
+		for index, element := range p.Elements {
+			i.destructure(element, elements[index], bind)
+		}
+
+		if p.Rest != nil {
+			i.destructure(p.Rest, rest(elements, len(p.Elements)), bind)
+		}
+	case *stm.MapPattern:
+		for index, key := range p.Keys {
+			member, ok := i.member(value, key)
+
+			if !ok {
+				panic(fmt.Sprintf("Can't destructure %s: it has no key or field %s.\n [line %d]", i.stringify(value), i.stringify(key), p.Brace.Line))
+			}
+
+			i.destructure(p.Values[index], member, bind)
+		}
+	}
+}
+
+// rest returns a new list of the elements after the first count.
+func rest(elements []any, count int) *LoxList {
+	return NewLoxList(append([]any{}, elements[count:]...))
+}
+
+// declare binds a name a declaration destructures into.
+func (i *Interpreter) declare(name tokens.Token, value any) {
+	if local, ok := i.locals[name]; ok {
+		i.frame.slots[local.index] = value
+	} else {
+		i.frame.globals.Define(name.Lexeme, value)
+	}
+}
+
+// declareConstant binds a name a const declaration destructures into.
+func (i *Interpreter) declareConstant(name tokens.Token, value any) {
+	if local, ok := i.locals[name]; ok {
+		i.frame.slots[local.index] = value
+	} else {
+		i.frame.globals.DefineConstant(name.Lexeme, value)
+	}
+}
+
+// assign stores into the variable an assignment destructures into.
+func (i *Interpreter) assign(name tokens.Token, value any) {
+	if local, ok := i.locals[name]; ok {
+		i.frame.Set(local.depth, local.index, value)
+	} else {
+		i.frame.globals.Assign(name, value)
+	}
+}
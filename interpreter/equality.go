@@ -0,0 +1,133 @@
+package interpreter
+
+import (
+	"fmt"
+	"lox/tokens"
+	"reflect"
+)
+
+// Methods a class defines to be compared by value and to be used as a key
+// of a Map or an element of a Set.
+const (
+	EQUALS_METHOD = "equals"
+	HASH_METHOD   = "hash"
+)
+
+// equalityMethods are the methods that define equality, __eq__ and equals,
+// in the order they are asked. Both are used by ==, Maps and Sets.
+var equalityMethods = []string{EQ_METHOD, EQUALS_METHOD}
+
+// hashed is the key a Map stores an instance under when its class defines
+// hash(). It keeps those keys apart from numbers and strings with the same
+// value.
+type hashed struct {
+	hash any
+}
+
+// equals compares with __eq__ or equals() when either operand defines one,
+// asking the left one first. Otherwise instances are only equal to
+// themselves. Enum values are equal when they have the same variant and equal
+// values, and lists when they have equal elements in the same order.
+func (i *Interpreter) equals(left, right any) bool {
+	if left, ok := left.(*LoxEnumValue); ok {
+		if right, ok := right.(*LoxEnumValue); ok {
+			return i.sameEnumValue(left, right)
+		}
+	}
+
+	if left, ok := left.(*LoxList); ok {
+		if right, ok := right.(*LoxList); ok {
+			return i.sameElements(left, right)
+		}
+	}
+
+	for _, name := range equalityMethods {
+		if result, ok := i.callSpecial(left, name, tokens.Token{}, right); ok {
+			return i.isTruthy(result)
+		}
+
+		if result, ok := i.callSpecial(right, name, tokens.Token{}, left); ok {
+			return i.isTruthy(result)
+		}
+	}
+
+	return identical(left, right)
+}
+
+// identical compares numbers, strings and booleans by value and everything
+// else by identity. A method bound twice to the same receiver is the same
+// method.
+func identical(left, right any) bool {
+	if method, ok := left.(*BoundMethod); ok {
+		other, ok := right.(*BoundMethod)
+		return ok && method.Method == other.Method && identical(method.This, other.This)
+	}
+
+	if left == nil || right == nil {
+		return left == nil && right == nil
+	}
+
+	kind := reflect.TypeOf(left)
+
+	if kind != reflect.TypeOf(right) || !kind.Comparable() {
+		return false
+	}
+
+	return left == right
+}
+
+func (i *Interpreter) sameElements(left, right *LoxList) bool {
+	if left == right {
+		return true
+	}
+
+	if len(left.elements) != len(right.elements) {
+		return false
+	}
+
+	for index, element := range left.elements {
+		if !i.equals(element, right.elements[index]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// hashKey returns the key a Map stores value under. Values that are equal
+// according to equals get the same key. Lists are compared by their elements,
+// which can change, so they can't be keys.
+func (i *Interpreter) hashKey(value any) any {
+	if enumValue, ok := value.(*LoxEnumValue); ok {
+		return i.enumHashKey(enumValue)
+	}
+
+	if _, ok := value.(*LoxList); ok {
+		panic(fmt.Sprintf("List %s can't be used as a key.", i.stringify(value)))
+	}
+
+	instance, ok := value.(*LoxInstance)
+
+	if !ok {
+		if value != nil && !reflect.TypeOf(value).Comparable() {
+			panic(fmt.Sprintf("Value %s can't be used as a key.", i.stringify(value)))
+		}
+		return value
+	}
+
+	if result, ok := i.callSpecial(instance, HASH_METHOD, tokens.Token{}); ok {
+		switch result.(type) {
+		case float64, string:
+			return hashed{result}
+		}
+		panic(fmt.Sprintf("%s() must return a number or a string.", HASH_METHOD))
+	}
+
+	for _, name := range equalityMethods {
+		if _, _, ok := specialMethod(instance, name); ok {
+			panic(fmt.Sprintf("Class %s defines %s() but not %s(), so its instances can't be used as keys.", instance.class.Name, name, HASH_METHOD))
+		}
+	}
+
+	return instance
+}
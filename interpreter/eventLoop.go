@@ -0,0 +1,211 @@
+package interpreter
+
+import (
+	"container/heap"
+	"fmt"
+	"math"
+	"time"
+)
+
+// eventLoop runs what a script scheduled for later, once the script itself
+// has finished: the reactions to settled promises first, as microtasks, and
+// then timers in the order they are due. With a virtual clock, time jumps
+// straight to the next timer instead of passing.
+type eventLoop struct {
+	virtual    bool
+	started    time.Time
+	now        float64
+	timers     timerQueue
+	active     map[float64]*timer
+	sequence   int
+	microtasks []func(interpreter *Interpreter)
+	unhandled  []*LoxPromise
+}
+
+type timer struct {
+	id       float64
+	due      float64
+	interval float64
+	callback Callable
+	sequence int
+}
+
+// timerQueue orders timers by when they are due, and then by when they were
+// scheduled.
+type timerQueue []*timer
+
+func newEventLoop() *eventLoop {
+	return &eventLoop{
+		started: time.Now(),
+		active:  make(map[float64]*timer),
+	}
+}
+
+// elapsed is how many milliseconds have passed since the loop was created.
+func (l *eventLoop) elapsed() float64 {
+	if l.virtual {
+		return l.now
+	}
+
+	return float64(time.Since(l.started)) / float64(time.Millisecond)
+}
+
+// clock is what the clock() builtin returns: milliseconds since the epoch,
+// or since the start with a virtual clock.
+func (l *eventLoop) clock() float64 {
+	if l.virtual {
+		return l.now
+	}
+
+	return float64(time.Now().UnixNano() / int64(time.Millisecond))
+}
+
+func (l *eventLoop) queueMicrotask(task func(interpreter *Interpreter)) {
+	l.microtasks = append(l.microtasks, task)
+}
+
+func (l *eventLoop) schedule(callback Callable, delay float64, interval float64) float64 {
+	l.sequence++
+
+	timer := &timer{
+		id:       float64(l.sequence),
+		due:      l.elapsed() + delay,
+		interval: interval,
+		callback: callback,
+		sequence: l.sequence,
+	}
+
+	l.active[timer.id] = timer
+	heap.Push(&l.timers, timer)
+
+	return timer.id
+}
+
+// next waits until the earliest timer is due and returns it, or nil when
+// no timers are left.
+func (l *eventLoop) next() *timer {
+	for len(l.timers) > 0 {
+		timer := heap.Pop(&l.timers).(*timer)
+
+		if _, ok := l.active[timer.id]; !ok {
+			continue
+		}
+
+		if l.virtual {
+			l.now = math.Max(l.now, timer.due)
+		} else if wait := timer.due - l.elapsed(); wait > 0 {
+			time.Sleep(time.Duration(wait * float64(time.Millisecond)))
+		}
+
+		if timer.interval > 0 {
+			l.sequence++
+			timer.due += timer.interval
+			timer.sequence = l.sequence
+			heap.Push(&l.timers, timer)
+		} else {
+			delete(l.active, timer.id)
+		}
+
+		return timer
+	}
+
+	return nil
+}
+
+func (q timerQueue) Len() int {
+	return len(q)
+}
+
+func (q timerQueue) Less(a, b int) bool {
+	if q[a].due != q[b].due {
+		return q[a].due < q[b].due
+	}
+	return q[a].sequence < q[b].sequence
+}
+
+func (q timerQueue) Swap(a, b int) {
+	q[a], q[b] = q[b], q[a]
+}
+
+func (q *timerQueue) Push(value any) {
+	*q = append(*q, value.(*timer))
+}
+
+func (q *timerQueue) Pop() any {
+	old := *q
+	last := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return last
+}
+
+func newTimerFunction(name string, repeat bool) func(interpreter *Interpreter, args []any) any {
+	return func(interpreter *Interpreter, args []any) any {
+		callback := callback(args[0], name+"()", 0)
+		delay, ok := args[1].(float64)
+
+		if !ok || delay < 0 || repeat && delay == 0 {
+			panic(fmt.Sprintf("Delay of %s() must be a positive number.", name))
+		}
+
+		interval := 0.0
+
+		if repeat {
+			interval = delay
+		}
+
+		return interpreter.loop.schedule(callback, delay, interval)
+	}
+}
+
+func clearTimer(interpreter *Interpreter, args []any) any {
+	if id, ok := args[0].(float64); ok {
+		delete(interpreter.loop.active, id)
+	}
+	return nil
+}
+
+// UseVirtualClock makes timers fire as soon as everything before them has
+// run, as if the time they wait for had passed, so scripts using them run
+// the same way every time.
+func (i *Interpreter) UseVirtualClock() {
+	i.loop.virtual = true
+}
+
+// RunEventLoop runs the microtasks and timers scheduled by the script until
+// none are left. A runtime error in one of them, or a rejected promise
+// nothing handles, stops the loop.
+func (i *Interpreter) RunEventLoop() {
+	defer i.afterPanic()
+	defer i.closeAbandoned()
+
+	for {
+		i.runMicrotasks()
+
+		timer := i.loop.next()
+
+		if timer == nil {
+			return
+		}
+
+		timer.callback.Call(i, []any{})
+		i.awaitFibers()
+	}
+}
+
+// runMicrotasks runs the reactions to settled promises, including those
+// they queue in turn.
+func (i *Interpreter) runMicrotasks() {
+	for len(i.loop.microtasks) > 0 {
+		task := i.loop.microtasks[0]
+		i.loop.microtasks = i.loop.microtasks[1:]
+		task(i)
+		i.awaitFibers()
+	}
+
+	for _, promise := range i.loop.unhandled {
+		if !promise.handled {
+			panic(fmt.Sprintf("Unhandled promise rejection: %s", i.stringify(promise.value)))
+		}
+	}
+	i.loop.unhandled = nil
+}
@@ -0,0 +1,95 @@
+package interpreter
+
+import (
+	"lox/tokens"
+	"sync"
+)
+
+const DEADLOCK_ERROR = "Deadlock: every fiber is waiting on a channel."
+
+// scheduler lets the fibers of a program take turns. Each fiber runs on its
+// own goroutine, but only the one holding lock runs Lox code; it lets go
+// only while it waits on a channel. Values and frames are shared between
+// fibers, and this keeps them from being used by two at once.
+type scheduler struct {
+	lock       sync.Mutex
+	wake       *sync.Cond
+	fibers     int
+	blocked    int
+	deadlocked bool
+}
+
+// fiberExit unwinds the fibers left waiting once a deadlock was reported.
+type fiberExit struct{}
+
+func newScheduler() *scheduler {
+	scheduler := &scheduler{fibers: 1}
+	scheduler.wake = sync.NewCond(&scheduler.lock)
+	scheduler.lock.Lock()
+
+	return scheduler
+}
+
+// spawn calls callee with arguments on a new fiber. The new fiber starts
+// once the current one waits on a channel or finishes.
+func (i *Interpreter) spawn(callee any, arguments []any, paren tokens.Token) {
+	fiber := i.fork()
+	i.scheduler.fibers++
+
+	go func() {
+		i.scheduler.lock.Lock()
+		defer i.scheduler.lock.Unlock()
+		defer fiber.exit()
+		defer fiber.afterPanic()
+		defer fiber.closeAbandoned()
+
+		fiber.call(callee, arguments, paren)
+	}()
+}
+
+func (i *Interpreter) exit() {
+	s := i.scheduler
+	s.fibers--
+
+	if s.fibers > 0 && s.blocked == s.fibers && !s.deadlocked {
+		s.deadlocked = true
+		i.errorLogger.RuntimeError(DEADLOCK_ERROR)
+	}
+
+	s.wake.Broadcast()
+}
+
+// block waits until another fiber completes the selection. A fiber that
+// would leave every fiber waiting reports a deadlock instead.
+func (i *Interpreter) block(selection *selection) {
+	s := i.scheduler
+	s.blocked++
+
+	if s.blocked == s.fibers && !s.deadlocked {
+		s.deadlocked = true
+		s.wake.Broadcast()
+		panic(DEADLOCK_ERROR)
+	}
+
+	for !selection.done {
+		if s.deadlocked {
+			panic(fiberExit{})
+		}
+
+		s.wake.Wait()
+	}
+}
+
+// awaitFibers waits until the fibers spawned by the script have finished.
+func (i *Interpreter) awaitFibers() {
+	s := i.scheduler
+	i.exit()
+
+	for s.fibers > 0 {
+		s.wake.Wait()
+	}
+
+	s.fibers = 1
+	s.blocked = 0
+	s.deadlocked = false
+}
@@ -0,0 +1,56 @@
+package interpreter
+
+import (
+	env "lox/environment"
+)
+
+// Frame holds the local variables of one function call, or of the top-level
+// script. Slots are numbered by the resolver; parent is the frame the function
+// was declared in, so closures keep seeing the variables they captured.
+// Frames share the globals of the module their code was written in.
+type Frame struct {
+	slots       []any
+	parent      *Frame
+	globals     *env.Environment
+	interpreter *Interpreter
+}
+
+func NewFrame(size int, parent *Frame, interpreter *Interpreter) *Frame {
+	return &Frame{
+		slots:       make([]any, size),
+		parent:      parent,
+		globals:     parent.globals,
+		interpreter: interpreter,
+	}
+}
+
+func newModuleFrame(globals *env.Environment, interpreter *Interpreter) *Frame {
+	return &Frame{
+		slots:       make([]any, 0),
+		globals:     globals,
+		interpreter: interpreter,
+	}
+}
+
+func (f *Frame) ancestor(depth int) *Frame {
+	frame := f
+
+	for i := 0; i < depth; i++ {
+		frame = frame.parent
+	}
+	return frame
+}
+
+func (f *Frame) Get(depth int, index int) any {
+	return f.ancestor(depth).slots[index]
+}
+
+func (f *Frame) Set(depth int, index int, value any) {
+	f.ancestor(depth).slots[index] = value
+}
+
+// slot is where the resolver found a local variable.
+type slot struct {
+	depth int
+	index int
+}
@@ -8,45 +8,141 @@ import (
 	"lox/tokens"
 	"reflect"
 	"strings"
-	"time"
+	"sync"
 )
 
 type ReturnValue struct {
 	Value any
 }
 
+// TailCall unwinds the calling function so its LoxFunction.Call loop can run
+// the callee in the same Go stack frame.
+type TailCall struct {
+	Function  *LoxFunction
+	This      any
+	Arguments []any
+}
+
+// Interpreter runs one fiber of a program: the main script, a spawned
+// function or the body of a generator. Its fields are the fiber's own state;
+// what the fibers share lives in process.
 type Interpreter struct {
-	errorLogger          interfaces.ErrorLogger
-	environment          *env.Environment
-	nearestEnclosingLoop []*stm.WhileStmt
+	*process
+	nearestEnclosingLoop []stm.Statement
 	breaking             bool
-	globals              *env.Environment
-	locals               map[tokens.Token]int
-	LocalVariables       []any
+	root                 *Frame
+	frame                *Frame
+	module               *LoxModule
+	importing            []*LoxModule
+	generator            *generatorRoutine
+	async                *asyncRoutine
+}
+
+type process struct {
+	errorLogger   interfaces.ErrorLogger
+	builtins      *env.Environment
+	locals        map[tokens.Token]slot
+	compiler      *Compiler
+	loader        ModuleLoader
+	modules       map[string]*LoxModule
+	abandoned     []*generatorRoutine
+	suspended     map[*generatorRoutine]bool
+	generatorLock sync.Mutex
+	scheduler     *scheduler
+	loop          *eventLoop
 }
 
 func NewInterpreter(errorLogger interfaces.ErrorLogger) *Interpreter {
-	globals := env.NewEnvironment()
+	builtins := env.NewEnvironment()
 
 	var clockCallable Callable = NewNativeFnCallable(
-		func() int { return 0 },
+		exactly(0),
 		func(interpreter *Interpreter, args []any) any {
-			return time.Now().UnixNano() / int64(time.Millisecond)
+			return interpreter.loop.clock()
 		})
 
-	globals.Define("clock", clockCallable)
-
-	return &Interpreter{
-		errorLogger:    errorLogger,
-		environment:    globals,
-		globals:        globals,
-		locals:         make(map[tokens.Token]int),
-		LocalVariables: make([]any, 0),
+	builtins.Define("clock", clockCallable)
+	builtins.Define("Map", NewNativeFnCallable(
+		exactly(0),
+		func(interpreter *Interpreter, args []any) any {
+			return NewLoxMap()
+		}))
+	builtins.Define("Set", NewNativeFnCallable(
+		exactly(0),
+		func(interpreter *Interpreter, args []any) any {
+			return NewLoxSet()
+		}))
+	builtins.Define("freeze", NewNativeFnCallable(exactly(1), freeze))
+	builtins.Define("range", NewNativeFnCallable(between(1, 3), newRange))
+	builtins.Define("Channel", NewNativeFnCallable(exactly(1), newChannel))
+	builtins.Define("Promise", NewNativeFnCallable(exactly(1), newPromise))
+	builtins.Define("setTimeout", NewNativeFnCallable(exactly(2), newTimerFunction("setTimeout", false)))
+	builtins.Define("setInterval", NewNativeFnCallable(exactly(2), newTimerFunction("setInterval", true)))
+	builtins.Define("clearTimeout", NewNativeFnCallable(exactly(1), clearTimer))
+	builtins.Define("clearInterval", NewNativeFnCallable(exactly(1), clearTimer))
+
+	interpreter := &Interpreter{
+		process: &process{
+			errorLogger: errorLogger,
+			builtins:    builtins,
+			locals:      make(map[tokens.Token]slot),
+			modules:     make(map[string]*LoxModule),
+			suspended:   make(map[*generatorRoutine]bool),
+			scheduler:   newScheduler(),
+			loop:        newEventLoop(),
+		},
+		module: NewLoxModule("", env.NewEnvironment(builtins)),
 	}
+
+	interpreter.root = newModuleFrame(interpreter.module.globals, interpreter)
+	interpreter.frame = interpreter.root
+
+	return interpreter
+}
+
+// UseCompiler switches the interpreter from walking the tree to running
+// statements compiled into Go closures.
+func (i *Interpreter) UseCompiler() {
+	i.compiler = NewCompiler(i)
+}
+
+// SetModuleLoader enables imports.
+func (i *Interpreter) SetModuleLoader(loader ModuleLoader) {
+	i.loader = loader
+}
+
+// SetScriptPath names the file being run, so its imports are found relative
+// to it and importing it back is reported as a cycle.
+func (i *Interpreter) SetScriptPath(path string) {
+	i.module.Path = path
+	i.module.loading = true
+	i.modules[path] = i.module
 }
 
 func (i *Interpreter) Interpret(statements []stm.Statement) {
 	defer i.afterPanic()
+	defer i.closeAbandoned()
+
+	i.run(statements)
+	i.awaitFibers()
+}
+
+// fork returns an interpreter for a new fiber, starting out in the module i
+// is running.
+func (i *Interpreter) fork() *Interpreter {
+	return &Interpreter{
+		process: i.process,
+		root:    i.root,
+		frame:   i.root,
+		module:  i.module,
+	}
+}
+
+func (i *Interpreter) run(statements []stm.Statement) {
+	if i.compiler != nil {
+		i.compiler.Compile(statements)(i.root)
+		return
+	}
 
 	for _, stmt := range statements {
 		i.execute(stmt)
@@ -75,22 +171,21 @@ func (i *Interpreter) execute(stmt stm.Statement) {
 }
 
 func (i *Interpreter) Resolve(token tokens.Token, depth int, index int) {
-	i.locals[token] = index
+	i.locals[token] = slot{depth: depth, index: index}
 }
 
-func (i *Interpreter) executeBlock(statements []stm.Statement, environment *env.Environment) {
-	previousEnv := i.environment
-
-	defer func() {
-		i.environment = previousEnv
-	}()
-
-	i.environment = environment
+// ReserveSlots makes room in the top-level frame for locals declared in
+// top-level blocks.
+func (i *Interpreter) ReserveSlots(count int) {
+	for len(i.root.slots) < count {
+		i.root.slots = append(i.root.slots, nil)
+	}
+}
 
+func (i *Interpreter) executeBlock(statements []stm.Statement) {
 	for _, stmt := range statements {
 		i.execute(stmt)
 	}
-
 }
 
 func (i *Interpreter) evaluate(expr stm.Expression) any {
@@ -98,18 +193,126 @@ func (i *Interpreter) evaluate(expr stm.Expression) any {
 }
 
 func (i *Interpreter) VisitFunctionStatement(stmt *stm.FunctionStm) any {
-	index, ok := i.locals[stmt.Name]
-	function := NewLoxFunction(*stmt, i.environment, false)
+	local, ok := i.locals[stmt.Name]
+	function := NewLoxFunction(stmt, i.frame, false)
 
 	if ok {
-		i.LocalVariables[index] = function
+		i.frame.slots[local.index] = function
 	} else {
-		i.globals.Define(stmt.Name.Lexeme, function)
+		i.frame.globals.Define(stmt.Name.Lexeme, function)
+	}
+
+	return nil
+}
+
+func (i *Interpreter) VisitImportStatement(stmt *stm.ImportStmt) any {
+	module := i.importModule(stmt)
+
+	if stmt.Alias != nil {
+		i.frame.globals.Define(stmt.Alias.Lexeme, module)
+	}
+
+	for _, name := range stmt.Names {
+		value, err := module.Get(name, i)
+
+		if err != nil {
+			panic(fmt.Sprintf("line[%d] %s", name.Line, err.Error()))
+		}
+
+		if module.constant(name) {
+			i.frame.globals.DefineConstant(name.Lexeme, value)
+		} else {
+			i.frame.globals.Define(name.Lexeme, value)
+		}
 	}
 
 	return nil
 }
 
+// importModule returns the module stmt refers to, running it first if this
+// is its first import.
+func (i *Interpreter) importModule(stmt *stm.ImportStmt) *LoxModule {
+	if i.loader == nil {
+		panic(fmt.Sprintf("line[%d] Can't import modules here.", stmt.Keyword.Line))
+	}
+
+	path, err := i.loader.FindModule(stmt.Path, i.module.Path)
+
+	if err != nil {
+		panic(fmt.Sprintf("line[%d] %s", stmt.Keyword.Line, err.Error()))
+	}
+
+	module, ok := i.modules[path]
+
+	if ok {
+		if module.loading {
+			panic(fmt.Sprintf("line[%d] Import cycle: %s.", stmt.Keyword.Line, i.importCycle(module)))
+		}
+		return module
+	}
+
+	module = NewLoxModule(path, env.NewEnvironment(i.builtins))
+	module.loading = true
+	i.modules[path] = module
+
+	i.runModule(module)
+
+	module.loading = false
+
+	return module
+}
+
+func (i *Interpreter) runModule(module *LoxModule) {
+	enclosing, root, frame := i.module, i.root, i.frame
+
+	i.importing = append(i.importing, enclosing)
+	i.module = module
+	i.root = newModuleFrame(module.globals, i)
+	i.frame = i.root
+
+	defer func() {
+		i.importing = i.importing[:len(i.importing)-1]
+		i.module, i.root, i.frame = enclosing, root, frame
+	}()
+
+	statements, err := i.loader.LoadModule(module.Path)
+
+	if err != nil {
+		panic(err.Error())
+	}
+
+	i.run(statements)
+}
+
+func (i *Interpreter) importCycle(module *LoxModule) string {
+	chain := append(append([]*LoxModule{}, i.importing...), i.module, module)
+
+	for len(chain) > 0 && chain[0] != module {
+		chain = chain[1:]
+	}
+
+	paths := make([]string, len(chain))
+
+	for index, importer := range chain {
+		paths[index] = importer.Path
+	}
+
+	return strings.Join(paths, " -> ")
+}
+
+func (i *Interpreter) VisitExportStatement(stmt *stm.ExportStmt) any {
+	i.execute(stmt.Declaration)
+	i.export(stmt)
+
+	return nil
+}
+
+func (i *Interpreter) export(stmt *stm.ExportStmt) {
+	for _, name := range stm.DeclaredNames(stmt.Declaration) {
+		i.module.exports[name.Lexeme] = true
+	}
+}
+
 func (i *Interpreter) VisitReturnStatement(stmt *stm.ReturnStmt) any {
 	var value any = nil
 
@@ -121,7 +324,7 @@ func (i *Interpreter) VisitReturnStatement(stmt *stm.ReturnStmt) any {
 }
 
 func (i *Interpreter) VisitBlockStatement(stmt *stm.BlockStmt) any {
-	i.executeBlock(stmt.Statements, env.NewEnvironment(i.environment))
+	i.executeBlock(stmt.Statements)
 	return nil
 }
 
@@ -137,39 +340,209 @@ func (i *Interpreter) VisitClassStatement(stmt *stm.ClassStmt) any {
 		}
 
 		superClass = value.(*LoxClass)
+	}
+
+	traits := make([]any, len(stmt.Traits))
 
-		i.LocalVariables[stmt.SuperIndex] = superClass
+	for index, trait := range stmt.Traits {
+		traits[index] = i.evaluate(trait)
 	}
 
-	index, ok := i.locals[stmt.Name]
+	interfaces := make([]any, len(stmt.Interfaces))
+
+	for index, iface := range stmt.Interfaces {
+		interfaces[index] = i.evaluate(iface)
+	}
+
+	local, ok := i.locals[stmt.Name]
 
 	if !ok {
-		i.globals.Define(stmt.Name.Lexeme, nil)
+		i.frame.globals.Define(stmt.Name.Lexeme, nil)
+	}
+
+	class := i.newClass(stmt, superClass, toTraits(traits), toInterfaces(interfaces))
+
+	if ok {
+		i.frame.slots[local.index] = class
+	} else {
+		i.frame.globals.Assign(stmt.Name, class)
 	}
 
+	i.initializeStaticFields(class, stmt, nil)
+
+	return nil
+
+}
+
+func (i *Interpreter) VisitTraitStatement(stmt *stm.TraitStmt) any {
+	trait := i.newTrait(stmt)
+
+	if local, ok := i.locals[stmt.Name]; ok {
+		i.frame.slots[local.index] = trait
+	} else {
+		i.frame.globals.Define(stmt.Name.Lexeme, trait)
+	}
+
+	return nil
+}
+
+func (i *Interpreter) VisitEnumStatement(stmt *stm.EnumStmt) any {
+	enum := newEnum(stmt)
+
+	if local, ok := i.locals[stmt.Name]; ok {
+		i.frame.slots[local.index] = enum
+	} else {
+		i.frame.globals.Define(stmt.Name.Lexeme, enum)
+	}
+
+	return nil
+}
+
+func (i *Interpreter) VisitInterfaceStatement(stmt *stm.InterfaceStmt) any {
+	iface := i.newInterface(stmt)
+
+	if local, ok := i.locals[stmt.Name]; ok {
+		i.frame.slots[local.index] = iface
+	} else {
+		i.frame.globals.Define(stmt.Name.Lexeme, iface)
+	}
+
+	return nil
+}
+
+func (i *Interpreter) newInterface(stmt *stm.InterfaceStmt) *LoxInterface {
+	methods := make(map[string]int)
+
+	for _, method := range stmt.Methods {
+		methods[method.Name.Lexeme] = len(method.Params)
+	}
+
+	return NewLoxInterface(stmt.Name.Lexeme, methods)
+}
+
+func (i *Interpreter) newTrait(stmt *stm.TraitStmt) *LoxTrait {
 	methods := make(map[string]*LoxFunction)
+
+	for _, method := range stmt.Methods {
+		methods[method.Name.Lexeme] = NewLoxMethod(method, i.frame, false)
+	}
+
+	return NewLoxTrait(stmt.Name.Lexeme, methods)
+}
+
+func (i *Interpreter) newClass(stmt *stm.ClassStmt, superClass *LoxClass, traits []*LoxTrait, interfaces []*LoxInterface) *LoxClass {
 	staticMethods := make(map[string]*LoxFunction)
+	closure := i.frame
+
+	if superClass != nil {
+		closure = NewFrame(1, i.frame, i)
+		closure.slots[0] = superClass
+	}
+
+	overridden := make(map[string]bool)
+
+	for _, method := range stmt.Methods {
+		overridden[method.Name.Lexeme] = true
+	}
+
+	methods := traitMethods(traits, overridden)
 
 	for _, method := range stmt.Methods {
-		function := NewLoxFunction(*method, i.globals, method.Name.Lexeme == "init")
+		function := NewLoxMethod(method, closure, method.Name.Lexeme == "init")
 		methods[method.Name.Lexeme] = function
 	}
 
 	for _, method := range stmt.StaticMethods {
-		function := NewLoxFunction(*method, i.globals, method.Name.Lexeme == "init")
+		function := NewLoxMethod(method, closure, false)
 		staticMethods[method.Name.Lexeme] = function
 	}
 
 	class := NewLoxClass(stmt.Name.Lexeme, methods, staticMethods, superClass)
+	class.Traits = traits
+	class.Interfaces = interfaces
+	class.closure = closure
 
-	if ok {
-		i.LocalVariables[index] = class
-	} else {
-		i.globals.Assign(stmt.Name, class)
+	for _, method := range stmt.Abstract {
+		class.Abstract[method.Name.Lexeme] = len(method.Params)
 	}
+	class.fieldSlots = stmt.FieldSlots
 
-	return nil
+	for _, field := range stmt.Fields {
+		class.fields = append(class.fields, &fieldInitializer{declaration: field})
+	}
 
+	accessors := []struct {
+		declarations []*stm.FunctionStm
+		functions    map[string]*LoxFunction
+	}{
+		{stmt.Getters, class.Getters},
+		{stmt.Setters, class.Setters},
+		{stmt.StaticGetters, class.StaticGetters},
+		{stmt.StaticSetters, class.StaticSetters},
+	}
+
+	for _, accessor := range accessors {
+		for _, declaration := range accessor.declarations {
+			accessor.functions[declaration.Name.Lexeme] = NewLoxMethod(declaration, closure, false)
+		}
+	}
+
+	class.unimplemented = class.unimplementedMethods()
+
+	for _, iface := range interfaces {
+		iface.check(class)
+	}
+
+	return class
+}
+
+// initializeStaticFields evaluates the static fields of class with this
+// bound to the class. values holds their compiled initializers when the
+// closure backend is used.
+func (i *Interpreter) initializeStaticFields(class *LoxClass, stmt *stm.ClassStmt, values []compiledExpr) {
+	fields := make([]*fieldInitializer, len(stmt.StaticFields))
+
+	for index, declaration := range stmt.StaticFields {
+		fields[index] = &fieldInitializer{declaration: declaration}
+
+		if values != nil {
+			fields[index].value = values[index]
+		}
+	}
+
+	i.evaluateFields(fields, stmt.StaticFieldSlots, class.closure, class, func(name string, value any) {
+		class.Fields[name] = value
+	})
+}
+
+// evaluateFields runs field initializers in a frame of their own, holding
+// this the way a method's frame does, and hands each value to store.
+func (i *Interpreter) evaluateFields(fields []*fieldInitializer, slots int, closure *Frame, this any, store func(name string, value any)) {
+	if len(fields) == 0 {
+		return
+	}
+
+	frame := NewFrame(slots, closure, i)
+	frame.slots[0] = this
+
+	previous := i.frame
+	i.frame = frame
+
+	defer func() {
+		i.frame = previous
+	}()
+
+	for _, field := range fields {
+		var value any
+
+		if field.declaration.Initializer != nil && field.value != nil {
+			value = field.value(frame)
+		} else if field.declaration.Initializer != nil {
+			value = i.evaluate(field.declaration.Initializer)
+		}
+
+		store(field.declaration.Name.Lexeme, value)
+	}
 }
 
 // VisitExprStatement implements stm.Visitor.
@@ -202,19 +575,103 @@ func (i *Interpreter) VisitWhileStatement(stmt *stm.WhileStmt) any {
 			break
 		}
 		i.execute(stmt.Body)
+
+		if i.breaking {
+			break
+		}
+	}
+	i.nearestEnclosingLoop = i.nearestEnclosingLoop[:len(i.nearestEnclosingLoop)-1]
+	i.breaking = false
+	return nil
+}
+
+func (i *Interpreter) VisitForInStatement(stmt *stm.ForInStmt) any {
+	next, stop := i.iterate(i.evaluate(stmt.Iterable), stmt.Keyword)
+	defer stop()
+
+	i.nearestEnclosingLoop = append(i.nearestEnclosingLoop, stmt)
+
+	for {
+		value, ok := next()
+
+		if !ok {
+			break
+		}
+
+		for index, value := range unpack(value, len(stmt.Names), stmt.Keyword) {
+			i.frame.slots[i.locals[stmt.Names[index]].index] = value
+		}
+
+		i.execute(stmt.Body)
+
+		if i.breaking {
+			break
+		}
 	}
 	i.nearestEnclosingLoop = i.nearestEnclosingLoop[:len(i.nearestEnclosingLoop)-1]
 	i.breaking = false
 	return nil
 }
 
+func (i *Interpreter) VisitYieldStatement(stmt *stm.YieldStmt) any {
+	i.yield(i.evaluate(stmt.Value))
+	return nil
+}
+
+func (i *Interpreter) VisitSpawnStatement(stmt *stm.SpawnStmt) any {
+	callee := i.evaluate(stmt.Call.Callee)
+	arguments := make([]any, 0)
+
+	for _, arg := range stmt.Call.Arguments {
+		arguments = append(arguments, i.evaluate(arg))
+	}
+
+	i.spawn(callee, arguments, stmt.Call.Paren)
+	return nil
+}
+
+func (i *Interpreter) VisitSelectStatement(stmt *stm.SelectStmt) any {
+	ops := make([]channelOp, len(stmt.Cases))
+
+	for index, selectCase := range stmt.Cases {
+		var value any
+
+		if selectCase.Value != nil {
+			value = i.evaluate(selectCase.Value)
+		}
+
+		ops[index] = newChannelOp(i.evaluate(selectCase.Channel), selectCase, value)
+	}
+
+	chosen, value, _ := i.selectOp(ops, stmt.Default == nil)
+
+	if chosen < 0 {
+		i.execute(stmt.Default)
+		return nil
+	}
+
+	selectCase := stmt.Cases[chosen]
+
+	if selectCase.Name != nil {
+		i.frame.slots[i.locals[*selectCase.Name].index] = value
+	}
+
+	i.execute(selectCase.Body)
+	return nil
+}
+
+func (i *Interpreter) VisitMatchStatement(stmt *stm.MatchStmt) any {
+	if matchCase, ok := i.matchCase(stmt.Cases, i.evaluate(stmt.Subject)); ok {
+		i.execute(matchCase.Body)
+	}
+	return nil
+}
+
 func (i *Interpreter) VisitBreakStatement(stmt *stm.BreakStmt) any {
 	if len(i.nearestEnclosingLoop) == 0 {
 		panic("Break not in loop")
 	}
-	enclosingLoop := i.nearestEnclosingLoop[len(i.nearestEnclosingLoop)-1]
 	i.breaking = true
-	enclosingLoop.Condition = stm.NewLiteral(false)
 	return nil
 }
 
@@ -226,11 +683,22 @@ func (i *Interpreter) VisitVarStatement(stmt *stm.VarStmt) any {
 	}
 
 	if stmt.Local {
-		index := i.locals[stmt.Name]
-		i.LocalVariables[index] = value
+		i.frame.slots[i.locals[stmt.Name].index] = value
+	} else if stmt.Const {
+		i.frame.globals.DefineConstant(stmt.Name.Lexeme, value)
+	} else {
+		i.frame.globals.Define(stmt.Name.Lexeme, value)
 	}
 
-	i.environment.Define(stmt.Name.Lexeme, value)
+	return nil
+}
+
+func (i *Interpreter) VisitVarPatternStatement(stmt *stm.VarPatternStmt) any {
+	if stmt.Const {
+		i.destructure(stmt.Pattern, i.evaluate(stmt.Initializer), i.declareConstant)
+	} else {
+		i.destructure(stmt.Pattern, i.evaluate(stmt.Initializer), i.declare)
+	}
 
 	return nil
 }
@@ -240,7 +708,7 @@ func (i *Interpreter) VisitErrorStatement(stmt *stm.ErrorStmt) any {
 }
 
 func (i *Interpreter) VisitAnonymousFuncExpr(expr *stm.AnonymousFunction) any {
-	function := NewAnonymousFunction(*expr, i.environment)
+	function := NewAnonymousFunction(*expr, i.frame)
 
 	return function
 }
@@ -250,17 +718,25 @@ func (i *Interpreter) VisitBinaryExpr(expr *stm.Binary) any {
 	left := i.evaluate(expr.Left)
 	right := i.evaluate(expr.Right)
 
-	switch expr.Operator.TokenType {
+	return i.binary(expr.Operator, left, right)
+}
+
+func (i *Interpreter) binary(operator tokens.Token, left, right any) any {
+	if result, ok := i.overloadBinary(operator, left, right); ok {
+		return result
+	}
+
+	switch operator.TokenType {
 	case tokens.MINUS:
-		i.checkNumberOperands(expr.Operator, left, right)
+		i.checkNumberOperands(operator, left, right)
 		return left.(float64) - right.(float64)
 
 	case tokens.SLASH:
-		i.checkNumberOperands(expr.Operator, left, right)
+		i.checkNumberOperands(operator, left, right)
 		return left.(float64) / right.(float64)
 
 	case tokens.STAR:
-		i.checkNumberOperands(expr.Operator, left, right)
+		i.checkNumberOperands(operator, left, right)
 		return left.(float64) * right.(float64)
 
 	case tokens.PLUS:
@@ -278,27 +754,27 @@ func (i *Interpreter) VisitBinaryExpr(expr *stm.Binary) any {
 		panic("Inconsistent types for + operation\n")
 
 	case tokens.GREATER:
-		i.checkNumberOperands(expr.Operator, left, right)
+		i.checkNumberOperands(operator, left, right)
 		return left.(float64) > right.(float64)
 
 	case tokens.GREATER_EQUAL:
-		i.checkNumberOperands(expr.Operator, left, right)
+		i.checkNumberOperands(operator, left, right)
 		return left.(float64) >= right.(float64)
 
 	case tokens.LESS:
-		i.checkNumberOperands(expr.Operator, left, right)
+		i.checkNumberOperands(operator, left, right)
 		return left.(float64) < right.(float64)
 
 	case tokens.LESS_EQUAL:
-		i.checkNumberOperands(expr.Operator, left, right)
+		i.checkNumberOperands(operator, left, right)
 
 		return left.(float64) <= right.(float64)
 
 	case tokens.BANG_EQUAL:
-		return !i.isEqual(left, right)
+		return !i.equals(left, right)
 
 	case tokens.EQUAL_EQUAL:
-		return i.isEqual(left, right)
+		return i.equals(left, right)
 	}
 
 	panic("Cannot execute expression\n")
@@ -355,9 +831,17 @@ func (i *Interpreter) VisitTernaryExpr(expr *stm.Ternary) any {
 func (i *Interpreter) VisitUnaryExpr(expr *stm.Unary) any {
 	right := i.evaluate(expr.Right)
 
-	switch expr.Operator.TokenType {
+	return i.unary(expr.Operator, right)
+}
+
+func (i *Interpreter) unary(operator tokens.Token, right any) any {
+	switch operator.TokenType {
 	case tokens.MINUS:
-		i.checkNumberOperands(expr.Operator, right)
+		if result, ok := i.callSpecial(right, NEG_METHOD, operator); ok {
+			return result
+		}
+
+		i.checkNumberOperands(operator, right)
 		return -right.(float64)
 	case tokens.BANG:
 		return !i.isTruthy(right)
@@ -367,38 +851,205 @@ func (i *Interpreter) VisitUnaryExpr(expr *stm.Unary) any {
 }
 
 func (i *Interpreter) VisitCallExpr(expr *stm.Call) any {
-	callee := i.evaluate(expr.Callee)
+	if get, ok := expr.Callee.(*stm.Get); ok {
+		object := i.evaluate(get.Object)
+
+		if instance, ok := object.(*LoxInstance); ok {
+			field, method := i.findProperty(instance, get.Name, &expr.Cache)
+
+			if method == nil {
+				return i.callValue(expr, field)
+			}
+
+			return i.invokeMethod(expr, instance, method)
+		}
+
+		return i.callValue(expr, i.getProperty(object, get.Name))
+	}
+
+	return i.callValue(expr, i.evaluate(expr.Callee))
+}
+
+func (i *Interpreter) callValue(expr *stm.Call, callee any) any {
 	arguments := make([]any, 0)
 
 	for _, arg := range expr.Arguments {
 		arguments = append(arguments, i.evaluate(arg))
 	}
 
+	arguments = i.arrange(callee, arguments, expr.Names, expr.Paren)
+
+	if expr.Tail {
+		i.tailCall(callee, arguments, expr.Paren)
+	}
+
+	return i.call(callee, arguments, expr.Paren)
+}
+
+func (i *Interpreter) call(callee any, arguments []any, paren tokens.Token) any {
+	if result, ok := i.callSpecial(callee, CALL_METHOD, paren, arguments...); ok {
+		return result
+	}
+
 	function, ok := callee.(Callable)
 
 	if !ok {
 		panic("Can only call functions and classes.")
 	}
 
-	if function.Arity() != len(arguments) {
-		errorMsg := fmt.Sprintf("line[%d] Expected %d arguments but got %d", expr.Paren.Line, function.Arity(), len(arguments))
+	i.checkArity(function, arguments, paren)
+
+	return function.Call(i, arguments)
+
+}
+
+// invokeMethod calls a method found on instance without binding it first.
+func (i *Interpreter) invokeMethod(expr *stm.Call, instance *LoxInstance, method *LoxFunction) any {
+	arguments := make([]any, 0, len(expr.Arguments))
+
+	for _, arg := range expr.Arguments {
+		arguments = append(arguments, i.evaluate(arg))
+	}
+
+	arguments = i.arrange(method, arguments, expr.Names, expr.Paren)
+	i.checkArity(method, arguments, expr.Paren)
+
+	if expr.Tail {
+		panic(TailCall{Function: method, This: instance, Arguments: arguments})
+	}
+
+	return method.CallMethod(i, instance, arguments)
+}
+
+// tailCall hands calls of Lox functions back to the enclosing
+// LoxFunction.Call instead of nesting them. Other callables are called and
+// their result is returned as usual.
+func (i *Interpreter) tailCall(callee any, arguments []any, paren tokens.Token) {
+	switch function := callee.(type) {
+	case *LoxFunction:
+		i.checkArity(function, arguments, paren)
+		panic(TailCall{Function: function, Arguments: arguments})
+	case *BoundMethod:
+		i.checkArity(function, arguments, paren)
+		panic(TailCall{Function: function.Method, This: function.This, Arguments: arguments})
+	}
+
+	panic(ReturnValue{Value: i.call(callee, arguments, paren)})
+}
+
+func (i *Interpreter) checkArity(function Callable, arguments []any, paren tokens.Token) {
+	if arity := function.Arity(); !arity.accepts(len(arguments)) {
+		errorMsg := fmt.Sprintf("line[%d] Expected %s arguments but got %d", paren.Line, arity, len(arguments))
 		panic(errorMsg)
 	}
+}
 
-	return function.Call(i, arguments)
+// VisitMatchExpr implements stm.ExprVisitor.
+func (i *Interpreter) VisitMatchExpr(expr *stm.Match) any {
+	subject := i.evaluate(expr.Subject)
+	matchCase, ok := i.matchCase(expr.Cases, subject)
 
+	if !ok {
+		i.noMatch(subject, expr.Keyword)
+	}
+
+	return i.evaluate(matchCase.Value)
+}
+
+// VisitAwaitExpr implements stm.ExprVisitor.
+func (i *Interpreter) VisitAwaitExpr(expr *stm.Await) any {
+	return i.await(i.evaluate(expr.Value))
+}
+
+// VisitIndexExpr implements stm.ExprVisitor.
+func (i *Interpreter) VisitIndexExpr(expr *stm.Index) any {
+	object := i.evaluate(expr.Object)
+
+	return i.index(object, i.evaluate(expr.Index), expr.Bracket)
+}
+
+// VisitListExpr implements stm.ExprVisitor.
+func (i *Interpreter) VisitListExpr(expr *stm.List) any {
+	elements := make([]any, len(expr.Elements))
+
+	for index, element := range expr.Elements {
+		elements[index] = i.evaluate(element)
+	}
+
+	return NewLoxList(elements)
 }
 
 func (i *Interpreter) VisitGetExpr(expr *stm.Get) any {
 	object := i.evaluate(expr.Object)
 
+	return i.getCached(object, expr)
+}
+
+func (i *Interpreter) getCached(object any, expr *stm.Get) any {
+	instance, ok := object.(*LoxInstance)
+
+	if !ok {
+		return i.getProperty(object, expr.Name)
+	}
+
+	field, method := i.findProperty(instance, expr.Name, &expr.Cache)
+
+	if method != nil {
+		return method.Bind(instance)
+	}
+	return field
+}
+
+// findProperty looks name up on instance, first in cache. It returns either
+// the value of the field or getter, or the method, leaving binding to the
+// caller. The cache is keyed by shape: the slot of a field and the accessor or
+// method a name refers to stay the same for every instance of that shape.
+func (i *Interpreter) findProperty(instance *LoxInstance, name tokens.Token, cache *stm.InlineCache) (any, *LoxFunction) {
+	if cache.Shape != instance.shape {
+		slot, ok := instance.shape.Slot(name.Lexeme)
+
+		if ok {
+			*cache = stm.InlineCache{Shape: instance.shape, Slot: slot}
+		} else if getter, ok := instance.class.FindGetter(name.Lexeme); ok {
+			*cache = stm.InlineCache{Shape: instance.shape, Slot: -1, Value: cachedGetter{getter}}
+		} else {
+			method, ok := instance.class.FindMethod(name.Lexeme)
+
+			if !ok && stm.IsPrivate(name.Lexeme) {
+				panic(fmt.Sprintf("Instance has no private member \"%s\".", stm.MemberName(name.Lexeme)))
+			}
+
+			if !ok {
+				panic(fmt.Sprintf("Undefined property \"%s\".", name.Lexeme))
+			}
+
+			*cache = stm.InlineCache{Shape: instance.shape, Slot: -1, Value: method}
+		}
+	}
+
+	if cache.Slot >= 0 {
+		return instance.values[cache.Slot], nil
+	}
+
+	if getter, ok := cache.Value.(cachedGetter); ok {
+		return getter.function.CallMethod(i, instance, nil), nil
+	}
+	return nil, cache.Value.(*LoxFunction)
+}
+
+// cachedGetter marks an inline cache entry as a getter rather than a method.
+type cachedGetter struct {
+	function *LoxFunction
+}
+
+func (i *Interpreter) getProperty(object any, name tokens.Token) any {
 	instance, ok := object.(IloxInstance)
 
 	if !ok {
 		panic("Only instances have properties.")
 	}
 
-	property, err := instance.Get(expr.Name, i)
+	property, err := instance.Get(name, i)
 
 	if err != nil {
 		panic(err.Error())
@@ -410,15 +1061,26 @@ func (i *Interpreter) VisitGetExpr(expr *stm.Get) any {
 
 func (i *Interpreter) VisitSetExpr(expr *stm.Set) any {
 	object := i.evaluate(expr.Object)
-	instance, ok := object.(*LoxInstance)
+
+	if expr.Operator != nil {
+		current := i.getProperty(object, expr.Name)
+
+		return i.setProperty(object, expr.Name, i.binary(*expr.Operator, current, i.evaluate(expr.Value)))
+	}
+
+	return i.setProperty(object, expr.Name, i.evaluate(expr.Value))
+}
+
+func (i *Interpreter) setProperty(object any, name tokens.Token, value any) any {
+	instance, ok := object.(IloxInstance)
 
 	if !ok {
 		panic("Only instances have fields.")
 	}
 
-	value := i.evaluate(expr.Value)
-
-	instance.Set(expr.Name, value)
+	if err := instance.Set(name, value, i); err != nil {
+		panic(err.Error())
+	}
 
 	return value
 }
@@ -430,30 +1092,60 @@ func (i *Interpreter) VisitThisExpr(expr *stm.This) any {
 func (i *Interpreter) VisitSuperExpr(expr *stm.Super) any {
 	class := i.lookupVariable(expr.Keyword, expr).(*LoxClass)
 
+	return i.superMethod(class, expr)
+}
+
+func (i *Interpreter) superMethod(class *LoxClass, expr *stm.Super) any {
+	this := i.frame.ancestor(expr.ThisDepth).slots[0]
+
+	if expr.Static {
+		if value, ok := class.getStatic(expr.Method.Lexeme, this.(*LoxClass), i); ok {
+			return value
+		}
+		panic(fmt.Sprintf("Undefined property \"%s\".", stm.MemberName(expr.Method.Lexeme)))
+	}
+
+	if getter, ok := class.FindGetter(expr.Method.Lexeme); ok {
+		return getter.CallMethod(i, this, nil)
+	}
+
 	method, ok := class.FindMethod(expr.Method.Lexeme)
 
 	if ok {
-		this := i.LocalVariables[expr.ThisIndex].(*LoxInstance)
-		method.Bind(this, i)
-		return method
+		return method.Bind(this)
 	}
 
-	panic("")
+	panic(fmt.Sprintf("Undefined property \"%s\".", stm.MemberName(expr.Method.Lexeme)))
 }
 
 func (i *Interpreter) VisitAssignExpr(expr *stm.Assign) any {
-	value := i.evaluate(expr.Value)
-	index, ok := i.locals[expr.Name]
+	var value any
+
+	if expr.Operator != nil {
+		current := i.lookupVariable(expr.Name, expr)
+		value = i.binary(*expr.Operator, current, i.evaluate(expr.Value))
+	} else {
+		value = i.evaluate(expr.Value)
+	}
+
+	local, ok := i.locals[expr.Name]
 
 	if ok {
-		i.LocalVariables[index] = value
+		i.frame.Set(local.depth, local.index, value)
 	} else {
-		i.globals.Assign(expr.Name, value)
+		i.frame.globals.Assign(expr.Name, value)
 	}
 
 	return value
 }
 
+func (i *Interpreter) VisitAssignPatternExpr(expr *stm.AssignPattern) any {
+	value := i.evaluate(expr.Value)
+	i.destructure(expr.Pattern, value, i.assign)
+
+	return value
+}
+
 func (i *Interpreter) isTruthy(value any) bool {
 	if value == nil {
 		return false
@@ -468,12 +1160,12 @@ func (i *Interpreter) isTruthy(value any) bool {
 }
 
 func (i *Interpreter) lookupVariable(name tokens.Token, expr stm.Expression) any {
-	index, ok := i.locals[name]
+	local, ok := i.locals[name]
 
 	if ok {
-		return i.LocalVariables[index]
+		return i.frame.Get(local.depth, local.index)
 	}
-	return i.globals.Get(name)
+	return i.frame.globals.Get(name)
 }
 
 func (i *Interpreter) tryTypeAssert(value any, targetType reflect.Kind) bool {
@@ -499,6 +1191,9 @@ func (i *Interpreter) tryReflectToString(value any) (*string, bool) {
 	case int, float64:
 		str := fmt.Sprintf("%v", v)
 		return &str, true
+	case *LoxInstance:
+		str := i.stringify(v)
+		return &str, true
 	}
 
 	val, ok := value.(fmt.Stringer)
@@ -511,10 +1206,6 @@ func (i *Interpreter) tryReflectToString(value any) (*string, bool) {
 	return nil, false
 }
 
-func (i *Interpreter) isEqual(left any, right any) bool {
-	return reflect.DeepEqual(left, right)
-}
-
 func (i *Interpreter) checkNumberOperands(operator tokens.Token, operands ...any) {
 	for _, operand := range operands {
 		if !i.tryTypeAssert(operand, reflect.Float64) {
@@ -535,6 +1226,21 @@ func (i *Interpreter) stringify(value any) string {
 	if value == nil {
 		return "nil"
 	}
+
+	switch object := value.(type) {
+	case *LoxInstance:
+		if text, ok := i.instanceString(object); ok {
+			return text
+		}
+	case *LoxMap:
+		return object.format(i)
+	case *LoxSet:
+		return object.format(i)
+	case *LoxList:
+		return object.format(i)
+	case *LoxEnumValue:
+		return object.format(i)
+	}
 	if i.tryTypeAssert(value, reflect.Float64) {
 		textValue := fmt.Sprintf("%v", value)
 
@@ -550,6 +1256,16 @@ func (i *Interpreter) stringify(value any) string {
 
 func (i *Interpreter) afterPanic() {
 	if r := recover(); r != nil {
-		i.errorLogger.RuntimeError(r.(string))
+		if _, ok := r.(fiberExit); ok {
+			return
+		}
+
+		message, ok := r.(string)
+
+		if !ok {
+			panic(r)
+		}
+
+		i.errorLogger.RuntimeError(message)
 	}
 }
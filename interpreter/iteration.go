@@ -0,0 +1,249 @@
+package interpreter
+
+import (
+	"fmt"
+	stm "lox/statement"
+	"lox/tokens"
+)
+
+// Methods an instance defines to be iterated by for-in. iterator() returns
+// the object whose done() says whether the elements have run out and whose
+// next() produces the next one, which can be nil.
+const (
+	ITERATOR_METHOD = "iterator"
+	NEXT_METHOD     = "next"
+	DONE_METHOD     = "done"
+)
+
+// iterator produces the elements a for-in loop visits, one at a time. ok is
+// false once it's exhausted.
+type iterator func() (value any, ok bool)
+
+// LoxIterator is an iterator handed to Lox code, like the one Map.items()
+// returns. done() reads one element ahead to know whether there is another.
+// Calling next() past the end returns nil.
+type LoxIterator struct {
+	produce iterator
+	pending any
+	ahead   bool
+	ended   bool
+}
+
+func NewLoxIterator(next iterator) *LoxIterator {
+	return &LoxIterator{produce: next}
+}
+
+func (l *LoxIterator) next() (any, bool) {
+	if l.ahead {
+		l.ahead = false
+		return l.pending, true
+	}
+
+	if l.ended {
+		return nil, false
+	}
+
+	value, ok := l.produce()
+	l.ended = !ok
+	return value, ok
+}
+
+func (l *LoxIterator) done() bool {
+	if !l.ahead && !l.ended {
+		l.pending, l.ahead = l.next()
+	}
+	return !l.ahead
+}
+
+func (l *LoxIterator) Get(name tokens.Token, interpreter *Interpreter) (any, error) {
+	switch name.Lexeme {
+	case NEXT_METHOD:
+		return nativeMethod(0, func(interpreter *Interpreter, args []any) any {
+			value, _ := l.next()
+			return value
+		}), nil
+	case DONE_METHOD:
+		return nativeMethod(0, func(interpreter *Interpreter, args []any) any {
+			return l.done()
+		}), nil
+	}
+
+	return nil, fmt.Errorf("Undefined property \"%s\".", stm.MemberName(name.Lexeme))
+}
+
+func (l *LoxIterator) Set(name tokens.Token, value any, interpreter *Interpreter) error {
+	return fmt.Errorf("Can't add properties to an iterator.")
+}
+
+func (l *LoxIterator) String() string {
+	return "<iterator>"
+}
+
+// LoxRange is what range(start, end, step) returns. It produces its numbers
+// as they are asked for.
+type LoxRange struct {
+	start float64
+	end   float64
+	step  float64
+}
+
+func NewLoxRange(start, end, step float64) *LoxRange {
+	return &LoxRange{start: start, end: end, step: step}
+}
+
+func (r *LoxRange) iterate() iterator {
+	current := r.start
+
+	return func() (any, bool) {
+		if (r.step > 0 && current >= r.end) || (r.step < 0 && current <= r.end) {
+			return nil, false
+		}
+
+		value := current
+		current += r.step
+		return value, true
+	}
+}
+
+func (r *LoxRange) String() string {
+	return fmt.Sprintf("range(%v, %v, %v)", r.start, r.end, r.step)
+}
+
+// newRange is range(end), range(start, end) or range(start, end, step).
+func newRange(interpreter *Interpreter, args []any) any {
+	bounds := make([]float64, len(args))
+
+	for index, arg := range args {
+		number, ok := arg.(float64)
+
+		if !ok {
+			panic("Arguments of range must be numbers.")
+		}
+
+		bounds[index] = number
+	}
+
+	switch len(bounds) {
+	case 1:
+		return NewLoxRange(0, bounds[0], 1)
+	case 2:
+		return NewLoxRange(bounds[0], bounds[1], 1)
+	}
+
+	if bounds[2] == 0 {
+		panic("Step of range can't be 0.")
+	}
+
+	return NewLoxRange(bounds[0], bounds[1], bounds[2])
+}
+
+// iterate returns the elements of value: the elements of a list or set, the
+// keys of a map, the characters of a string, what a generator yields, what
+// a channel receives until it is closed, or what an instance's iterator() or
+// next() produce until done() returns true. stop is called when the loop
+// ends, and closes a generator left unfinished.
+func (i *Interpreter) iterate(value any, keyword tokens.Token) (next iterator, stop func()) {
+	stop = func() {}
+
+	switch iterable := value.(type) {
+	case *LoxList:
+		index := 0
+
+		return func() (any, bool) {
+			if index >= len(iterable.elements) {
+				return nil, false
+			}
+
+			index++
+			return iterable.elements[index-1], true
+		}, stop
+	case *LoxMap:
+		return iterateEntries(iterable.table.entries, func(entry *entry) any { return entry.key }), stop
+	case *LoxSet:
+		return iterateEntries(iterable.table.entries, func(entry *entry) any { return entry.key }), stop
+	case string:
+		characters := []rune(iterable)
+		index := 0
+
+		return func() (any, bool) {
+			if index >= len(characters) {
+				return nil, false
+			}
+
+			index++
+			return string(characters[index-1]), true
+		}, stop
+	case *LoxRange:
+		return iterable.iterate(), stop
+	case *LoxIterator:
+		return iterable.next, stop
+	case *LoxChannel:
+		return func() (any, bool) {
+			_, value, ok := i.selectOp([]channelOp{{channel: iterable}}, true)
+			return value, ok
+		}, stop
+	case *LoxGenerator:
+		next = func() (any, bool) { return iterable.next(i) }
+		stop = func() { iterable.close(i) }
+
+		return next, stop
+	case *LoxInstance:
+		if result, ok := i.callSpecial(iterable, ITERATOR_METHOD, keyword); ok {
+			if _, ok := result.(*LoxInstance); !ok {
+				return i.iterate(result, keyword)
+			}
+			iterable = result.(*LoxInstance)
+		}
+
+		if _, _, ok := specialMethod(iterable, NEXT_METHOD); ok {
+			if _, _, ok := specialMethod(iterable, DONE_METHOD); !ok {
+				panic(fmt.Sprintf("Iterator %s defines %s() but not %s().\n [line %d]", i.stringify(iterable), NEXT_METHOD, DONE_METHOD, keyword.Line))
+			}
+
+			return func() (any, bool) {
+				if done, _ := i.callSpecial(iterable, DONE_METHOD, keyword); i.isTruthy(done) {
+					return nil, false
+				}
+
+				next, _ := i.callSpecial(iterable, NEXT_METHOD, keyword)
+				return next, true
+			}, stop
+		}
+	}
+
+	panic(fmt.Sprintf("Can't iterate over %s.\n [line %d]", i.stringify(value), keyword.Line))
+}
+
+// iterateEntries walks the entries of a map or set as they were when the
+// loop started.
+func iterateEntries(entries []*entry, element func(entry *entry) any) iterator {
+	index := 0
+
+	return func() (any, bool) {
+		if index >= len(entries) {
+			return nil, false
+		}
+
+		index++
+		return element(entries[index-1]), true
+	}
+}
+
+// unpack splits an element of a for-in loop over several names.
+func unpack(value any, count int, keyword tokens.Token) []any {
+	if count == 1 {
+		return []any{value}
+	}
+
+	list, ok := value.(*LoxList)
+
+	if !ok {
+		panic(fmt.Sprintf("Can only unpack lists.\n [line %d]", keyword.Line))
+	}
+
+	if len(list.elements) != count {
+		panic(fmt.Sprintf("Expected %d values to unpack but got %d.\n [line %d]", count, len(list.elements), keyword.Line))
+	}
+
+	return list.elements
+}
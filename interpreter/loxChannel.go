@@ -0,0 +1,246 @@
+package interpreter
+
+import (
+	"fmt"
+	stm "lox/statement"
+	"lox/tokens"
+	"math"
+)
+
+// LoxChannel passes values between fibers. Up to capacity values wait in
+// buffer; past that, or with no buffer at all, a sender waits for a receiver.
+type LoxChannel struct {
+	capacity  int
+	buffer    []any
+	closed    bool
+	receivers []*channelWaiter
+	senders   []*channelWaiter
+}
+
+// selection is what a waiting fiber was waiting for: one of the channel
+// operations of a select, or a single send or recv. The fiber that completes
+// one of them fills it in.
+type selection struct {
+	done   bool
+	chosen int
+	value  any
+	ok     bool
+	closed bool
+}
+
+// channelWaiter is a waiting fiber queued on a channel, for the operation at
+// index in its selection. value is what it sends.
+type channelWaiter struct {
+	selection *selection
+	index     int
+	value     any
+}
+
+// channelOp is a send to or a receive from channel.
+type channelOp struct {
+	channel *LoxChannel
+	send    bool
+	value   any
+}
+
+// newChannelOp is the operation of a select case on channel.
+func newChannelOp(channel any, selectCase *stm.SelectCase, value any) channelOp {
+	loxChannel, ok := channel.(*LoxChannel)
+
+	if !ok {
+		panic(fmt.Sprintf("Can only select on channels.\n [line %d]", selectCase.Keyword.Line))
+	}
+
+	return channelOp{channel: loxChannel, send: selectCase.Value != nil, value: value}
+}
+
+func NewLoxChannel(capacity int) *LoxChannel {
+	return &LoxChannel{capacity: capacity}
+}
+
+func newChannel(interpreter *Interpreter, args []any) any {
+	capacity, ok := args[0].(float64)
+
+	if !ok || capacity < 0 || capacity != math.Trunc(capacity) {
+		panic("Capacity of a channel must be a whole number.")
+	}
+
+	return NewLoxChannel(int(capacity))
+}
+
+func (c *LoxChannel) Get(name tokens.Token, interpreter *Interpreter) (any, error) {
+	switch name.Lexeme {
+	case "send":
+		return nativeMethod(1, func(interpreter *Interpreter, args []any) any {
+			interpreter.selectOp([]channelOp{{channel: c, send: true, value: args[0]}}, true)
+			return nil
+		}), nil
+	case "recv":
+		return nativeMethod(0, func(interpreter *Interpreter, args []any) any {
+			_, value, _ := interpreter.selectOp([]channelOp{{channel: c}}, true)
+			return value
+		}), nil
+	case "close":
+		return nativeMethod(0, func(interpreter *Interpreter, args []any) any {
+			c.close(interpreter)
+			return nil
+		}), nil
+	}
+
+	return nil, fmt.Errorf("Undefined property \"%s\".", stm.MemberName(name.Lexeme))
+}
+
+func (c *LoxChannel) Set(name tokens.Token, value any, interpreter *Interpreter) error {
+	return fmt.Errorf("Can't add properties to a channel.")
+}
+
+func (c *LoxChannel) String() string {
+	return "<channel>"
+}
+
+// trySend sends value if a receiver is waiting or the buffer has room.
+func (c *LoxChannel) trySend(interpreter *Interpreter, value any) bool {
+	if c.closed {
+		panic("Can't send on a closed channel.")
+	}
+
+	if receiver := dequeue(&c.receivers); receiver != nil {
+		interpreter.complete(receiver, value, true)
+		return true
+	}
+
+	if len(c.buffer) < c.capacity {
+		c.buffer = append(c.buffer, value)
+		return true
+	}
+
+	return false
+}
+
+// tryRecv receives a value if one is buffered or a sender is waiting. A
+// closed channel with nothing left in it is ready too, and gives nil with ok
+// false.
+func (c *LoxChannel) tryRecv(interpreter *Interpreter) (value any, ok bool, ready bool) {
+	if len(c.buffer) > 0 {
+		value = c.buffer[0]
+		c.buffer = c.buffer[1:]
+
+		if sender := dequeue(&c.senders); sender != nil {
+			c.buffer = append(c.buffer, sender.value)
+			interpreter.complete(sender, nil, true)
+		}
+
+		return value, true, true
+	}
+
+	if sender := dequeue(&c.senders); sender != nil {
+		interpreter.complete(sender, nil, true)
+		return sender.value, true, true
+	}
+
+	return nil, false, c.closed
+}
+
+// close wakes the fibers waiting on the channel. Receivers get nil; senders
+// fail.
+func (c *LoxChannel) close(interpreter *Interpreter) {
+	if c.closed {
+		panic("Channel is already closed.")
+	}
+
+	c.closed = true
+
+	for receiver := dequeue(&c.receivers); receiver != nil; receiver = dequeue(&c.receivers) {
+		interpreter.complete(receiver, nil, false)
+	}
+
+	for sender := dequeue(&c.senders); sender != nil; sender = dequeue(&c.senders) {
+		sender.selection.closed = true
+		interpreter.complete(sender, nil, false)
+	}
+}
+
+// forget removes the waiters of a completed selection.
+func (c *LoxChannel) forget(selection *selection) {
+	remove := func(waiters []*channelWaiter) []*channelWaiter {
+		kept := waiters[:0]
+
+		for _, waiter := range waiters {
+			if waiter.selection != selection {
+				kept = append(kept, waiter)
+			}
+		}
+		return kept
+	}
+
+	c.receivers = remove(c.receivers)
+	c.senders = remove(c.senders)
+}
+
+// dequeue takes the first waiter whose selection isn't complete yet.
+func dequeue(waiters *[]*channelWaiter) *channelWaiter {
+	for len(*waiters) > 0 {
+		waiter := (*waiters)[0]
+		*waiters = (*waiters)[1:]
+
+		if !waiter.selection.done {
+			return waiter
+		}
+	}
+
+	return nil
+}
+
+// complete wakes the fiber waiting on waiter.
+func (i *Interpreter) complete(waiter *channelWaiter, value any, ok bool) {
+	waiter.selection.done = true
+	waiter.selection.chosen = waiter.index
+	waiter.selection.value = value
+	waiter.selection.ok = ok
+
+	i.scheduler.blocked--
+	i.scheduler.wake.Broadcast()
+}
+
+// selectOp performs the first of ops that is ready, in order. If none is and
+// wait is set, the fiber waits until another fiber completes one of them;
+// otherwise chosen is -1.
+func (i *Interpreter) selectOp(ops []channelOp, wait bool) (chosen int, value any, ok bool) {
+	for index, op := range ops {
+		if op.send {
+			if op.channel.trySend(i, op.value) {
+				return index, nil, true
+			}
+		} else if value, ok, ready := op.channel.tryRecv(i); ready {
+			return index, value, ok
+		}
+	}
+
+	if !wait {
+		return -1, nil, false
+	}
+
+	selection := &selection{}
+
+	for index, op := range ops {
+		waiter := &channelWaiter{selection: selection, index: index, value: op.value}
+
+		if op.send {
+			op.channel.senders = append(op.channel.senders, waiter)
+		} else {
+			op.channel.receivers = append(op.channel.receivers, waiter)
+		}
+	}
+
+	i.block(selection)
+
+	for _, op := range ops {
+		op.channel.forget(selection)
+	}
+
+	if selection.closed {
+		panic("Can't send on a closed channel.")
+	}
+
+	return selection.chosen, selection.value, selection.ok
+}
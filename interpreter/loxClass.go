@@ -2,46 +2,118 @@ package interpreter
 
 import (
 	"fmt"
+	stm "lox/statement"
 	"lox/tokens"
+	"sort"
+	"strings"
 )
 
 type LoxClass struct {
 	Name          string
 	Methods       map[string]*LoxFunction
 	StaticMethods map[string]*LoxFunction
+	Getters       map[string]*LoxFunction
+	Setters       map[string]*LoxFunction
+	StaticGetters map[string]*LoxFunction
+	StaticSetters map[string]*LoxFunction
 	Fields        map[string]any
 	SuperClass    *LoxClass
+	Traits        []*LoxTrait
+	Interfaces    []*LoxInterface
+	Abstract      map[string]int
+	rootShape     *Shape
+	initializer   *LoxFunction
+	unimplemented []string
+	fields        []*fieldInitializer
+	fieldSlots    int
+	closure       *Frame
+}
+
+// fieldInitializer is a "var name = value;" declared in a class body. value
+// is set when the class was compiled by the closure backend.
+type fieldInitializer struct {
+	declaration *stm.VarStmt
+	value       compiledExpr
 }
 
 func NewLoxClass(name string, methods map[string]*LoxFunction, staticMethods map[string]*LoxFunction, superClass *LoxClass) *LoxClass {
-	return &LoxClass{
+	class := &LoxClass{
 		Name:          name,
 		Methods:       methods,
 		StaticMethods: staticMethods,
+		Getters:       make(map[string]*LoxFunction),
+		Setters:       make(map[string]*LoxFunction),
+		StaticGetters: make(map[string]*LoxFunction),
+		StaticSetters: make(map[string]*LoxFunction),
 		Fields:        make(map[string]any),
+		Abstract:      make(map[string]int),
 		SuperClass:    superClass,
 	}
+
+	class.rootShape = newRootShape(class)
+	class.initializer, _ = class.FindMethod("init")
+
+	return class
 }
 
-func (l *LoxClass) Set(name tokens.Token, value any) {
+// Set assigns a static property. Static setters are inherited. A public
+// static field assigned through a subclass becomes the subclass's own, while
+// a private one is assigned where it was declared.
+func (l *LoxClass) Set(name tokens.Token, value any, interpreter *Interpreter) error {
+	for class := l; class != nil; class = class.SuperClass {
+		if setter, ok := class.StaticSetters[name.Lexeme]; ok {
+			setter.CallMethod(interpreter, l, []any{value})
+			return nil
+		}
+
+		if _, ok := class.StaticGetters[name.Lexeme]; ok {
+			return fmt.Errorf("Property \"%s\" has a getter but no setter.", stm.MemberName(name.Lexeme))
+		}
+
+		if _, ok := class.Fields[name.Lexeme]; ok && (class == l || stm.IsPrivate(name.Lexeme)) {
+			class.Fields[name.Lexeme] = value
+			return nil
+		}
+	}
+
+	if stm.IsPrivate(name.Lexeme) {
+		return fmt.Errorf("Class has no private member \"%s\".", stm.MemberName(name.Lexeme))
+	}
+
 	l.Fields[name.Lexeme] = value
+	return nil
 }
 
 func (l *LoxClass) Get(name tokens.Token, interpreter *Interpreter) (any, error) {
-	field, ok := l.Fields[name.Lexeme]
+	value, ok := l.getStatic(name.Lexeme, l, interpreter)
 
 	if ok {
-		return field, nil
+		return value, nil
 	}
 
-	method, ok := l.StaticMethods[name.Lexeme]
+	return nil, fmt.Errorf(fmt.Sprintf("Undefined property \"%s\".", stm.MemberName(name.Lexeme)))
 
-	if ok {
-		return method, nil
-	}
+}
 
-	return nil, fmt.Errorf(fmt.Sprintf("Undefined property \"%s\".", name.Lexeme))
+// getStatic looks name up among the static members of the class and its
+// superclasses. Static methods and getters run with this as their receiver,
+// which is the class they were accessed through.
+func (l *LoxClass) getStatic(name string, this *LoxClass, interpreter *Interpreter) (any, bool) {
+	for class := l; class != nil; class = class.SuperClass {
+		if field, ok := class.Fields[name]; ok {
+			return field, true
+		}
+
+		if getter, ok := class.StaticGetters[name]; ok {
+			return getter.CallMethod(interpreter, this, nil), true
+		}
+
+		if method, ok := class.StaticMethods[name]; ok {
+			return method.Bind(this), true
+		}
+	}
 
+	return nil, false
 }
 
 func (l *LoxClass) FindMethod(name string) (*LoxFunction, bool) {
@@ -58,25 +130,121 @@ func (l *LoxClass) FindMethod(name string) (*LoxFunction, bool) {
 	return nil, false
 }
 
+// Implements reports whether the class or one of its superclasses declares
+// that it implements iface.
+func (l *LoxClass) Implements(iface *LoxInterface) bool {
+	for class := l; class != nil; class = class.SuperClass {
+		for _, implemented := range class.Interfaces {
+			if implemented == iface {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// findArity returns the arity of the method name, abstract or not.
+func (l *LoxClass) findArity(name string) (Arity, bool) {
+	for class := l; class != nil; class = class.SuperClass {
+		if method, ok := class.Methods[name]; ok {
+			return method.Arity(), true
+		}
+
+		if arity, ok := class.Abstract[name]; ok {
+			return exactly(arity), true
+		}
+	}
+	return Arity{}, false
+}
+
+// unimplementedMethods returns the abstract methods of the class and its
+// superclasses that no method closer to the class implements, sorted.
+func (l *LoxClass) unimplementedMethods() []string {
+	unimplemented := make([]string, 0)
+	decided := make(map[string]bool)
+
+	for class := l; class != nil; class = class.SuperClass {
+		for name := range class.Abstract {
+			if !decided[name] {
+				unimplemented = append(unimplemented, name)
+			}
+		}
+
+		for name := range class.Abstract {
+			decided[name] = true
+		}
+
+		for name := range class.Methods {
+			decided[name] = true
+		}
+	}
+
+	sort.Strings(unimplemented)
+
+	return unimplemented
+}
+
+// Includes reports whether the class or one of its superclasses includes
+// trait.
+func (l *LoxClass) Includes(trait *LoxTrait) bool {
+	for class := l; class != nil; class = class.SuperClass {
+		for _, included := range class.Traits {
+			if included == trait {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (l *LoxClass) FindGetter(name string) (*LoxFunction, bool) {
+	for class := l; class != nil; class = class.SuperClass {
+		if getter, ok := class.Getters[name]; ok {
+			return getter, true
+		}
+	}
+	return nil, false
+}
+
+func (l *LoxClass) FindSetter(name string) (*LoxFunction, bool) {
+	for class := l; class != nil; class = class.SuperClass {
+		if setter, ok := class.Setters[name]; ok {
+			return setter, true
+		}
+	}
+	return nil, false
+}
+
 func (l *LoxClass) Call(interpreter *Interpreter, args []any) any {
+	if len(l.unimplemented) > 0 {
+		panic(fmt.Sprintf("Can't instantiate abstract class %s. Missing implementation of \"%s\".", l.Name, strings.Join(l.unimplemented, "\", \"")))
+	}
+
 	instance := NewLoxInstance(l)
-	initializer, ok := l.FindMethod("init")
+	l.initializeFields(interpreter, instance)
 
-	if ok {
-		initializer.Bind(instance, interpreter)
-		initializer.Call(interpreter, args)
+	if l.initializer != nil {
+		l.initializer.CallMethod(interpreter, instance, args)
 	}
 
 	return instance
 }
 
-func (l *LoxClass) Arity() int {
-	initializer, ok := l.FindMethod("init")
+// initializeFields gives instance the declared fields of the class and its
+// superclasses, superclass fields first, before init runs.
+func (l *LoxClass) initializeFields(interpreter *Interpreter, instance *LoxInstance) {
+	if l.SuperClass != nil {
+		l.SuperClass.initializeFields(interpreter, instance)
+	}
 
-	if ok {
-		return initializer.Arity()
+	interpreter.evaluateFields(l.fields, l.fieldSlots, l.closure, instance, instance.setField)
+}
+
+func (l *LoxClass) Arity() Arity {
+	if l.initializer != nil {
+		return l.initializer.Arity()
 	}
-	return 0
+	return exactly(0)
 }
 
 func (l *LoxClass) String() string {
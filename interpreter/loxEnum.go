@@ -0,0 +1,199 @@
+package interpreter
+
+import (
+	"fmt"
+	stm "lox/statement"
+	"lox/tokens"
+	"strings"
+)
+
+// LoxEnum is what an enum declaration evaluates to. Its properties are its
+// variants: the value itself for a variant without parameters, a
+// constructor for one with parameters.
+type LoxEnum struct {
+	Name     string
+	variants []any
+	byName   map[string]any
+}
+
+// enumVariant constructs the values of a variant with parameters.
+type enumVariant struct {
+	enum    *LoxEnum
+	name    string
+	ordinal int
+	params  []string
+}
+
+// LoxEnumValue is a variant without parameters, or a value constructed from
+// a variant with parameters and the arguments it was called with.
+type LoxEnumValue struct {
+	variant *enumVariant
+	values  []any
+}
+
+// enumKey is the key a Map stores a constructed enum value under. Chaining
+// the keys of its values keeps it comparable.
+type enumKey struct {
+	previous any
+	value    any
+}
+
+func newEnum(stmt *stm.EnumStmt) *LoxEnum {
+	enum := &LoxEnum{
+		Name:     stmt.Name.Lexeme,
+		variants: make([]any, len(stmt.Variants)),
+		byName:   make(map[string]any),
+	}
+
+	for ordinal, declared := range stmt.Variants {
+		variant := &enumVariant{enum: enum, name: declared.Name.Lexeme, ordinal: ordinal}
+
+		for _, param := range declared.Params {
+			variant.params = append(variant.params, param.Lexeme)
+		}
+
+		var value any = variant
+
+		if len(variant.params) == 0 {
+			value = &LoxEnumValue{variant: variant}
+		}
+
+		enum.variants[ordinal] = value
+		enum.byName[variant.name] = value
+	}
+
+	return enum
+}
+
+func (e *LoxEnum) Get(name tokens.Token, interpreter *Interpreter) (any, error) {
+	if variant, ok := e.byName[name.Lexeme]; ok {
+		return variant, nil
+	}
+
+	if name.Lexeme == "values" {
+		return nativeMethod(0, func(interpreter *Interpreter, args []any) any {
+			return NewLoxList(append([]any{}, e.variants...))
+		}), nil
+	}
+
+	return nil, fmt.Errorf("Enum %s has no variant \"%s\".", e.Name, stm.MemberName(name.Lexeme))
+}
+
+func (e *LoxEnum) Set(name tokens.Token, value any, interpreter *Interpreter) error {
+	return fmt.Errorf("Can't add variants to an enum.")
+}
+
+func (e *LoxEnum) String() string {
+	return "<enum " + e.Name + ">"
+}
+
+func (v *enumVariant) Call(interpreter *Interpreter, args []any) any {
+	return &LoxEnumValue{variant: v, values: args}
+}
+
+func (v *enumVariant) Arity() Arity {
+	return exactly(len(v.params))
+}
+
+func (v *enumVariant) String() string {
+	return "<variant " + v.enum.Name + "." + v.name + ">"
+}
+
+// value returns the value of the parameter name, if the variant has one.
+func (v *LoxEnumValue) value(name string) (any, bool) {
+	for index, param := range v.variant.params {
+		if param == name {
+			return v.values[index], true
+		}
+	}
+
+	return nil, false
+}
+
+func (v *LoxEnumValue) Get(name tokens.Token, interpreter *Interpreter) (any, error) {
+	if value, ok := v.value(name.Lexeme); ok {
+		return value, nil
+	}
+
+	switch name.Lexeme {
+	case "name":
+		return v.variant.name, nil
+	case "ordinal":
+		return float64(v.variant.ordinal), nil
+	}
+
+	return nil, fmt.Errorf("Undefined property \"%s\".", stm.MemberName(name.Lexeme))
+}
+
+func (v *LoxEnumValue) Set(name tokens.Token, value any, interpreter *Interpreter) error {
+	return fmt.Errorf("Can't set properties of an enum value.")
+}
+
+func (v *LoxEnumValue) format(interpreter *Interpreter) string {
+	text := v.variant.enum.Name + "." + v.variant.name
+
+	if len(v.variant.params) == 0 {
+		return text
+	}
+
+	values := make([]string, len(v.values))
+
+	for index, value := range v.values {
+		values[index] = interpreter.stringify(value)
+	}
+
+	return text + "(" + strings.Join(values, ", ") + ")"
+}
+
+// sameEnumValue compares constructed values by variant and by value.
+func (i *Interpreter) sameEnumValue(left, right *LoxEnumValue) bool {
+	if left.variant != right.variant {
+		return false
+	}
+
+	for index, value := range left.values {
+		if !i.equals(value, right.values[index]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (i *Interpreter) enumHashKey(value *LoxEnumValue) any {
+	var key any = value.variant
+
+	for _, element := range value.values {
+		key = enumKey{previous: key, value: i.hashKey(element)}
+	}
+
+	return key
+}
+
+// variantFields returns the values a class pattern naming variant reads from
+// value: those of its positional patterns in order, then the named ones.
+func variantFields(value any, variant *enumVariant, pattern *stm.ClassPattern) ([]any, bool) {
+	enumValue, ok := value.(*LoxEnumValue)
+
+	if !ok || enumValue.variant != variant {
+		return nil, false
+	}
+
+	if len(pattern.Positional) > len(variant.params) {
+		panic(fmt.Sprintf("Variant %s.%s has %d parameters but the pattern matches %d.\n [line %d]", variant.enum.Name, variant.name, len(variant.params), len(pattern.Positional), pattern.Paren.Line))
+	}
+
+	fields := append([]any{}, enumValue.values[:len(pattern.Positional)]...)
+
+	for _, name := range pattern.Names {
+		field, ok := enumValue.value(name.Lexeme)
+
+		if !ok {
+			return nil, false
+		}
+
+		fields = append(fields, field)
+	}
+
+	return fields, true
+}
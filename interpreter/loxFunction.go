@@ -1,17 +1,18 @@
 package interpreter
 
 import (
-	env "lox/environment"
 	stm "lox/statement"
 )
 
 type LoxFunction struct {
-	Declaration   stm.FunctionStm
-	Closure       *env.Environment
+	Declaration   *stm.FunctionStm
+	Closure       *Frame
 	isInitializer bool
+	isMethod      bool
+	body          compiledStmt
 }
 
-func NewLoxFunction(declaration stm.FunctionStm, closure *env.Environment, isInitialzier bool) *LoxFunction {
+func NewLoxFunction(declaration *stm.FunctionStm, closure *Frame, isInitialzier bool) *LoxFunction {
 	return &LoxFunction{
 		Declaration:   declaration,
 		Closure:       closure,
@@ -19,26 +20,80 @@ func NewLoxFunction(declaration stm.FunctionStm, closure *env.Environment, isIni
 	}
 }
 
-func (l *LoxFunction) Bind(instance *LoxInstance, interpreter *Interpreter) {
-	thisIndex := l.Declaration.ThisIndex
+// NewLoxMethod creates a function that keeps its receiver in the first slot
+// of its frame.
+func NewLoxMethod(declaration *stm.FunctionStm, closure *Frame, isInitialzier bool) *LoxFunction {
+	method := NewLoxFunction(declaration, closure, isInitialzier)
+	method.isMethod = true
 
-	if thisIndex != -1 {
-		interpreter.LocalVariables[thisIndex] = instance
+	return method
+}
+
+func (l *LoxFunction) Bind(this any) *BoundMethod {
+	return &BoundMethod{Method: l, This: this}
+}
+
+func (l *LoxFunction) Call(interpreter *Interpreter, args []any) any {
+	return l.CallMethod(interpreter, nil, args)
+}
+
+// CallMethod calls the function with this as its receiver. Tail calls made
+// by the body are run here, one after another, instead of nesting. Calling a
+// generator only creates the generator, and calling an async function runs
+// it up to its first await.
+func (l *LoxFunction) CallMethod(interpreter *Interpreter, this any, args []any) any {
+	function := l
+
+	for {
+		if function.Declaration.Generator {
+			return NewLoxGenerator(interpreter, function, this, args)
+		}
+
+		if function.Declaration.Async {
+			return startAsync(interpreter, function, this, args)
+		}
+
+		result, tailCall := function.invoke(interpreter, this, args)
+
+		if tailCall == nil {
+			return result
+		}
+
+		function = tailCall.Function
+		this = tailCall.This
+		args = tailCall.Arguments
 	}
 }
 
-func (l *LoxFunction) Call(interpreter *Interpreter, args []any) (result any) {
+func (l *LoxFunction) invoke(interpreter *Interpreter, this any, args []any) (result any, tailCall *TailCall) {
+	frame := NewFrame(l.Declaration.Slots, l.Closure, interpreter)
+	offset := 0
+
+	if l.isMethod {
+		frame.slots[0] = this
+		offset = 1
+	}
+
+	copy(frame.slots[offset:], frameArguments(l.Declaration.Parameters, args))
+
+	previous := interpreter.frame
+	interpreter.frame = frame
 
 	defer func() {
+		interpreter.frame = previous
 		value := recover()
-		thisIndex := l.Declaration.ThisIndex
 
 		if l.isInitializer {
-			result = interpreter.LocalVariables[thisIndex]
+			result = this
 		}
 
 		if value != nil {
 
+			if call, ok := value.(TailCall); ok {
+				tailCall = &call
+				return
+			}
+
 			returnValue, ok := value.(ReturnValue)
 
 			if !ok {
@@ -52,22 +107,39 @@ func (l *LoxFunction) Call(interpreter *Interpreter, args []any) (result any) {
 
 	}()
 
-	environment := env.NewEnvironment(l.Closure)
-
-	for i, param := range l.Declaration.Params {
-		index := interpreter.locals[param]
-		interpreter.LocalVariables[index] = args[i]
+	if l.body != nil {
+		l.body(frame)
+	} else {
+		interpreter.fillDefaults(l.Declaration.Parameters)
+		interpreter.executeBlock(l.Declaration.Body)
 	}
 
-	interpreter.executeBlock(l.Declaration.Body, environment)
-
 	return
 }
 
-func (l *LoxFunction) Arity() int {
-	return len(l.Declaration.Params)
+func (l *LoxFunction) Arity() Arity {
+	return ParameterArity(l.Declaration.Parameters)
 }
 
 func (l *LoxFunction) String() string {
 	return "<fn " + l.Declaration.Name.Lexeme + ">"
 }
+
+// BoundMethod is a method read off an instance as a value, such as
+// "var f = point.move;". It remembers the instance it was read from.
+type BoundMethod struct {
+	Method *LoxFunction
+	This   any
+}
+
+func (b *BoundMethod) Call(interpreter *Interpreter, args []any) any {
+	return b.Method.CallMethod(interpreter, b.This, args)
+}
+
+func (b *BoundMethod) Arity() Arity {
+	return b.Method.Arity()
+}
+
+func (b *BoundMethod) String() string {
+	return b.Method.String()
+}
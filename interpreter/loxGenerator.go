@@ -0,0 +1,170 @@
+package interpreter
+
+import (
+	"fmt"
+	stm "lox/statement"
+	"lox/tokens"
+	"runtime"
+)
+
+// LoxGenerator is what calling a generator function returns. Its body runs
+// as a coroutine, from one yield to the next each time next() is called.
+//
+// The goroutine only holds on to the embedded generatorRoutine. Once nothing
+// refers to the LoxGenerator anymore, its finalizer hands the routine to the
+// interpreter to be closed. A generator its own body refers to, such as one
+// stored on the instance whose method it runs, is never finalized; it is
+// closed by CloseGenerators when the program ends.
+type LoxGenerator struct {
+	*generatorRoutine
+}
+
+type generatorRoutine struct {
+	coroutine
+	function  *LoxFunction
+	this      any
+	arguments []any
+}
+
+func NewLoxGenerator(interpreter *Interpreter, function *LoxFunction, this any, arguments []any) *LoxGenerator {
+	interpreter.closeAbandoned()
+
+	generator := &LoxGenerator{&generatorRoutine{
+		function:  function,
+		this:      this,
+		arguments: arguments,
+	}}
+
+	runtime.SetFinalizer(generator, func(generator *LoxGenerator) {
+		interpreter.abandon(generator.generatorRoutine)
+	})
+
+	return generator
+}
+
+func (g *LoxGenerator) Get(name tokens.Token, interpreter *Interpreter) (any, error) {
+	switch name.Lexeme {
+	case NEXT_METHOD:
+		return nativeMethod(0, func(interpreter *Interpreter, args []any) any {
+			value, _ := g.next(interpreter)
+			return value
+		}), nil
+	case "close":
+		return nativeMethod(0, func(interpreter *Interpreter, args []any) any {
+			g.close(interpreter)
+			return nil
+		}), nil
+	}
+
+	return nil, fmt.Errorf("Undefined property \"%s\".", stm.MemberName(name.Lexeme))
+}
+
+func (g *LoxGenerator) Set(name tokens.Token, value any, interpreter *Interpreter) error {
+	return fmt.Errorf("Can't add properties to a generator.")
+}
+
+func (g *LoxGenerator) String() string {
+	return "<generator " + g.function.Declaration.Name.Lexeme + ">"
+}
+
+// next runs the body up to its next yield. ok is false once the body has
+// finished.
+func (g *generatorRoutine) next(interpreter *Interpreter) (any, bool) {
+	if g.done {
+		return nil, false
+	}
+
+	if !g.started {
+		interpreter.track(g)
+		g.start(func() any {
+			body := interpreter.fork()
+			body.generator = g
+			g.function.invoke(body, g.this, g.arguments)
+			return nil
+		})
+	}
+
+	step := g.step(true)
+
+	if step.done {
+		interpreter.untrack(g)
+	}
+
+	if step.err != nil {
+		panic(step.err)
+	}
+
+	return step.value, !step.done
+}
+
+// close stops a generator that hasn't finished, unwinding its body.
+func (g *generatorRoutine) close(interpreter *Interpreter) {
+	if g.started && !g.done {
+		interpreter.untrack(g)
+
+		if step := g.step(false); step.err != nil {
+			panic(step.err)
+		}
+	}
+	g.done = true
+}
+
+// yield hands value to the caller of next() and waits to be resumed.
+func (i *Interpreter) yield(value any) {
+	i.generator.suspend(value)
+}
+
+// abandon is called by the finalizer of a generator nothing refers to
+// anymore. It runs on the finalizer goroutine, so the generator is only
+// queued here and closed by the interpreter later.
+func (i *Interpreter) abandon(generator *generatorRoutine) {
+	i.generatorLock.Lock()
+	defer i.generatorLock.Unlock()
+
+	i.abandoned = append(i.abandoned, generator)
+}
+
+// closeAbandoned closes the generators whose finalizers have run, ending
+// their goroutines.
+func (i *Interpreter) closeAbandoned() {
+	i.generatorLock.Lock()
+	abandoned := i.abandoned
+	i.abandoned = nil
+	i.generatorLock.Unlock()
+
+	for _, generator := range abandoned {
+		generator.close(i)
+	}
+}
+
+// track records a generator whose body has started, until it finishes or is
+// closed.
+func (i *Interpreter) track(generator *generatorRoutine) {
+	i.generatorLock.Lock()
+	defer i.generatorLock.Unlock()
+
+	i.suspended[generator] = true
+}
+
+func (i *Interpreter) untrack(generator *generatorRoutine) {
+	i.generatorLock.Lock()
+	defer i.generatorLock.Unlock()
+
+	delete(i.suspended, generator)
+}
+
+// CloseGenerators closes every generator that started but didn't finish,
+// reachable or not, ending their goroutines. It is called once the program
+// and its event loop are done.
+func (i *Interpreter) CloseGenerators() {
+	i.closeAbandoned()
+
+	i.generatorLock.Lock()
+	suspended := i.suspended
+	i.suspended = make(map[*generatorRoutine]bool)
+	i.generatorLock.Unlock()
+
+	for generator := range suspended {
+		generator.close(i)
+	}
+}
@@ -0,0 +1,133 @@
+package interpreter_test
+
+import (
+	"lox/interpreter"
+	"lox/parser"
+	"lox/resolver"
+	"lox/scanner"
+	stm "lox/statement"
+	"lox/tokens"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// failingLogger fails the test on any error in the source under test.
+type failingLogger struct {
+	t *testing.T
+}
+
+func (l failingLogger) Error(line int, message string) {
+	l.t.Errorf("[line %d] %s", line, message)
+}
+
+func (l failingLogger) Report(line int, where string, message string) {
+	l.t.Errorf("[line %d] %s: %s", line, where, message)
+}
+
+func (l failingLogger) ErrorForToken(token tokens.Token, message string) error {
+	l.t.Errorf("[line %d] at %q: %s", token.Line, token.Lexeme, message)
+	return nil
+}
+
+func (l failingLogger) RuntimeError(message string) {
+	l.t.Error(message)
+}
+
+func (l failingLogger) Warning(token tokens.Token, message string) {}
+
+// resolve returns the resolved statements of source and the interpreter
+// they were resolved for.
+func resolve(t *testing.T, source string) ([]stm.Statement, *interpreter.Interpreter) {
+	t.Helper()
+
+	logger := failingLogger{t}
+	scan := scanner.NewScanner(logger)
+	scan.LoadSource(source)
+
+	parse := parser.NewParser(logger)
+	parse.LoadTokens(scan.ScanTokens())
+	statements := parse.Parse()
+
+	lox := interpreter.NewInterpreter(logger)
+	resolver.NewResolver(lox, logger).ResolveBlock(statements)
+
+	return statements, lox
+}
+
+// awaitGoroutines waits for a closed generator's goroutine, which ends just
+// after handing back its last step, to be gone.
+func awaitGoroutines(want int) int {
+	deadline := time.Now().Add(time.Second)
+
+	for runtime.NumGoroutine() > want && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	return runtime.NumGoroutine()
+}
+
+func TestGeneratorsEndTheirGoroutines(t *testing.T) {
+	tests := []struct {
+		name string
+		// closeAtEnd is whether the goroutines only end once the program is
+		// over and CloseGenerators has run.
+		closeAtEnd bool
+		source     string
+	}{
+		{"finished", false, `
+fun* two() { yield 1; yield 2; }
+for (value in two()) {}
+`},
+		{"closed", false, `
+fun* naturals() { var n = 0; while (true) { yield n; n = n + 1; } }
+var numbers = naturals();
+numbers.next();
+numbers.next();
+numbers.close();
+`},
+		{"left by break", true, `
+fun* naturals() { var n = 0; while (true) { yield n; n = n + 1; } }
+for (value in naturals()) {
+    if (value == 3) break;
+}
+`},
+		{"never resumed", true, `
+fun* naturals() { var n = 0; while (true) { yield n; n = n + 1; } }
+var numbers = naturals();
+numbers.next();
+`},
+		{"held by its own body", true, `
+class Counter {
+    init() { this.values = this.count(); }
+    *count() { var n = 0; while (this.values != nil) { yield n; n = n + 1; } }
+}
+var counter = Counter();
+counter.values.next();
+counter.values.next();
+`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// The parser keeps a goroutine of its own, so count from after
+			// parsing.
+			statements, lox := resolve(t, test.source)
+			before := runtime.NumGoroutine()
+			lox.Interpret(statements)
+
+			if !test.closeAtEnd {
+				if after := awaitGoroutines(before); after != before {
+					t.Errorf("%d goroutines left running", after-before)
+				}
+				return
+			}
+
+			lox.CloseGenerators()
+
+			if after := awaitGoroutines(before); after != before {
+				t.Errorf("%d goroutines left running after CloseGenerators", after-before)
+			}
+		})
+	}
+}
@@ -2,46 +2,94 @@ package interpreter
 
 import (
 	"fmt"
+	stm "lox/statement"
 	"lox/tokens"
 )
 
 type IloxInstance interface {
 	Get(name tokens.Token, interpreter *Interpreter) (any, error)
-	Set(name tokens.Token, value any)
+	Set(name tokens.Token, value any, interpreter *Interpreter) error
 }
 
 type LoxInstance struct {
 	class  *LoxClass
-	fields map[string]any
+	shape  *Shape
+	values []any
+	frozen bool
 }
 
 func NewLoxInstance(class *LoxClass) *LoxInstance {
 	return &LoxInstance{
 		class:  class,
-		fields: make(map[string]any),
+		shape:  class.rootShape,
+		values: make([]any, 0),
 	}
 }
 
 func (l *LoxInstance) Get(name tokens.Token, interpreter *Interpreter) (any, error) {
-	field, ok := l.fields[name.Lexeme]
+	slot, ok := l.shape.Slot(name.Lexeme)
 
 	if ok {
-		return field, nil
+		return l.values[slot], nil
+	}
+
+	if getter, ok := l.class.FindGetter(name.Lexeme); ok {
+		return getter.CallMethod(interpreter, l, nil), nil
 	}
 
 	method, ok := l.class.FindMethod(name.Lexeme)
 
 	if ok {
-		method.Bind(l, interpreter)
-		return method, nil
+		return method.Bind(l), nil
+	}
+
+	return nil, fmt.Errorf(fmt.Sprintf("Undefined property \"%s\".", stm.MemberName(name.Lexeme)))
+
+}
+
+// Set calls the setter for name if the class has one. Otherwise it stores a
+// field, moving the instance to a new shape when the field is new.
+func (l *LoxInstance) Set(name tokens.Token, value any, interpreter *Interpreter) error {
+	if l.frozen {
+		return fmt.Errorf("Can't modify a frozen %s instance.", l.class.Name)
+	}
+
+	slot, ok := l.shape.Slot(name.Lexeme)
+
+	if ok {
+		l.values[slot] = value
+		return nil
+	}
+
+	if setter, ok := l.class.FindSetter(name.Lexeme); ok {
+		setter.CallMethod(interpreter, l, []any{value})
+		return nil
+	}
+
+	if _, ok := l.class.FindGetter(name.Lexeme); ok {
+		return fmt.Errorf("Property \"%s\" has a getter but no setter.", stm.MemberName(name.Lexeme))
 	}
 
-	return nil, fmt.Errorf(fmt.Sprintf("Undefined property \"%s\".", name.Lexeme))
+	// Private fields are all declared, so a missing one means the instance
+	// isn't of the class that declared it.
+	if stm.IsPrivate(name.Lexeme) {
+		return fmt.Errorf("Instance has no private member \"%s\".", stm.MemberName(name.Lexeme))
+	}
 
+	l.setField(name.Lexeme, value)
+	return nil
 }
 
-func (l *LoxInstance) Set(name tokens.Token, value any) {
-	l.fields[name.Lexeme] = value
+func (l *LoxInstance) setField(name string, value any) {
+	slot, ok := l.shape.Slot(name)
+
+	if ok {
+		l.values[slot] = value
+		return
+	}
+
+	l.shape = l.shape.withField(name)
+	l.values = append(l.values, value)
 }
 
 func (l *LoxInstance) String() string {
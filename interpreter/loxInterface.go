@@ -0,0 +1,65 @@
+package interpreter
+
+import (
+	"fmt"
+	"sort"
+)
+
+// LoxInterface lists the methods, by name and arity, that a class declaring
+// "implements" must have.
+type LoxInterface struct {
+	Name    string
+	Methods map[string]int
+}
+
+func NewLoxInterface(name string, methods map[string]int) *LoxInterface {
+	return &LoxInterface{
+		Name:    name,
+		Methods: methods,
+	}
+}
+
+// check panics unless class has every method of the interface with the
+// right arity. An abstract method is enough; instantiating the class is
+// what fails then.
+func (i *LoxInterface) check(class *LoxClass) {
+	names := make([]string, 0, len(i.Methods))
+
+	for name := range i.Methods {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		arity, ok := class.findArity(name)
+
+		if !ok {
+			panic(fmt.Sprintf("Class %s does not implement method \"%s\" of %s.", class.Name, name, i.Name))
+		}
+
+		if !arity.accepts(i.Methods[name]) {
+			panic(fmt.Sprintf("Method \"%s\" of class %s must take %d parameters to implement %s.", name, class.Name, i.Methods[name], i.Name))
+		}
+	}
+}
+
+func (i *LoxInterface) String() string {
+	return "<interface " + i.Name + ">"
+}
+
+// toInterfaces checks that the values a class implements are interfaces.
+func toInterfaces(values []any) []*LoxInterface {
+	interfaces := make([]*LoxInterface, len(values))
+
+	for index, value := range values {
+		iface, ok := value.(*LoxInterface)
+
+		if !ok {
+			panic("Can only implement interfaces.")
+		}
+
+		interfaces[index] = iface
+	}
+
+	return interfaces
+}
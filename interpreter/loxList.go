@@ -0,0 +1,84 @@
+package interpreter
+
+import (
+	"fmt"
+	stm "lox/statement"
+	"lox/tokens"
+	"math"
+	"strings"
+)
+
+// LoxList is what a list literal evaluates to.
+type LoxList struct {
+	elements []any
+	frozen   bool
+}
+
+func NewLoxList(elements []any) *LoxList {
+	return &LoxList{elements: elements}
+}
+
+func (l *LoxList) Get(name tokens.Token, interpreter *Interpreter) (any, error) {
+	switch name.Lexeme {
+	case "push":
+		return nativeMethod(1, func(interpreter *Interpreter, args []any) any {
+			l.checkFrozen()
+			l.elements = append(l.elements, args[0])
+			return nil
+		}), nil
+	case "pop":
+		return nativeMethod(0, func(interpreter *Interpreter, args []any) any {
+			l.checkFrozen()
+
+			if len(l.elements) == 0 {
+				panic("Can't pop from an empty list.")
+			}
+
+			last := l.elements[len(l.elements)-1]
+			l.elements = l.elements[:len(l.elements)-1]
+			return last
+		}), nil
+	case "size":
+		return nativeMethod(0, func(interpreter *Interpreter, args []any) any {
+			return float64(len(l.elements))
+		}), nil
+	}
+
+	return nil, fmt.Errorf("Undefined property \"%s\".", stm.MemberName(name.Lexeme))
+}
+
+func (l *LoxList) checkFrozen() {
+	if l.frozen {
+		panic("Can't modify a frozen list.")
+	}
+}
+
+func (l *LoxList) Set(name tokens.Token, value any, interpreter *Interpreter) error {
+	return fmt.Errorf("Can't add properties to a list.")
+}
+
+func (l *LoxList) format(interpreter *Interpreter) string {
+	elements := make([]string, len(l.elements))
+
+	for index, element := range l.elements {
+		elements[index] = interpreter.stringify(element)
+	}
+
+	return "[" + strings.Join(elements, ", ") + "]"
+}
+
+// position checks that index is a whole number within a sequence of length
+// elements.
+func position(index any, length int, bracket tokens.Token) int {
+	number, ok := index.(float64)
+
+	if !ok || number != math.Trunc(number) {
+		panic(fmt.Sprintf("Index must be a whole number.\n [line %d]", bracket.Line))
+	}
+
+	if number < 0 || int(number) >= length {
+		panic(fmt.Sprintf("Index %d is out of range.\n [line %d]", int(number), bracket.Line))
+	}
+
+	return int(number)
+}
@@ -0,0 +1,243 @@
+package interpreter
+
+import (
+	"fmt"
+	stm "lox/statement"
+	"lox/tokens"
+	"strings"
+)
+
+// hashTable keeps entries in insertion order and finds them through their
+// hash key. Entries whose keys collide are told apart with equals().
+type hashTable struct {
+	buckets map[any][]*entry
+	entries []*entry
+}
+
+type entry struct {
+	key   any
+	value any
+}
+
+func newHashTable() *hashTable {
+	return &hashTable{buckets: make(map[any][]*entry)}
+}
+
+func (h *hashTable) find(interpreter *Interpreter, key any) (*entry, any) {
+	hash := interpreter.hashKey(key)
+
+	for _, candidate := range h.buckets[hash] {
+		if interpreter.equals(key, candidate.key) {
+			return candidate, hash
+		}
+	}
+
+	return nil, hash
+}
+
+func (h *hashTable) put(interpreter *Interpreter, key, value any) {
+	found, hash := h.find(interpreter, key)
+
+	if found != nil {
+		found.value = value
+		return
+	}
+
+	added := &entry{key: key, value: value}
+	h.buckets[hash] = append(h.buckets[hash], added)
+	h.entries = append(h.entries, added)
+}
+
+func (h *hashTable) remove(interpreter *Interpreter, key any) bool {
+	found, hash := h.find(interpreter, key)
+
+	if found == nil {
+		return false
+	}
+
+	h.buckets[hash] = without(h.buckets[hash], found)
+
+	if len(h.buckets[hash]) == 0 {
+		delete(h.buckets, hash)
+	}
+
+	h.entries = without(h.entries, found)
+
+	return true
+}
+
+func without(entries []*entry, removed *entry) []*entry {
+	kept := make([]*entry, 0, len(entries))
+
+	for _, entry := range entries {
+		if entry != removed {
+			kept = append(kept, entry)
+		}
+	}
+
+	return kept
+}
+
+// LoxMap is what Map() returns. Keys are compared with equals() and hashed
+// with hash() when their class defines them.
+type LoxMap struct {
+	table  *hashTable
+	frozen bool
+}
+
+func NewLoxMap() *LoxMap {
+	return &LoxMap{table: newHashTable()}
+}
+
+func (m *LoxMap) Get(name tokens.Token, interpreter *Interpreter) (any, error) {
+	switch name.Lexeme {
+	case "get":
+		return nativeMethod(1, func(interpreter *Interpreter, args []any) any {
+			return m.lookup(interpreter, args[0])
+		}), nil
+	case "set":
+		return nativeMethod(2, func(interpreter *Interpreter, args []any) any {
+			m.checkFrozen()
+			m.table.put(interpreter, args[0], args[1])
+			return nil
+		}), nil
+	case "has":
+		return nativeMethod(1, func(interpreter *Interpreter, args []any) any {
+			found, _ := m.table.find(interpreter, args[0])
+			return found != nil
+		}), nil
+	case "remove":
+		return nativeMethod(1, func(interpreter *Interpreter, args []any) any {
+			m.checkFrozen()
+			return m.table.remove(interpreter, args[0])
+		}), nil
+	case "size":
+		return nativeMethod(0, func(interpreter *Interpreter, args []any) any {
+			return float64(len(m.table.entries))
+		}), nil
+	case "keys":
+		return m.entries(func(entry *entry) any { return entry.key }), nil
+	case "values":
+		return m.entries(func(entry *entry) any { return entry.value }), nil
+	case "items":
+		return m.entries(func(entry *entry) any { return NewLoxList([]any{entry.key, entry.value}) }), nil
+	}
+
+	return nil, fmt.Errorf("Undefined property \"%s\".", stm.MemberName(name.Lexeme))
+}
+
+// entries returns a method producing a lazy iterator over the entries.
+func (m *LoxMap) entries(element func(entry *entry) any) *NativeFunctionCallable {
+	return nativeMethod(0, func(interpreter *Interpreter, args []any) any {
+		return NewLoxIterator(iterateEntries(m.table.entries, element))
+	})
+}
+
+func (m *LoxMap) checkFrozen() {
+	if m.frozen {
+		panic("Can't modify a frozen Map.")
+	}
+}
+
+func (m *LoxMap) Set(name tokens.Token, value any, interpreter *Interpreter) error {
+	return fmt.Errorf("Can't add properties to a Map.")
+}
+
+// lookup returns the value stored under key, or nil.
+func (m *LoxMap) lookup(interpreter *Interpreter, key any) any {
+	if found, _ := m.table.find(interpreter, key); found != nil {
+		return found.value
+	}
+	return nil
+}
+
+func (m *LoxMap) format(interpreter *Interpreter) string {
+	entries := make([]string, len(m.table.entries))
+
+	for index, entry := range m.table.entries {
+		entries[index] = interpreter.stringify(entry.key) + ": " + interpreter.stringify(entry.value)
+	}
+
+	return "{" + strings.Join(entries, ", ") + "}"
+}
+
+// LoxSet is what Set() returns. Its elements are compared and hashed the way
+// map keys are.
+type LoxSet struct {
+	table  *hashTable
+	frozen bool
+}
+
+func NewLoxSet() *LoxSet {
+	return &LoxSet{table: newHashTable()}
+}
+
+func (s *LoxSet) Get(name tokens.Token, interpreter *Interpreter) (any, error) {
+	switch name.Lexeme {
+	case "add":
+		return nativeMethod(1, func(interpreter *Interpreter, args []any) any {
+			s.checkFrozen()
+			s.table.put(interpreter, args[0], true)
+			return nil
+		}), nil
+	case "has":
+		return nativeMethod(1, func(interpreter *Interpreter, args []any) any {
+			found, _ := s.table.find(interpreter, args[0])
+			return found != nil
+		}), nil
+	case "remove":
+		return nativeMethod(1, func(interpreter *Interpreter, args []any) any {
+			s.checkFrozen()
+			return s.table.remove(interpreter, args[0])
+		}), nil
+	case "size":
+		return nativeMethod(0, func(interpreter *Interpreter, args []any) any {
+			return float64(len(s.table.entries))
+		}), nil
+	}
+
+	return nil, fmt.Errorf("Undefined property \"%s\".", stm.MemberName(name.Lexeme))
+}
+
+func (s *LoxSet) checkFrozen() {
+	if s.frozen {
+		panic("Can't modify a frozen Set.")
+	}
+}
+
+func (s *LoxSet) Set(name tokens.Token, value any, interpreter *Interpreter) error {
+	return fmt.Errorf("Can't add properties to a Set.")
+}
+
+func (s *LoxSet) format(interpreter *Interpreter) string {
+	elements := make([]string, len(s.table.entries))
+
+	for index, entry := range s.table.entries {
+		elements[index] = interpreter.stringify(entry.key)
+	}
+
+	return "Set{" + strings.Join(elements, ", ") + "}"
+}
+
+// freeze makes an instance, list, Map or Set read-only and returns it. Only
+// the value itself is frozen, not the values it holds.
+func freeze(interpreter *Interpreter, args []any) any {
+	switch value := args[0].(type) {
+	case *LoxInstance:
+		value.frozen = true
+	case *LoxList:
+		value.frozen = true
+	case *LoxMap:
+		value.frozen = true
+	case *LoxSet:
+		value.frozen = true
+	default:
+		panic(fmt.Sprintf("Can only freeze instances, lists, maps and sets, not %s.", interpreter.stringify(args[0])))
+	}
+
+	return args[0]
+}
+
+func nativeMethod(arity int, call func(interpreter *Interpreter, args []any) any) *NativeFunctionCallable {
+	return NewNativeFnCallable(exactly(arity), call)
+}
@@ -0,0 +1,53 @@
+package interpreter
+
+import (
+	"fmt"
+	env "lox/environment"
+	stm "lox/statement"
+	"lox/tokens"
+)
+
+// ModuleLoader finds the file an import refers to and turns it into resolved
+// statements.
+type ModuleLoader interface {
+	FindModule(path string, importer string) (string, error)
+	LoadModule(path string) ([]stm.Statement, error)
+}
+
+// LoxModule is one loaded file. Its top-level declarations live in its own
+// globals; importers only see the ones marked with "export".
+type LoxModule struct {
+	Path    string
+	globals *env.Environment
+	exports map[string]bool
+	loading bool
+}
+
+func NewLoxModule(path string, globals *env.Environment) *LoxModule {
+	return &LoxModule{
+		Path:    path,
+		globals: globals,
+		exports: make(map[string]bool),
+	}
+}
+
+func (m *LoxModule) Get(name tokens.Token, interpreter *Interpreter) (any, error) {
+	if !m.exports[name.Lexeme] {
+		return nil, fmt.Errorf("Module \"%s\" does not export \"%s\".", m.Path, name.Lexeme)
+	}
+
+	return m.globals.Values[name.Lexeme], nil
+}
+
+// constant reports whether the exported name is a constant of the module.
+func (m *LoxModule) constant(name tokens.Token) bool {
+	return m.globals.Constants[name.Lexeme]
+}
+
+func (m *LoxModule) Set(name tokens.Token, value any, interpreter *Interpreter) error {
+	return fmt.Errorf("Can't assign to \"%s\" of module \"%s\".", name.Lexeme, m.Path)
+}
+
+func (m *LoxModule) String() string {
+	return fmt.Sprintf("<module %s>", m.Path)
+}
@@ -0,0 +1,276 @@
+package interpreter
+
+import (
+	"fmt"
+	stm "lox/statement"
+	"lox/tokens"
+	"strings"
+)
+
+type promiseState int
+
+const (
+	PENDING promiseState = iota
+	FULFILLED
+	REJECTED
+)
+
+// LoxPromise is a value that becomes available later. It is what calling an
+// async function or Promise(executor) returns. Reactions to it run as
+// microtasks of the event loop once it settles.
+type LoxPromise struct {
+	state     promiseState
+	value     any
+	locked    bool
+	reactions []func(interpreter *Interpreter)
+	handled   bool
+}
+
+// asyncRoutine runs the body of an async function, which suspends at each
+// await until the awaited promise settles.
+type asyncRoutine struct {
+	coroutine
+	promise *LoxPromise
+}
+
+func NewLoxPromise() *LoxPromise {
+	return &LoxPromise{}
+}
+
+// newPromise calls executor with the resolve and reject functions of a new
+// promise. A runtime error in executor rejects the promise.
+func newPromise(interpreter *Interpreter, args []any) any {
+	executor := callback(args[0], "Promise()", 2)
+	promise := NewLoxPromise()
+
+	resolve := nativeMethod(1, func(interpreter *Interpreter, args []any) any {
+		promise.resolve(interpreter, args[0])
+		return nil
+	})
+	reject := nativeMethod(1, func(interpreter *Interpreter, args []any) any {
+		promise.reject(interpreter, args[0])
+		return nil
+	})
+
+	if _, err, failed := interpreter.attempt(func() any {
+		return executor.Call(interpreter, []any{resolve, reject})
+	}); failed {
+		promise.reject(interpreter, err)
+	}
+
+	return promise
+}
+
+func (p *LoxPromise) Get(name tokens.Token, interpreter *Interpreter) (any, error) {
+	switch name.Lexeme {
+	case "then":
+		return nativeMethod(1, func(interpreter *Interpreter, args []any) any {
+			return p.chain(interpreter, FULFILLED, callback(args[0], "then()", 1))
+		}), nil
+	case "catch":
+		return nativeMethod(1, func(interpreter *Interpreter, args []any) any {
+			return p.chain(interpreter, REJECTED, callback(args[0], "catch()", 1))
+		}), nil
+	}
+
+	return nil, fmt.Errorf("Undefined property \"%s\".", stm.MemberName(name.Lexeme))
+}
+
+func (p *LoxPromise) Set(name tokens.Token, value any, interpreter *Interpreter) error {
+	return fmt.Errorf("Can't add properties to a promise.")
+}
+
+func (p *LoxPromise) String() string {
+	return "<promise>"
+}
+
+// chain returns a promise settled by handler once p settles in state, or
+// settled like p otherwise.
+func (p *LoxPromise) chain(interpreter *Interpreter, state promiseState, handler Callable) *LoxPromise {
+	chained := NewLoxPromise()
+
+	p.whenSettled(interpreter, func(interpreter *Interpreter) {
+		if p.state != state {
+			chained.settle(interpreter, p.state, p.value)
+			return
+		}
+
+		result, err, failed := interpreter.attempt(func() any {
+			return handler.Call(interpreter, []any{p.value})
+		})
+
+		if failed {
+			chained.reject(interpreter, err)
+		} else {
+			chained.resolve(interpreter, result)
+		}
+	})
+
+	return chained
+}
+
+// resolve fulfills p with value. Resolving with another promise makes p
+// settle like it.
+func (p *LoxPromise) resolve(interpreter *Interpreter, value any) {
+	if p.state != PENDING || p.locked {
+		return
+	}
+
+	other, ok := value.(*LoxPromise)
+
+	if !ok {
+		p.settle(interpreter, FULFILLED, value)
+		return
+	}
+
+	if other == p {
+		p.settle(interpreter, REJECTED, "A promise can't be resolved with itself.")
+		return
+	}
+
+	p.locked = true
+	other.whenSettled(interpreter, func(interpreter *Interpreter) {
+		p.settle(interpreter, other.state, other.value)
+	})
+}
+
+func (p *LoxPromise) reject(interpreter *Interpreter, value any) {
+	if p.state != PENDING || p.locked {
+		return
+	}
+
+	p.settle(interpreter, REJECTED, value)
+}
+
+func (p *LoxPromise) settle(interpreter *Interpreter, state promiseState, value any) {
+	if p.state != PENDING {
+		return
+	}
+
+	p.state = state
+	p.value = value
+
+	for _, reaction := range p.reactions {
+		interpreter.loop.queueMicrotask(reaction)
+	}
+	p.reactions = nil
+
+	if state == REJECTED && !p.handled {
+		interpreter.loop.unhandled = append(interpreter.loop.unhandled, p)
+	}
+}
+
+// whenSettled runs reaction as a microtask once p has settled.
+func (p *LoxPromise) whenSettled(interpreter *Interpreter, reaction func(interpreter *Interpreter)) {
+	p.handled = true
+
+	if p.state == PENDING {
+		p.reactions = append(p.reactions, reaction)
+		return
+	}
+
+	interpreter.loop.queueMicrotask(reaction)
+}
+
+// startAsync runs an async function up to its first await and returns the
+// promise its result settles.
+func startAsync(interpreter *Interpreter, function *LoxFunction, this any, arguments []any) *LoxPromise {
+	routine := &asyncRoutine{promise: NewLoxPromise()}
+
+	routine.start(func() any {
+		body := interpreter.fork()
+		body.async = routine
+		result, _ := function.invoke(body, this, arguments)
+		return result
+	})
+	routine.advance(interpreter)
+
+	return routine.promise
+}
+
+// advance runs the body up to its next await, and settles the promise once
+// the body has finished.
+func (a *asyncRoutine) advance(interpreter *Interpreter) {
+	step := a.step(true)
+
+	if !step.done {
+		return
+	}
+
+	if step.err == nil {
+		a.promise.resolve(interpreter, step.value)
+		return
+	}
+
+	message, ok := step.err.(string)
+
+	if !ok {
+		panic(step.err)
+	}
+
+	a.promise.reject(interpreter, errorValue(message))
+}
+
+// await suspends the async function running on i until value settles, and
+// returns what it was fulfilled with. A rejection is raised as a runtime
+// error.
+func (i *Interpreter) await(value any) any {
+	promise, ok := value.(*LoxPromise)
+
+	if !ok {
+		promise = NewLoxPromise()
+		promise.resolve(i, value)
+	}
+
+	promise.whenSettled(i, i.async.advance)
+	i.async.suspend(nil)
+
+	if promise.state == REJECTED {
+		if message, ok := promise.value.(string); ok {
+			panic(message)
+		}
+		panic(i.stringify(promise.value))
+	}
+
+	return promise.value
+}
+
+// attempt calls body, catching the runtime errors it raises.
+func (i *Interpreter) attempt(body func() any) (result any, err any, failed bool) {
+	loops, breaking := i.nearestEnclosingLoop, i.breaking
+
+	defer func() {
+		if value := recover(); value != nil {
+			message, ok := value.(string)
+
+			if !ok {
+				panic(value)
+			}
+
+			i.nearestEnclosingLoop, i.breaking = loops, breaking
+			err, failed = errorValue(message), true
+		}
+	}()
+
+	return body(), nil, false
+}
+
+// errorValue is the value a runtime error rejects a promise with.
+func errorValue(message string) string {
+	return strings.TrimSpace(message)
+}
+
+// callback checks that value is a function taking arity arguments, for the
+// native function called name.
+func callback(value any, name string, arity int) Callable {
+	function, ok := value.(Callable)
+
+	if !ok || !function.Arity().accepts(arity) {
+		if arity == 1 {
+			panic(fmt.Sprintf("%s expects a function with 1 parameter.", name))
+		}
+		panic(fmt.Sprintf("%s expects a function with %d parameters.", name, arity))
+	}
+
+	return function
+}
@@ -0,0 +1,60 @@
+package interpreter
+
+import (
+	"fmt"
+)
+
+// LoxTrait is a named set of methods. A class including it gets copies of
+// them, which its own methods override.
+type LoxTrait struct {
+	Name    string
+	Methods map[string]*LoxFunction
+}
+
+func NewLoxTrait(name string, methods map[string]*LoxFunction) *LoxTrait {
+	return &LoxTrait{
+		Name:    name,
+		Methods: methods,
+	}
+}
+
+func (t *LoxTrait) String() string {
+	return "<trait " + t.Name + ">"
+}
+
+// traitMethods merges the methods of traits. A method two traits provide is
+// an error unless overridden lists it.
+func traitMethods(traits []*LoxTrait, overridden map[string]bool) map[string]*LoxFunction {
+	methods := make(map[string]*LoxFunction)
+	providers := make(map[string]*LoxTrait)
+
+	for _, trait := range traits {
+		for name, method := range trait.Methods {
+			if provider, ok := providers[name]; ok && provider != trait && !overridden[name] {
+				panic(fmt.Sprintf("Method \"%s\" is provided by both %s and %s.", name, provider.Name, trait.Name))
+			}
+
+			methods[name] = method
+			providers[name] = trait
+		}
+	}
+
+	return methods
+}
+
+// toTraits checks that the values a class includes are traits.
+func toTraits(values []any) []*LoxTrait {
+	traits := make([]*LoxTrait, len(values))
+
+	for index, value := range values {
+		trait, ok := value.(*LoxTrait)
+
+		if !ok {
+			panic("Can only include traits.")
+		}
+
+		traits[index] = trait
+	}
+
+	return traits
+}
@@ -0,0 +1,115 @@
+package interpreter
+
+import (
+	"fmt"
+	"lox/tokens"
+)
+
+// Special methods a class defines to give its instances operators and
+// built-in behavior.
+const (
+	ADD_METHOD   = "__add__"
+	SUB_METHOD   = "__sub__"
+	MUL_METHOD   = "__mul__"
+	DIV_METHOD   = "__div__"
+	LT_METHOD    = "__lt__"
+	EQ_METHOD    = "__eq__"
+	NEG_METHOD   = "__neg__"
+	INDEX_METHOD = "__index__"
+	CALL_METHOD  = "__call__"
+	STR_METHOD   = "__str__"
+)
+
+// specialMethod returns the method called name when value is an instance
+// whose class defines it.
+func specialMethod(value any, name string) (*LoxInstance, *LoxFunction, bool) {
+	instance, ok := value.(*LoxInstance)
+
+	if !ok {
+		return nil, nil, false
+	}
+
+	method, ok := instance.class.FindMethod(name)
+
+	return instance, method, ok
+}
+
+// callSpecial calls the special method name of receiver. ok is false when
+// receiver doesn't define it.
+func (i *Interpreter) callSpecial(receiver any, name string, token tokens.Token, arguments ...any) (any, bool) {
+	instance, method, ok := specialMethod(receiver, name)
+
+	if !ok {
+		return nil, false
+	}
+
+	i.checkArity(method, arguments, token)
+
+	return method.CallMethod(i, instance, arguments), true
+}
+
+// overloadBinary runs the special method behind operator. Arithmetic is
+// dispatched to the left operand. All comparisons are derived from __lt__,
+// so "a > b" asks b and "a <= b" is "not b < a".
+func (i *Interpreter) overloadBinary(operator tokens.Token, left, right any) (any, bool) {
+	switch operator.TokenType {
+	case tokens.PLUS:
+		return i.callSpecial(left, ADD_METHOD, operator, right)
+	case tokens.MINUS:
+		return i.callSpecial(left, SUB_METHOD, operator, right)
+	case tokens.STAR:
+		return i.callSpecial(left, MUL_METHOD, operator, right)
+	case tokens.SLASH:
+		return i.callSpecial(left, DIV_METHOD, operator, right)
+	case tokens.LESS:
+		return i.callSpecial(left, LT_METHOD, operator, right)
+	case tokens.GREATER:
+		return i.callSpecial(right, LT_METHOD, operator, left)
+	case tokens.LESS_EQUAL:
+		if result, ok := i.callSpecial(right, LT_METHOD, operator, left); ok {
+			return !i.isTruthy(result), true
+		}
+	case tokens.GREATER_EQUAL:
+		if result, ok := i.callSpecial(left, LT_METHOD, operator, right); ok {
+			return !i.isTruthy(result), true
+		}
+	}
+
+	return nil, false
+}
+
+// index evaluates "object[index]".
+func (i *Interpreter) index(object, index any, bracket tokens.Token) any {
+	switch target := object.(type) {
+	case *LoxMap:
+		return target.lookup(i, index)
+	case *LoxList:
+		return target.elements[position(index, len(target.elements), bracket)]
+	case string:
+		characters := []rune(target)
+		return string(characters[position(index, len(characters), bracket)])
+	}
+
+	if result, ok := i.callSpecial(object, INDEX_METHOD, bracket, index); ok {
+		return result
+	}
+
+	panic(fmt.Sprintf("Only lists, strings, maps and instances defining %s can be indexed.\n [line %d]", INDEX_METHOD, bracket.Line))
+}
+
+// instanceString returns what __str__ makes of instance.
+func (i *Interpreter) instanceString(instance *LoxInstance) (string, bool) {
+	result, ok := i.callSpecial(instance, STR_METHOD, tokens.Token{})
+
+	if !ok {
+		return "", false
+	}
+
+	text, ok := result.(string)
+
+	if !ok {
+		panic(fmt.Sprintf("%s must return a string.", STR_METHOD))
+	}
+
+	return text, true
+}
@@ -0,0 +1,205 @@
+package interpreter
+
+import (
+	"fmt"
+	stm "lox/statement"
+	"lox/tokens"
+)
+
+// matchCase returns the first of cases whose pattern matches value and whose
+// guard holds, with the names its pattern binds set in the current frame.
+func (i *Interpreter) matchCase(cases []*stm.MatchCase, value any) (*stm.MatchCase, bool) {
+	for _, matchCase := range cases {
+		if !i.matches(matchCase.Pattern, value) {
+			continue
+		}
+
+		if matchCase.Guard == nil || i.isTruthy(i.evaluate(matchCase.Guard)) {
+			return matchCase, true
+		}
+	}
+
+	return nil, false
+}
+
+func (i *Interpreter) matches(pattern stm.Pattern, value any) bool {
+	switch p := pattern.(type) {
+	case *stm.LiteralPattern:
+		return i.equals(value, p.Value)
+	case *stm.WildcardPattern:
+		return true
+	case *stm.BindingPattern:
+		i.frame.slots[i.locals[p.Name].index] = value
+		return true
+	case *stm.ValuePattern:
+		return i.equals(value, i.evaluate(p.Value))
+	case *stm.ListPattern:
+		elements, ok := listOfLength(value, len(p.Elements), p.Rest != nil)
+
+		if !ok {
+			return false
+		}
+
+		for index, element := range p.Elements {
+			if !i.matches(element, elements[index]) {
+				return false
+			}
+		}
+		return p.Rest == nil || i.matches(p.Rest, rest(elements, len(p.Elements)))
+	case *stm.MapPattern:
+		for index, key := range p.Keys {
+			member, ok := i.member(value, key)
+
+			if !ok || !i.matches(p.Values[index], member) {
+				return false
+			}
+		}
+		return true
+	case *stm.ClassPattern:
+		fields, ok := i.classFields(value, i.evaluate(p.Class), p)
+
+		if !ok {
+			return false
+		}
+
+		patterns := append(append([]stm.Pattern{}, p.Positional...), p.Named...)
+
+		for index, field := range fields {
+			if !i.matches(patterns[index], field) {
+				return false
+			}
+		}
+		return true
+	}
+
+	return false
+}
+
+// listOfLength returns the elements of value if it is a list of length
+// elements, or of at least length elements when orMore is set.
+func listOfLength(value any, length int, orMore bool) ([]any, bool) {
+	list, ok := value.(*LoxList)
+
+	if !ok || len(list.elements) < length || len(list.elements) > length && !orMore {
+		return nil, false
+	}
+
+	return list.elements, true
+}
+
+// member returns what a map stores under key, or the field key of an
+// instance.
+func (i *Interpreter) member(value any, key any) (any, bool) {
+	switch container := value.(type) {
+	case *LoxMap:
+		if found, _ := container.table.find(i, key); found != nil {
+			return found.value, true
+		}
+	case *LoxInstance:
+		if name, ok := key.(string); ok {
+			return i.field(container, name)
+		}
+	}
+
+	return nil, false
+}
+
+// field returns the field name of instance, or what its getter returns.
+// Methods aren't fields.
+func (i *Interpreter) field(instance *LoxInstance, name string) (any, bool) {
+	if slot, ok := instance.shape.Slot(name); ok {
+		return instance.values[slot], true
+	}
+
+	if getter, ok := instance.class.FindGetter(name); ok {
+		return getter.CallMethod(i, instance, nil), true
+	}
+
+	return nil, false
+}
+
+// classFields returns the values a class pattern reads from value, in the
+// order of its subpatterns, if value is of the class it names.
+func (i *Interpreter) classFields(value any, class any, pattern *stm.ClassPattern) ([]any, bool) {
+	if variant, ok := class.(*enumVariant); ok {
+		return variantFields(value, variant, pattern)
+	}
+
+	instance, ok := instanceOf(value, class, pattern.Paren)
+
+	if !ok {
+		return nil, false
+	}
+
+	names := fieldNames(pattern)
+	fields := make([]any, len(names))
+
+	for index, name := range names {
+		field, ok := i.field(instance, name)
+
+		if !ok {
+			return nil, false
+		}
+
+		fields[index] = field
+	}
+
+	return fields, true
+}
+
+// instanceOf returns value if it is an instance of class or a subclass of it,
+// or of a class including the trait or implementing the interface class.
+func instanceOf(value any, class any, paren tokens.Token) (*LoxInstance, bool) {
+	instance, isInstance := value.(*LoxInstance)
+
+	switch class := class.(type) {
+	case *LoxClass:
+		if isInstance {
+			for candidate := instance.class; candidate != nil; candidate = candidate.SuperClass {
+				if candidate == class {
+					return instance, true
+				}
+			}
+		}
+	case *LoxTrait:
+		if isInstance && instance.class.Includes(class) {
+			return instance, true
+		}
+	case *LoxInterface:
+		if isInstance && instance.class.Implements(class) {
+			return instance, true
+		}
+	default:
+		panic(fmt.Sprintf("Class pattern must name a class, trait, interface or enum variant.\n [line %d]", paren.Line))
+	}
+
+	return nil, false
+}
+
+// fieldNames returns the fields a class pattern reads: those its positional
+// patterns bind, then those it names.
+func fieldNames(pattern *stm.ClassPattern) []string {
+	names := make([]string, 0, len(pattern.Positional)+len(pattern.Names))
+
+	for _, positional := range pattern.Positional {
+		binding, ok := positional.(*stm.BindingPattern)
+
+		if !ok {
+			panic(fmt.Sprintf("Positional patterns of a class pattern must be field names.\n [line %d]", pattern.Paren.Line))
+		}
+
+		names = append(names, binding.Name.Lexeme)
+	}
+
+	for _, name := range pattern.Names {
+		names = append(names, name.Lexeme)
+	}
+
+	return names
+}
+
+// noMatch raises the error of a match expression none of whose cases
+// matches value.
+func (i *Interpreter) noMatch(value any, keyword tokens.Token) {
+	panic(fmt.Sprintf("No case matches %s.\n [line %d]", i.stringify(value), keyword.Line))
+}
@@ -0,0 +1,51 @@
+package interpreter
+
+// Shape is the hidden class of a LoxInstance: it maps field names to slots in
+// the instance's values. Instances that got the same fields in the same order
+// share a shape, so a lookup cached against a shape stays valid until the
+// instance gains a field and moves on to another shape.
+type Shape struct {
+	class       *LoxClass
+	slots       map[string]int
+	transitions map[string]*Shape
+}
+
+func newRootShape(class *LoxClass) *Shape {
+	return &Shape{
+		class:       class,
+		slots:       make(map[string]int),
+		transitions: make(map[string]*Shape),
+	}
+}
+
+func (s *Shape) Slot(name string) (int, bool) {
+	slot, ok := s.slots[name]
+	return slot, ok
+}
+
+// withField returns the shape an instance moves to when name is added.
+func (s *Shape) withField(name string) *Shape {
+	next, ok := s.transitions[name]
+
+	if ok {
+		return next
+	}
+
+	slots := make(map[string]int, len(s.slots)+1)
+
+	for field, slot := range s.slots {
+		slots[field] = slot
+	}
+
+	slots[name] = len(s.slots)
+
+	next = &Shape{
+		class:       s.class,
+		slots:       slots,
+		transitions: make(map[string]*Shape),
+	}
+
+	s.transitions[name] = next
+
+	return next
+}
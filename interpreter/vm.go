@@ -0,0 +1,317 @@
+package interpreter
+
+import (
+	"fmt"
+	"lox/bytecode"
+	env "lox/environment"
+	"lox/tokens"
+)
+
+// MAX_FRAMES bounds the calls a script compiled to bytecode can nest.
+const MAX_FRAMES = 1 << 16
+
+var vmOperators = map[bytecode.OpCode]tokens.Token{
+	bytecode.OP_ADD:           {TokenType: tokens.PLUS, Lexeme: "+"},
+	bytecode.OP_SUBTRACT:      {TokenType: tokens.MINUS, Lexeme: "-"},
+	bytecode.OP_MULTIPLY:      {TokenType: tokens.STAR, Lexeme: "*"},
+	bytecode.OP_DIVIDE:        {TokenType: tokens.SLASH, Lexeme: "/"},
+	bytecode.OP_GREATER:       {TokenType: tokens.GREATER, Lexeme: ">"},
+	bytecode.OP_GREATER_EQUAL: {TokenType: tokens.GREATER_EQUAL, Lexeme: ">="},
+	bytecode.OP_LESS:          {TokenType: tokens.LESS, Lexeme: "<"},
+	bytecode.OP_LESS_EQUAL:    {TokenType: tokens.LESS_EQUAL, Lexeme: "<="},
+	bytecode.OP_EQUAL:         {TokenType: tokens.EQUAL_EQUAL, Lexeme: "=="},
+	bytecode.OP_NOT_EQUAL:     {TokenType: tokens.BANG_EQUAL, Lexeme: "!="},
+	bytecode.OP_NEGATE:        {TokenType: tokens.MINUS, Lexeme: "-"},
+	bytecode.OP_NOT:           {TokenType: tokens.BANG, Lexeme: "!"},
+}
+
+// vmClosure is a function compiled to bytecode together with the variables
+// it captured.
+type vmClosure struct {
+	function *bytecode.Function
+	upvalues []*vmUpvalue
+}
+
+// Call runs the closure on a VM of its own, for callers such as native
+// functions and timers that call it from Go.
+func (c *vmClosure) Call(interpreter *Interpreter, args []any) any {
+	return newVM(interpreter).call(c, args)
+}
+
+func (c *vmClosure) Arity() Arity {
+	return exactly(c.function.Arity)
+}
+
+func (c *vmClosure) String() string {
+	return c.function.String()
+}
+
+// vmUpvalue is a captured variable. It refers to a slot on the stack of the
+// VM that created it until the function owning the slot returns, and holds
+// the value itself after that.
+type vmUpvalue struct {
+	vm     *vm
+	index  int
+	closed bool
+	value  any
+}
+
+func (u *vmUpvalue) get() any {
+	if u.closed {
+		return u.value
+	}
+	return u.vm.stack[u.index]
+}
+
+func (u *vmUpvalue) set(value any) {
+	if u.closed {
+		u.value = value
+	} else {
+		u.vm.stack[u.index] = value
+	}
+}
+
+type vmFrame struct {
+	closure *vmClosure
+	ip      int
+	base    int
+}
+
+func (f *vmFrame) readByte() int {
+	f.ip++
+	return int(f.closure.function.Chunk.Code[f.ip-1])
+}
+
+func (f *vmFrame) readShort() int {
+	f.ip += 2
+	return int(f.closure.function.Chunk.ReadShort(f.ip - 2))
+}
+
+func (f *vmFrame) readName(line int) tokens.Token {
+	name := f.closure.function.Chunk.Constants[f.readShort()].(string)
+	return tokens.Token{TokenType: tokens.IDENTIFIER, Lexeme: name, Line: line}
+}
+
+// vm runs bytecode. Values are the same as the interpreter's, and operators,
+// calls of anything but compiled functions and printing go through the
+// interpreter, so both report the same results and errors.
+type vm struct {
+	interpreter *Interpreter
+	globals     *env.Environment
+	stack       []any
+	frames      []vmFrame
+	open        []*vmUpvalue
+}
+
+func newVM(interpreter *Interpreter) *vm {
+	return &vm{
+		interpreter: interpreter,
+		globals:     interpreter.root.globals,
+		stack:       make([]any, 0, 256),
+		frames:      make([]vmFrame, 0, 16),
+		open:        make([]*vmUpvalue, 0),
+	}
+}
+
+// InterpretChunk runs a script compiled to bytecode, such as one loaded from
+// a cache file, in place of its statements.
+func (i *Interpreter) InterpretChunk(script *bytecode.Function) {
+	defer i.afterPanic()
+	defer i.closeAbandoned()
+
+	newVM(i).call(&vmClosure{function: script}, nil)
+	i.awaitFibers()
+}
+
+// call runs closure until it returns and returns its result.
+func (m *vm) call(closure *vmClosure, args []any) any {
+	m.push(closure)
+	m.stack = append(m.stack, args...)
+	m.enter(closure, len(args))
+
+	return m.run(len(m.frames) - 1)
+}
+
+func (m *vm) enter(closure *vmClosure, argCount int) {
+	if len(m.frames) == MAX_FRAMES {
+		panic("Stack overflow.")
+	}
+
+	m.frames = append(m.frames, vmFrame{closure: closure, base: len(m.stack) - argCount - 1})
+}
+
+func (m *vm) push(value any) {
+	m.stack = append(m.stack, value)
+}
+
+func (m *vm) pop() any {
+	value := m.stack[len(m.stack)-1]
+	m.stack = m.stack[:len(m.stack)-1]
+	return value
+}
+
+func (m *vm) peek(distance int) any {
+	return m.stack[len(m.stack)-1-distance]
+}
+
+// capture returns the upvalue for the stack slot at index, shared by every
+// closure capturing it.
+func (m *vm) capture(index int) *vmUpvalue {
+	for _, upvalue := range m.open {
+		if upvalue.index == index {
+			return upvalue
+		}
+	}
+
+	upvalue := &vmUpvalue{vm: m, index: index}
+	m.open = append(m.open, upvalue)
+	return upvalue
+}
+
+// closeUpvalues moves the values of the slots from base up into the upvalues
+// capturing them.
+func (m *vm) closeUpvalues(base int) {
+	open := m.open[:0]
+
+	for _, upvalue := range m.open {
+		if upvalue.index >= base {
+			upvalue.value = m.stack[upvalue.index]
+			upvalue.closed = true
+		} else {
+			open = append(open, upvalue)
+		}
+	}
+
+	m.open = open
+}
+
+// run executes instructions until the frame at depth returns.
+func (m *vm) run(depth int) any {
+	i := m.interpreter
+
+	for {
+		frame := &m.frames[len(m.frames)-1]
+		chunk := frame.closure.function.Chunk
+		line := chunk.Lines[frame.ip]
+		op := bytecode.OpCode(chunk.Code[frame.ip])
+		frame.ip++
+
+		switch op {
+		case bytecode.OP_CONSTANT:
+			m.push(chunk.Constants[frame.readShort()])
+		case bytecode.OP_NIL:
+			m.push(nil)
+		case bytecode.OP_TRUE:
+			m.push(true)
+		case bytecode.OP_FALSE:
+			m.push(false)
+		case bytecode.OP_POP:
+			m.pop()
+
+		case bytecode.OP_GET_LOCAL:
+			m.push(m.stack[frame.base+frame.readByte()])
+		case bytecode.OP_SET_LOCAL:
+			m.stack[frame.base+frame.readByte()] = m.peek(0)
+		case bytecode.OP_GET_UPVALUE:
+			m.push(frame.closure.upvalues[frame.readByte()].get())
+		case bytecode.OP_SET_UPVALUE:
+			frame.closure.upvalues[frame.readByte()].set(m.peek(0))
+		case bytecode.OP_GET_GLOBAL:
+			m.push(m.globals.Get(frame.readName(line)))
+		case bytecode.OP_DEFINE_GLOBAL:
+			m.globals.Define(frame.readName(line).Lexeme, m.pop())
+		case bytecode.OP_SET_GLOBAL:
+			m.globals.Assign(frame.readName(line), m.peek(0))
+
+		case bytecode.OP_ADD, bytecode.OP_SUBTRACT, bytecode.OP_MULTIPLY, bytecode.OP_DIVIDE,
+			bytecode.OP_GREATER, bytecode.OP_GREATER_EQUAL, bytecode.OP_LESS, bytecode.OP_LESS_EQUAL,
+			bytecode.OP_EQUAL, bytecode.OP_NOT_EQUAL:
+			operator := vmOperators[op]
+			operator.Line = line
+			right := m.pop()
+			left := m.pop()
+			m.push(i.binary(operator, left, right))
+		case bytecode.OP_NEGATE, bytecode.OP_NOT:
+			operator := vmOperators[op]
+			operator.Line = line
+			m.push(i.unary(operator, m.pop()))
+
+		case bytecode.OP_PRINT:
+			fmt.Println(i.stringify(m.pop()))
+
+		case bytecode.OP_JUMP:
+			offset := frame.readShort()
+			frame.ip += offset
+		case bytecode.OP_JUMP_IF_FALSE:
+			offset := frame.readShort()
+
+			if !i.isTruthy(m.peek(0)) {
+				frame.ip += offset
+			}
+		case bytecode.OP_LOOP:
+			offset := frame.readShort()
+			frame.ip -= offset
+
+		case bytecode.OP_CALL, bytecode.OP_TAIL_CALL:
+			argCount := frame.readByte()
+			callee := m.peek(argCount)
+			paren := tokens.Token{TokenType: tokens.RIGHT_PAREN, Lexeme: ")", Line: line}
+
+			if closure, ok := callee.(*vmClosure); ok {
+				i.checkArity(closure, m.stack[len(m.stack)-argCount:], paren)
+
+				if op == bytecode.OP_CALL {
+					m.enter(closure, argCount)
+					continue
+				}
+
+				// A tail call reuses the frame of the caller, whose result it is.
+				m.closeUpvalues(frame.base)
+				copy(m.stack[frame.base:], m.stack[len(m.stack)-argCount-1:])
+				m.stack = m.stack[:frame.base+argCount+1]
+				frame.closure = closure
+				frame.ip = 0
+				continue
+			}
+
+			args := append([]any{}, m.stack[len(m.stack)-argCount:]...)
+			m.stack = m.stack[:len(m.stack)-argCount-1]
+			m.push(i.call(callee, args, paren))
+
+		case bytecode.OP_CLOSURE:
+			function := chunk.Constants[frame.readShort()].(*bytecode.Function)
+			closure := &vmClosure{function: function, upvalues: make([]*vmUpvalue, function.UpvalueCount)}
+
+			for index := range closure.upvalues {
+				isLocal := frame.readByte()
+				slot := frame.readByte()
+
+				if isLocal == 1 {
+					closure.upvalues[index] = m.capture(frame.base + slot)
+				} else {
+					closure.upvalues[index] = frame.closure.upvalues[slot]
+				}
+			}
+
+			m.push(closure)
+		case bytecode.OP_CLOSE_UPVALUE:
+			m.closeUpvalues(len(m.stack) - 1)
+			m.pop()
+
+		case bytecode.OP_RETURN:
+			result := m.pop()
+			m.closeUpvalues(frame.base)
+			m.stack = m.stack[:frame.base]
+			m.frames = m.frames[:len(m.frames)-1]
+
+			if len(m.frames) == depth {
+				return result
+			}
+
+			m.push(result)
+
+		default:
+			panic(fmt.Sprintf("Can't run %s in this version.", op))
+		}
+	}
+}
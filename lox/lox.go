@@ -4,12 +4,17 @@ import (
 	"bufio"
 	"fmt"
 	"log"
+	"lox/ast"
+	"lox/bytecode"
 	"lox/interfaces"
 	"lox/interpreter"
+	"lox/optimizer"
 	"lox/parser"
 	"lox/resolver"
 	"lox/scanner"
+	stm "lox/statement"
 	"os"
+	"path/filepath"
 )
 
 type Token struct{}
@@ -22,13 +27,32 @@ type Lox struct {
 	parser          *parser.Parser
 	interpreter     *interpreter.Interpreter
 	resolver        *resolver.Resolver
+	optimizer       *optimizer.Optimizer
+	useCache        bool
 }
 
-func (l *Lox) SetComponents(scanner *scanner.Scanner, parser *parser.Parser, interpreter *interpreter.Interpreter, resolver *resolver.Resolver) {
+func (l *Lox) SetComponents(scanner *scanner.Scanner, parser *parser.Parser, interpreter *interpreter.Interpreter, resolver *resolver.Resolver, optimizer *optimizer.Optimizer) {
 	l.scanner = scanner
 	l.parser = parser
 	l.interpreter = interpreter
 	l.resolver = resolver
+	l.optimizer = optimizer
+
+	interpreter.SetModuleLoader(l)
+}
+
+// UseVirtualClock makes timers fire without waiting for them.
+func (l *Lox) UseVirtualClock() {
+	l.interpreter.UseVirtualClock()
+}
+
+// UseCache makes RunFile run the bytecode "lox compile" left next to a
+// script instead of its source, for as long as the source doesn't change.
+// The bytecode was compiled with every optimization pass and runs on the
+// bytecode VM, so it only stands in for a run with the default backend and
+// passes.
+func (l *Lox) UseCache() {
+	l.useCache = true
 }
 
 func (l *Lox) RunFile(path string) {
@@ -38,7 +62,17 @@ func (l *Lox) RunFile(path string) {
 		log.Fatal(err)
 	}
 
-	l.run(string(file))
+	if abs, err := filepath.Abs(path); err == nil {
+		l.interpreter.SetScriptPath(abs)
+	}
+
+	if script := l.loadCache(path, file); script != nil {
+		l.runCompiled(script)
+	} else {
+		l.run(string(file))
+	}
+
+	l.interpreter.CloseGenerators()
 
 	if l.HadError {
 		os.Exit(65)
@@ -49,6 +83,49 @@ func (l *Lox) RunFile(path string) {
 	}
 }
 
+// loadCache returns the bytecode compiled from source, or nil if the cache
+// isn't used or doesn't hold a valid compilation of it.
+func (l *Lox) loadCache(path string, source []byte) *bytecode.Function {
+	if !l.useCache {
+		return nil
+	}
+
+	script, err := bytecode.LoadCache(bytecode.CachePath(path), source)
+
+	if err != nil {
+		return nil
+	}
+	return script
+}
+
+// Compile writes the bytecode of the script at path next to it, where
+// RunFile picks it up for as long as the script doesn't change.
+func (l *Lox) Compile(path string) error {
+	file, err := os.ReadFile(path)
+
+	if err != nil {
+		return err
+	}
+
+	stmts := l.parse(string(file))
+
+	if !l.HadError {
+		stmts = l.resolve(stmts)
+	}
+
+	if l.HadError {
+		os.Exit(65)
+	}
+
+	script, err := bytecode.Compile(stmts)
+
+	if err != nil {
+		return err
+	}
+
+	return bytecode.WriteFile(bytecode.CachePath(path), script, file)
+}
+
 func (l *Lox) RunPrompt() {
 
 	scanner := bufio.NewScanner(os.Stdin)
@@ -68,27 +145,44 @@ func (l *Lox) RunPrompt() {
 
 	}
 
+	l.interpreter.CloseGenerators()
+
 	if scanner.Err() != nil {
 		log.Fatal(scanner.Err())
 	}
 
 }
 
-func (l *Lox) run(source string) {
+// PrintAst prints the syntax tree of the file at path. With optimized set the
+// tree is resolved and optimized first, exactly as it would be before running.
+func (l *Lox) PrintAst(path string, optimized bool) {
+	file, err := os.ReadFile(path)
 
-	l.scanner.LoadSource(source)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	tokens := l.scanner.ScanTokens()
+	stmts := l.parse(string(file))
 
-	l.parser.LoadTokens(tokens)
+	if !l.HadError && optimized {
+		stmts = l.resolve(stmts)
+	}
 
-	stmts := l.parser.Parse()
+	if l.HadError {
+		os.Exit(65)
+	}
+
+	fmt.Print(ast.NewPrinter().PrintProgram(stmts))
+}
+
+func (l *Lox) run(source string) {
+	stmts := l.parse(source)
 
 	if l.HadError {
 		return
 	}
 
-	l.resolver.ResolveBlock(stmts)
+	stmts = l.resolve(stmts)
 
 	if l.HadError {
 		return
@@ -96,20 +190,57 @@ func (l *Lox) run(source string) {
 
 	l.interpreter.Interpret(stmts)
 
+	if !l.HadRuntimeError {
+		l.interpreter.RunEventLoop()
+	}
 }
 
-func (l *Lox) runRepl(source string) {
+// runCompiled runs a script loaded from its cache file, without scanning,
+// parsing or resolving the source.
+func (l *Lox) runCompiled(script *bytecode.Function) {
+	l.interpreter.InterpretChunk(script)
+
+	if !l.HadRuntimeError {
+		l.interpreter.RunEventLoop()
+	}
+}
+
+func (l *Lox) parse(source string) []stm.Statement {
 	l.scanner.LoadSource(source)
 
 	tokens := l.scanner.ScanTokens()
 
 	l.parser.LoadTokens(tokens)
 
-	stmts := l.parser.Parse()
+	return l.parser.Parse()
+}
+
+func (l *Lox) resolve(stmts []stm.Statement) []stm.Statement {
+	l.resolver.ResolveBlock(stmts)
+
+	if l.HadError || l.optimizer == nil {
+		return stmts
+	}
+
+	return l.optimizer.Optimize(stmts)
+}
+
+func (l *Lox) runRepl(source string) {
+	stmts := l.parse(source)
+
+	if l.HadError {
+		return
+	}
+
+	stmts = l.resolve(stmts)
 
 	if l.HadError {
 		return
 	}
 
 	l.interpreter.InterpretRepl(stmts)
+
+	if !l.HadRuntimeError {
+		l.interpreter.RunEventLoop()
+	}
 }
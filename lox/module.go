@@ -0,0 +1,100 @@
+package lox
+
+import (
+	"fmt"
+	"lox/manifest"
+	"lox/resolver"
+	stm "lox/statement"
+	"os"
+	"path/filepath"
+)
+
+// FindModule implements interpreter.ModuleLoader. A path without an extension
+// gets ".lox". A path starting with the name of a dependency from the nearest
+// lox.mod is looked up in its vendor directory. Other relative paths are
+// looked up next to the importing file, then in each directory of LOX_PATH.
+func (l *Lox) FindModule(path string, importer string) (string, error) {
+	if filepath.Ext(path) == "" {
+		path += ".lox"
+	}
+
+	if filepath.IsAbs(path) {
+		return findFile(path)
+	}
+
+	dirs := []string{"."}
+
+	if importer != "" {
+		dirs[0] = filepath.Dir(importer)
+	}
+
+	pkg, err := manifest.Find(dirs[0])
+
+	if err != nil {
+		return "", err
+	}
+
+	if pkg != nil {
+		vendored, ok, err := pkg.Resolve(path)
+
+		if err != nil {
+			return "", err
+		}
+
+		if ok {
+			if found, err := findFile(vendored); err == nil {
+				return found, nil
+			}
+			return "", fmt.Errorf("Module \"%s\" not found in vendored dependencies.", path)
+		}
+	}
+
+	dirs = append(dirs, filepath.SplitList(os.Getenv("LOX_PATH"))...)
+
+	for _, dir := range dirs {
+		if found, err := findFile(filepath.Join(dir, path)); err == nil {
+			return found, nil
+		}
+	}
+
+	return "", fmt.Errorf("Module \"%s\" not found.", path)
+}
+
+func findFile(path string) (string, error) {
+	info, err := os.Stat(path)
+
+	if err != nil {
+		return "", err
+	}
+
+	if info.IsDir() {
+		return "", fmt.Errorf("\"%s\" is a directory.", path)
+	}
+
+	return filepath.Abs(path)
+}
+
+// LoadModule implements interpreter.ModuleLoader. Every module gets a fresh
+// resolver, since its top-level frame is numbered from zero.
+func (l *Lox) LoadModule(path string) ([]stm.Statement, error) {
+	file, err := os.ReadFile(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	stmts := l.parse(string(file))
+
+	if !l.HadError {
+		enclosing := l.resolver
+		l.resolver = resolver.NewResolver(l.interpreter, l.ErrorLogger)
+		stmts = l.resolve(stmts)
+		l.resolver = enclosing
+	}
+
+	if l.HadError {
+		return nil, fmt.Errorf("Could not load module \"%s\".", path)
+	}
+
+	return stmts, nil
+}
@@ -1,15 +1,84 @@
 package main
 
 import (
+	"flag"
+	"fmt"
+	"lox/bytecode"
 	"lox/errorLogger"
 	"lox/interpreter"
 	"lox/lox"
+	"lox/manifest"
+	"lox/optimizer"
 	"lox/parser"
 	"lox/resolver"
 	"lox/scanner"
+	"os"
+	"time"
 )
 
 func main() {
+	args := os.Args[1:]
+
+	if len(args) > 0 {
+		switch args[0] {
+		case "compile":
+			compile(args[1:])
+			return
+		case "disasm":
+			disasm(args[1:])
+			return
+		case "bench":
+			bench(args[1:])
+			return
+		case "ast":
+			printAst(args[1:])
+			return
+		case "mod":
+			mod(args[1:])
+			return
+		}
+	}
+
+	flags := flag.NewFlagSet("lox", flag.ExitOnError)
+	backend := flags.String("backend", "tree", "execution backend: tree or closure")
+	passes := flags.String("passes", "all", "optimization passes: fold,branches,deadcode,loops, all or none")
+	virtualClock := flags.Bool("virtual-clock", false, "fire timers without waiting for them, for deterministic runs")
+	flags.Parse(args)
+
+	passSet := parsePasses(*passes)
+	lox := newLox(*backend, passSet)
+
+	if *virtualClock {
+		lox.UseVirtualClock()
+	}
+
+	// A script compiled with "lox compile" runs from its .loxc file only
+	// when --backend and --passes are left alone: the file holds bytecode
+	// for the VM, optimized with every pass, so it can't honour either flag.
+	if *backend == "tree" && passSet == optimizer.ALL_PASSES {
+		lox.UseCache()
+	}
+
+	if flags.NArg() == 0 {
+		lox.RunPrompt()
+		return
+	}
+
+	lox.RunFile(flags.Arg(0))
+}
+
+func parsePasses(names string) optimizer.Pass {
+	passes, err := optimizer.ParsePasses(names)
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(64)
+	}
+
+	return passes
+}
+
+func newLox(backend string, passes optimizer.Pass) *lox.Lox {
 	lox := lox.Lox{}
 
 	errorLogger := errorLogger.ErrorLogger{
@@ -23,18 +92,128 @@ func main() {
 	interpreter := interpreter.NewInterpreter(errorLogger)
 	resolver := resolver.NewResolver(interpreter, errorLogger)
 
-	lox.SetComponents(scanner, parses, interpreter, resolver)
+	switch backend {
+	case "tree":
+	case "closure":
+		interpreter.UseCompiler()
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown backend %q.\n", backend)
+		os.Exit(64)
+	}
+
+	lox.SetComponents(scanner, parses, interpreter, resolver, optimizer.NewOptimizer(passes))
+
+	return &lox
+}
+
+func bench(args []string) {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: lox bench <script>")
+		os.Exit(64)
+	}
+
+	for _, backend := range []string{"tree", "closure"} {
+		start := time.Now()
+		newLox(backend, optimizer.ALL_PASSES).RunFile(args[0])
+		fmt.Fprintf(os.Stderr, "%-8s %v\n", backend, time.Since(start))
+	}
+}
+
+func printAst(args []string) {
+	flags := flag.NewFlagSet("ast", flag.ExitOnError)
+	optimized := flags.Bool("optimized", false, "resolve and optimize before printing")
+	passes := flags.String("passes", "all", "optimization passes used with --optimized")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: lox ast [--optimized] [--passes=...] <script>")
+		os.Exit(64)
+	}
+
+	newLox("tree", parsePasses(*passes)).PrintAst(flags.Arg(0), *optimized)
+}
+
+func mod(args []string) {
+	if len(args) < 1 || len(args) > 2 || (args[0] != "vendor" && args[0] != "verify") {
+		fmt.Fprintln(os.Stderr, "Usage: lox mod vendor|verify [dir]")
+		os.Exit(64)
+	}
+
+	dir := "."
+
+	if len(args) == 2 {
+		dir = args[1]
+	}
+
+	pkg, err := manifest.Find(dir)
+
+	if err == nil && pkg == nil {
+		err = fmt.Errorf("no %s found in %s or above", manifest.MANIFEST_FILE, dir)
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "vendor":
+		lock, err := manifest.Vendor(pkg)
+
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
 
-	lox.RunFile("testFiles/inheritance.txt")
+		for _, entry := range lock.Entries {
+			fmt.Printf("vendored %s from %s\n", entry.Name, entry.Source)
+		}
+	case "verify":
+		problems, err := manifest.Verify(pkg)
 
-	// expr := expressions.Binary{
-	// 	Left:     expressions.Binary{Operator: tokens.NewToken(tokens.PLUS, "+", nil, 1), Left: expressions.Literal{Value: 1}, Right: expressions.Literal{Value: 2}},
-	// 	Operator: tokens.NewToken(tokens.STAR, "*", nil, 1),
-	// 	Right:    expressions.Binary{Operator: tokens.NewToken(tokens.MINUS, "-", nil, 1), Left: expressions.Literal{Value: 4}, Right: expressions.Literal{Value: 3}},
-	// }
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
 
-	// printer := ast.NewPrinter()
+		for _, problem := range problems {
+			fmt.Fprintln(os.Stderr, problem)
+		}
 
-	// fmt.Println(printer.Print(expr))
+		if len(problems) > 0 {
+			os.Exit(1)
+		}
+
+		fmt.Println("all dependencies verified")
+	}
+}
+
+func compile(args []string) {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: lox compile <script>")
+		os.Exit(64)
+	}
+
+	if err := newLox("tree", optimizer.ALL_PASSES).Compile(args[0]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(65)
+	}
+
+	fmt.Printf("wrote %s\n", bytecode.CachePath(args[0]))
+}
+
+func disasm(args []string) {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: lox disasm <file.loxc>")
+		os.Exit(64)
+	}
+
+	script, _, err := bytecode.ReadFile(args[0])
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(66)
+	}
 
+	bytecode.Disassemble(os.Stdout, script)
 }
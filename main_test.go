@@ -0,0 +1,294 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"lox/bytecode"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const (
+	EXPECT_OUTPUT        = "// expect:"
+	EXPECT_RUNTIME_ERROR = "// expect runtime error:"
+)
+
+// TestMain lets the samples run the test binary as lox itself, so exit codes
+// and output are those of the real command.
+func TestMain(m *testing.M) {
+	if os.Getenv("LOX_TEST_MAIN") != "" {
+		main()
+		os.Exit(0)
+	}
+
+	os.Exit(m.Run())
+}
+
+// sample is what a file in testFiles says it prints to stdout: one line per
+// "// expect: " or "// expect runtime error: " comment, in order. A runtime
+// error message that runs over several lines continues with "// expect: "
+// comments. A sample with a runtime error exits with 70.
+type sample struct {
+	output []string
+	code   int
+}
+
+func readSample(t *testing.T, path string) (sample, bool) {
+	file, err := os.Open(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var expected sample
+	annotated := false
+	scanner := bufio.NewScanner(file)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if index := strings.Index(line, EXPECT_OUTPUT); index >= 0 {
+			expected.output = append(expected.output, expectation(line[index+len(EXPECT_OUTPUT):]))
+			annotated = true
+		} else if index := strings.Index(line, EXPECT_RUNTIME_ERROR); index >= 0 {
+			expected.output = append(expected.output, expectation(line[index+len(EXPECT_RUNTIME_ERROR):]))
+			expected.code = 70
+			annotated = true
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	return expected, annotated
+}
+
+// expectation drops the space that separates an expected line from its
+// comment, which an empty line doesn't have.
+func expectation(text string) string {
+	return strings.TrimPrefix(text, " ")
+}
+
+// runLox runs the lox command with args and returns what it printed to
+// stdout and stderr, and its exit code.
+func runLox(t *testing.T, args ...string) (string, int) {
+	t.Helper()
+
+	stdout, stderr, code := runLoxOutputs(t, args...)
+
+	return stdout + stderr, code
+}
+
+func runLoxOutputs(t *testing.T, args ...string) (string, string, int) {
+	t.Helper()
+
+	command := exec.Command(os.Args[0], args...)
+	command.Env = append(os.Environ(), "LOX_TEST_MAIN=1")
+
+	var stdout, stderr bytes.Buffer
+	command.Stdout = &stdout
+	command.Stderr = &stderr
+
+	err := command.Run()
+
+	var exit *exec.ExitError
+
+	if errors.As(err, &exit) {
+		return stdout.String(), stderr.String(), exit.ExitCode()
+	}
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return stdout.String(), stderr.String(), 0
+}
+
+func TestSamples(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("testFiles", "*.txt"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range paths {
+		expected, ok := readSample(t, path)
+
+		if !ok {
+			continue
+		}
+
+		for _, backend := range []string{"tree", "closure"} {
+			path, backend := path, backend
+
+			t.Run(filepath.Base(path)+"/"+backend, func(t *testing.T) {
+				t.Parallel()
+
+				// Warnings go to stderr and aren't compared.
+				output, stderr, code := runLoxOutputs(t, "--virtual-clock", "--backend="+backend, path)
+				lines := strings.Split(strings.TrimSuffix(output, "\n"), "\n")
+				want := expected.output
+
+				if output == "" {
+					lines = nil
+				}
+
+				if code != expected.code {
+					t.Errorf("exit code %d, want %d\n%s%s", code, expected.code, output, stderr)
+				}
+
+				for index := 0; index < len(want) || index < len(lines); index++ {
+					switch {
+					case index >= len(lines):
+						t.Fatalf("line %d: missing, want %q", index+1, want[index])
+					case index >= len(want):
+						t.Fatalf("line %d: got unexpected %q", index+1, lines[index])
+					case lines[index] != want[index]:
+						t.Fatalf("line %d: got %q, want %q", index+1, lines[index], want[index])
+					}
+				}
+			})
+		}
+	}
+}
+
+func writeFile(t *testing.T, path, text string) {
+	t.Helper()
+
+	if err := os.WriteFile(path, []byte(text), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBytecodeCache(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "script.lox")
+	other := filepath.Join(dir, "other.lox")
+	writeFile(t, script, "print \"from source\";\n")
+	writeFile(t, other, "print \"from cache\";\n")
+
+	if output, code := runLox(t, "compile", other); code != 0 {
+		t.Fatalf("compile failed: %s", output)
+	}
+
+	// Give script a cache that prints something else, so the output tells
+	// which of the two ran.
+	compiled, _, err := bytecode.ReadFile(bytecode.CachePath(other))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	source, err := os.ReadFile(script)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := bytecode.WriteFile(bytecode.CachePath(script), compiled, source); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{script}, "from cache\n"},
+		{[]string{"--backend=tree", "--passes=all", script}, "from cache\n"},
+		{[]string{"--backend=closure", script}, "from source\n"},
+		{[]string{"--passes=none", script}, "from source\n"},
+		{[]string{"--passes=fold", script}, "from source\n"},
+	}
+
+	for _, test := range tests {
+		if output, _ := runLox(t, test.args...); output != test.want {
+			t.Errorf("lox %s printed %q, want %q", strings.Join(test.args, " "), output, test.want)
+		}
+	}
+
+	writeFile(t, script, "print \"edited\";\n")
+
+	if output, _ := runLox(t, script); output != "edited\n" {
+		t.Errorf("after an edit printed %q, want %q", output, "edited\n")
+	}
+}
+
+func TestCompiledSampleMatchesSource(t *testing.T) {
+	source, err := os.ReadFile(filepath.Join("testFiles", "bytecode.txt"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	script := filepath.Join(t.TempDir(), "bytecode.txt")
+	writeFile(t, script, string(source))
+	fromSource, _ := runLox(t, script)
+
+	if output, code := runLox(t, "compile", script); code != 0 {
+		t.Fatalf("compile failed: %s", output)
+	}
+
+	if _, err := os.Stat(bytecode.CachePath(script)); err != nil {
+		t.Fatal(err)
+	}
+
+	if fromCache, _ := runLox(t, script); fromCache != fromSource {
+		t.Errorf("from the cache printed\n%s\nbut from source\n%s", fromCache, fromSource)
+	}
+}
+
+// TestTailCallsDontNestFrames runs recursion far deeper than the VM's
+// MAX_FRAMES from bytecode. The tree and closure backends run the same
+// recursion in testFiles/tailcall.txt.
+func TestTailCallsDontNestFrames(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+		code   int
+	}{
+		{"tail call", `fun count(n, acc) {
+  if (n == 0) return acc;
+  return count(n - 1, acc + 1);
+}
+print count(200000, 0);
+`, "200000\n", 0},
+		{"mutual tail calls", `fun isEven(n) {
+  if (n == 0) return true;
+  return isOdd(n - 1);
+}
+fun isOdd(n) {
+  if (n == 0) return false;
+  return isEven(n - 1);
+}
+print isEven(200001);
+`, "false\n", 0},
+		{"call that isn't a tail call", `fun deep(n) {
+  if (n == 0) return 0;
+  return 1 + deep(n - 1);
+}
+print deep(200000);
+`, "Stack overflow.\n", 70},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			script := filepath.Join(t.TempDir(), "script.lox")
+			writeFile(t, script, test.source)
+
+			if output, code := runLox(t, "compile", script); code != 0 {
+				t.Fatalf("compile failed: %s", output)
+			}
+
+			if output, code := runLox(t, script); output != test.want || code != test.code {
+				t.Errorf("exit %d, printed %q, want exit %d and %q", code, output, test.code, test.want)
+			}
+		})
+	}
+}
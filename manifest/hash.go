@@ -0,0 +1,56 @@
+package manifest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+const HASH_PREFIX = "h1:"
+
+// HashDir hashes every file under dir by its path relative to dir and its
+// contents, so a dependency hashes the same whether it came from a directory
+// or an archive.
+func HashDir(dir string) (string, error) {
+	files := make([]string, 0)
+
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if entry.Type().IsRegular() {
+			rel, err := filepath.Rel(dir, path)
+
+			if err != nil {
+				return err
+			}
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	sort.Strings(files)
+
+	summary := sha256.New()
+
+	for _, file := range files {
+		data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(file)))
+
+		if err != nil {
+			return "", err
+		}
+
+		fmt.Fprintf(summary, "%x  %s\n", sha256.Sum256(data), file)
+	}
+
+	return HASH_PREFIX + hex.EncodeToString(summary.Sum(nil)), nil
+}
@@ -0,0 +1,88 @@
+package manifest
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// LockEntry records what was vendored for one dependency: where it came from
+// and the hash of the vendored files.
+type LockEntry struct {
+	Name   string
+	Source string
+	Hash   string
+}
+
+// Lock is a parsed lox.sum. It lists every vendored dependency, including the
+// dependencies of dependencies, one "<name> <source> <hash>" per line. A source
+// containing spaces is written as a quoted string.
+type Lock struct {
+	Entries []LockEntry
+}
+
+func ParseLock(data []byte) (*Lock, error) {
+	lock := &Lock{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	line := 0
+
+	for scanner.Scan() {
+		line++
+		fields, err := splitFields(scanner.Text())
+
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", LOCK_FILE, line, err)
+		}
+
+		if len(fields) == 0 {
+			continue
+		}
+
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("%s:%d: expected \"<name> <source> <hash>\"", LOCK_FILE, line)
+		}
+
+		lock.Entries = append(lock.Entries, LockEntry{Name: fields[0], Source: fields[1], Hash: fields[2]})
+	}
+
+	return lock, scanner.Err()
+}
+
+func ReadLock(dir string) (*Lock, error) {
+	data, err := os.ReadFile(filepath.Join(dir, LOCK_FILE))
+
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseLock(data)
+}
+
+func (l *Lock) Entry(name string) *LockEntry {
+	for index := range l.Entries {
+		if l.Entries[index].Name == name {
+			return &l.Entries[index]
+		}
+	}
+	return nil
+}
+
+func (l *Lock) Format() []byte {
+	var buffer bytes.Buffer
+
+	entries := append([]LockEntry{}, l.Entries...)
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+
+	for _, entry := range entries {
+		fmt.Fprintf(&buffer, "%s %s %s\n", entry.Name, quoteField(entry.Source), entry.Hash)
+	}
+
+	return buffer.Bytes()
+}
+
+func (l *Lock) Write(dir string) error {
+	return os.WriteFile(filepath.Join(dir, LOCK_FILE), l.Format(), 0644)
+}
@@ -0,0 +1,88 @@
+package manifest
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLockRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+	}{
+		{"plain", "../strings"},
+		{"archive", "deps/vectors.tar.gz"},
+		{"space", "../my libs/strings"},
+		{"tab", "deps/a\tb"},
+		{"quote", `"quoted"/lib`},
+		{"inner quote", `lib "two"`},
+		{"backslash", `C:\libs\strings`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			lock := &Lock{Entries: []LockEntry{
+				{Name: "strings", Source: test.source, Hash: "sha256:0123abcd"},
+				{Name: "vectors", Source: "vectors", Hash: "sha256:4567ef01"},
+			}}
+
+			parsed, err := ParseLock(lock.Format())
+
+			if err != nil {
+				t.Fatalf("ParseLock(%q): %v", lock.Format(), err)
+			}
+
+			if !reflect.DeepEqual(parsed.Entries, lock.Entries) {
+				t.Errorf("round trip of %q gave %+v, want %+v", lock.Format(), parsed.Entries, lock.Entries)
+			}
+		})
+	}
+}
+
+func TestLockWriteAndRead(t *testing.T) {
+	dir := t.TempDir()
+	lock := &Lock{Entries: []LockEntry{{Name: "strings", Source: "../my libs/strings", Hash: "sha256:0123abcd"}}}
+
+	if err := lock.Write(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	read, err := ReadLock(dir)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(read.Entries, lock.Entries) {
+		t.Errorf("read %+v, want %+v", read.Entries, lock.Entries)
+	}
+}
+
+func TestParseLockErrors(t *testing.T) {
+	tests := []string{
+		"strings ../strings\n",
+		"strings ../strings sha256:00 extra\n",
+		"strings \"../my libs sha256:00\n",
+		"strings \"../lib\"x sha256:00\n",
+	}
+
+	for _, data := range tests {
+		if _, err := ParseLock([]byte(data)); err == nil {
+			t.Errorf("ParseLock(%q) succeeded", data)
+		}
+	}
+}
+
+func TestParseQuotedRequire(t *testing.T) {
+	manifest, err := Parse(".", []byte("package app\nrequire strings \"../my libs/strings\" // vendored\n"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Requirement{{Name: "strings", Source: "../my libs/strings"}}
+
+	if !reflect.DeepEqual(manifest.Requires, want) {
+		t.Errorf("got %+v, want %+v", manifest.Requires, want)
+	}
+}
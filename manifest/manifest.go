@@ -0,0 +1,226 @@
+package manifest
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	MANIFEST_FILE = "lox.mod"
+	LOCK_FILE     = "lox.sum"
+	VENDOR_DIR    = "vendor"
+)
+
+var ErrNotVendored = errors.New("dependency is not vendored")
+
+// Requirement is one "require <name> <source>" line. Source is a directory or
+// a .tar, .tar.gz or .tgz archive, relative to the manifest's directory. A
+// source containing spaces is written as a quoted string.
+type Requirement struct {
+	Name   string
+	Source string
+}
+
+// Manifest is a parsed lox.mod:
+//
+//	package geometry
+//	require strings ../strings
+//	require vectors deps/vectors.tar.gz
+type Manifest struct {
+	Dir      string
+	Package  string
+	Requires []Requirement
+}
+
+func Parse(dir string, data []byte) (*Manifest, error) {
+	manifest := &Manifest{Dir: dir}
+	names := make(map[string]bool)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	line := 0
+
+	for scanner.Scan() {
+		line++
+		text, _, _ := strings.Cut(scanner.Text(), "//")
+		fields, err := splitFields(text)
+
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", MANIFEST_FILE, line, err)
+		}
+
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch {
+		case fields[0] == "package" && len(fields) == 2:
+			if manifest.Package != "" {
+				return nil, fmt.Errorf("%s:%d: package declared twice", MANIFEST_FILE, line)
+			}
+			manifest.Package = fields[1]
+		case fields[0] == "require" && len(fields) == 3:
+			if !validName(fields[1]) {
+				return nil, fmt.Errorf("%s:%d: invalid dependency name %q", MANIFEST_FILE, line, fields[1])
+			}
+
+			if names[fields[1]] {
+				return nil, fmt.Errorf("%s:%d: dependency %q required twice", MANIFEST_FILE, line, fields[1])
+			}
+
+			names[fields[1]] = true
+			manifest.Requires = append(manifest.Requires, Requirement{Name: fields[1], Source: fields[2]})
+		default:
+			return nil, fmt.Errorf("%s:%d: expected \"package <name>\" or \"require <name> <source>\"", MANIFEST_FILE, line)
+		}
+	}
+
+	if manifest.Package == "" {
+		return nil, fmt.Errorf("%s: missing package declaration", MANIFEST_FILE)
+	}
+
+	return manifest, scanner.Err()
+}
+
+// splitFields splits a line of lox.mod or lox.sum at spaces. A field that
+// starts with a double quote is a Go string literal and can contain spaces.
+func splitFields(text string) ([]string, error) {
+	fields := make([]string, 0)
+
+	for {
+		text = strings.TrimLeftFunc(text, unicode.IsSpace)
+
+		if text == "" {
+			return fields, nil
+		}
+
+		if text[0] != '"' {
+			end := strings.IndexFunc(text, unicode.IsSpace)
+
+			if end < 0 {
+				end = len(text)
+			}
+
+			fields = append(fields, text[:end])
+			text = text[end:]
+			continue
+		}
+
+		quoted, err := strconv.QuotedPrefix(text)
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid quoted string %s", text)
+		}
+
+		field, _ := strconv.Unquote(quoted)
+		fields = append(fields, field)
+		text = text[len(quoted):]
+
+		if text != "" && !unicode.IsSpace(rune(text[0])) {
+			return nil, fmt.Errorf("expected a space after %s", quoted)
+		}
+	}
+}
+
+// quoteField quotes field if splitFields wouldn't read it back as one field.
+func quoteField(field string) string {
+	if field == "" || strings.HasPrefix(field, `"`) || strings.ContainsFunc(field, unicode.IsSpace) {
+		return strconv.Quote(field)
+	}
+	return field
+}
+
+func validName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.ContainsAny(name, `/\`)
+}
+
+func Read(dir string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, MANIFEST_FILE))
+
+	if err != nil {
+		return nil, err
+	}
+
+	return Parse(dir, data)
+}
+
+// Find reads the lox.mod in dir or the nearest directory above it. It returns
+// nil when there is none.
+func Find(dir string) (*Manifest, error) {
+	dir, err := filepath.Abs(dir)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		manifest, err := Read(dir)
+
+		if err == nil {
+			return manifest, nil
+		}
+
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+
+		parent := filepath.Dir(dir)
+
+		if parent == dir {
+			return nil, nil
+		}
+		dir = parent
+	}
+}
+
+func (m *Manifest) Require(name string) (Requirement, bool) {
+	for _, requirement := range m.Requires {
+		if requirement.Name == name {
+			return requirement, true
+		}
+	}
+	return Requirement{}, false
+}
+
+func (m *Manifest) VendorDir() string {
+	return filepath.Join(m.Dir, VENDOR_DIR)
+}
+
+// Resolve maps an import path whose first element names a dependency, such as
+// "strings/format.lox", to the file in the vendor directory. Dependencies of
+// dependencies are vendored next to the direct ones, so the lock file is
+// consulted as well as the manifest. It reports false for other paths.
+func (m *Manifest) Resolve(path string) (string, bool, error) {
+	name, rest, ok := strings.Cut(filepath.ToSlash(path), "/")
+
+	if !ok {
+		return "", false, nil
+	}
+
+	_, required := m.Require(name)
+
+	if !required {
+		lock, err := ReadLock(m.Dir)
+
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return "", false, err
+		}
+
+		if lock == nil || lock.Entry(name) == nil {
+			return "", false, nil
+		}
+	}
+
+	dir := filepath.Join(m.VendorDir(), name)
+
+	if _, err := os.Stat(dir); err != nil {
+		return "", true, fmt.Errorf("%w: %q, run \"lox mod vendor\"", ErrNotVendored, name)
+	}
+
+	return filepath.Join(dir, filepath.FromSlash(rest)), true, nil
+}
@@ -0,0 +1,330 @@
+package manifest
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+type pending struct {
+	requirement Requirement
+	dir         string
+}
+
+// Vendor copies every dependency, and every dependency of a dependency, into
+// the vendor directory and records their hashes in lox.sum. The vendor
+// directory is only replaced once all of them were copied.
+func Vendor(m *Manifest) (*Lock, error) {
+	staging, err := os.MkdirTemp(m.Dir, ".vendor-")
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer os.RemoveAll(staging)
+
+	lock := &Lock{}
+	sources := make(map[string]string)
+	queue := make([]pending, 0)
+
+	for _, requirement := range m.Requires {
+		queue = append(queue, pending{requirement: requirement, dir: m.Dir})
+	}
+
+	for len(queue) > 0 {
+		next := queue[0]
+		queue = queue[1:]
+
+		name := next.requirement.Name
+		source := next.requirement.Source
+
+		if !filepath.IsAbs(source) {
+			source = filepath.Join(next.dir, source)
+		}
+
+		if previous, ok := sources[name]; ok {
+			if previous != source {
+				return nil, fmt.Errorf("dependency %q is required from both %s and %s", name, previous, source)
+			}
+			continue
+		}
+
+		sources[name] = source
+		target := filepath.Join(staging, name)
+		sourceDir, err := fetch(source, target)
+
+		if err != nil {
+			return nil, fmt.Errorf("vendoring %q: %w", name, err)
+		}
+
+		dependency, err := Read(target)
+
+		if err == nil {
+			for _, requirement := range dependency.Requires {
+				queue = append(queue, pending{requirement: requirement, dir: sourceDir})
+			}
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("vendoring %q: %w", name, err)
+		}
+
+		os.Remove(filepath.Join(target, MANIFEST_FILE))
+
+		hash, err := HashDir(target)
+
+		if err != nil {
+			return nil, err
+		}
+
+		lock.Entries = append(lock.Entries, LockEntry{Name: name, Source: m.lockSource(source), Hash: hash})
+	}
+
+	if err := os.RemoveAll(m.VendorDir()); err != nil {
+		return nil, err
+	}
+
+	if err := os.Rename(staging, m.VendorDir()); err != nil {
+		return nil, err
+	}
+
+	return lock, lock.Write(m.Dir)
+}
+
+// Verify checks the vendor directory against lox.sum, and lox.sum against
+// lox.mod. It returns one line per problem found.
+func Verify(m *Manifest) ([]string, error) {
+	lock, err := ReadLock(m.Dir)
+
+	if errors.Is(err, os.ErrNotExist) {
+		return []string{LOCK_FILE + " is missing, run \"lox mod vendor\""}, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	problems := make([]string, 0)
+
+	for _, requirement := range m.Requires {
+		entry := lock.Entry(requirement.Name)
+		source := requirement.Source
+
+		if !filepath.IsAbs(source) {
+			source = filepath.Join(m.Dir, source)
+		}
+
+		if entry == nil {
+			problems = append(problems, fmt.Sprintf("%s: required in %s but missing from %s", requirement.Name, MANIFEST_FILE, LOCK_FILE))
+		} else if entry.Source != m.lockSource(source) {
+			problems = append(problems, fmt.Sprintf("%s: %s has source %s but %s has %s", requirement.Name, MANIFEST_FILE, requirement.Source, LOCK_FILE, entry.Source))
+		}
+	}
+
+	for _, entry := range lock.Entries {
+		hash, err := HashDir(filepath.Join(m.VendorDir(), entry.Name))
+
+		if errors.Is(err, os.ErrNotExist) {
+			problems = append(problems, fmt.Sprintf("%s: not vendored", entry.Name))
+		} else if err != nil {
+			return nil, err
+		} else if hash != entry.Hash {
+			problems = append(problems, fmt.Sprintf("%s: vendored files do not match %s", entry.Name, LOCK_FILE))
+		}
+	}
+
+	vendored, err := os.ReadDir(m.VendorDir())
+
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	for _, dir := range vendored {
+		if lock.Entry(dir.Name()) == nil {
+			problems = append(problems, fmt.Sprintf("%s: vendored but missing from %s", dir.Name(), LOCK_FILE))
+		}
+	}
+
+	sort.Strings(problems)
+
+	return problems, nil
+}
+
+// lockSource is how lox.sum records source: relative to the manifest when it
+// can be, so the lock file does not depend on where the package is checked out.
+func (m *Manifest) lockSource(source string) string {
+	if rel, err := filepath.Rel(m.Dir, source); err == nil {
+		return filepath.ToSlash(rel)
+	}
+	return filepath.ToSlash(source)
+}
+
+// fetch copies a dependency to target and returns the directory its own
+// requirements are relative to.
+func fetch(source string, target string) (string, error) {
+	info, err := os.Stat(source)
+
+	if err != nil {
+		return "", err
+	}
+
+	if info.IsDir() {
+		return source, copyDir(source, target)
+	}
+
+	if isArchive(source) {
+		return filepath.Dir(source), extract(source, target)
+	}
+
+	return "", fmt.Errorf("%s is neither a directory nor a .tar, .tar.gz or .tgz archive", source)
+}
+
+func isArchive(path string) bool {
+	for _, suffix := range []string{".tar", ".tar.gz", ".tgz"} {
+		if strings.HasSuffix(path, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// copyDir copies the files of a dependency, leaving out its own lock file
+// and vendor directory.
+func copyDir(source string, target string) error {
+	return filepath.WalkDir(source, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(source, path)
+
+		if err != nil {
+			return err
+		}
+
+		if rel == VENDOR_DIR || rel == LOCK_FILE || strings.HasPrefix(entry.Name(), ".") && rel != "." {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		destination := filepath.Join(target, rel)
+
+		if entry.IsDir() {
+			return os.MkdirAll(destination, 0755)
+		}
+
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+
+		if err != nil {
+			return err
+		}
+
+		return os.WriteFile(destination, data, 0644)
+	})
+}
+
+// extract unpacks an archive into target. Archives that hold a single
+// top-level directory, as "tar czf strings.tgz strings" makes, are unpacked
+// from inside it.
+func extract(source string, target string) error {
+	unpacked, err := os.MkdirTemp(filepath.Dir(target), ".unpack-")
+
+	if err != nil {
+		return err
+	}
+
+	defer os.RemoveAll(unpacked)
+
+	if err := untar(source, unpacked); err != nil {
+		return err
+	}
+
+	root := unpacked
+	entries, err := os.ReadDir(unpacked)
+
+	if err != nil {
+		return err
+	}
+
+	if len(entries) == 1 && entries[0].IsDir() {
+		root = filepath.Join(unpacked, entries[0].Name())
+	}
+
+	return copyDir(root, target)
+}
+
+func untar(source string, target string) error {
+	file, err := os.Open(source)
+
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	var reader io.Reader = file
+
+	if !strings.HasSuffix(source, ".tar") {
+		compressed, err := gzip.NewReader(file)
+
+		if err != nil {
+			return err
+		}
+
+		defer compressed.Close()
+		reader = compressed
+	}
+
+	archive := tar.NewReader(reader)
+
+	for {
+		header, err := archive.Next()
+
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		name := filepath.Clean(filepath.FromSlash(header.Name))
+
+		if filepath.IsAbs(name) || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("archive entry %q is outside the archive", header.Name)
+		}
+
+		destination := filepath.Join(target, name)
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(destination, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(destination), 0755); err != nil {
+				return err
+			}
+
+			data, err := io.ReadAll(archive)
+
+			if err != nil {
+				return err
+			}
+
+			if err := os.WriteFile(destination, data, 0644); err != nil {
+				return err
+			}
+		}
+	}
+}
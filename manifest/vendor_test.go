@@ -0,0 +1,225 @@
+package manifest
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeFiles creates files, keyed by slash-separated paths, under dir.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+
+	for name, text := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path, []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// writeArchive packs files into a .tar.gz under a single top-level directory,
+// as "tar czf" would.
+func writeArchive(t *testing.T, path, top string, files map[string]string) {
+	t.Helper()
+
+	file, err := os.Create(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	zipped := gzip.NewWriter(file)
+	archive := tar.NewWriter(zipped)
+
+	for name, text := range files {
+		header := &tar.Header{Name: top + "/" + name, Mode: 0644, Size: int64(len(text)), Typeflag: tar.TypeReg}
+
+		if err := archive.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := archive.Write([]byte(text)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := zipped.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func hashFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	writeFiles(t, dir, files)
+	hash, err := HashDir(dir)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return hash
+}
+
+func TestHashDir(t *testing.T) {
+	base := map[string]string{"format.lox": "export fun pad() {}", "lib/util.lox": "var x = 1;"}
+	hash := hashFiles(t, base)
+
+	if !strings.HasPrefix(hash, HASH_PREFIX) {
+		t.Errorf("hash %s doesn't start with %s", hash, HASH_PREFIX)
+	}
+
+	if again := hashFiles(t, base); again != hash {
+		t.Errorf("the same files in another directory hash to %s, not %s", again, hash)
+	}
+
+	tests := []struct {
+		name  string
+		files map[string]string
+	}{
+		{"edited file", map[string]string{"format.lox": "export fun pad() { }", "lib/util.lox": "var x = 1;"}},
+		{"renamed file", map[string]string{"format2.lox": "export fun pad() {}", "lib/util.lox": "var x = 1;"}},
+		{"moved file", map[string]string{"format.lox": "export fun pad() {}", "util.lox": "var x = 1;"}},
+		{"added file", map[string]string{"format.lox": "export fun pad() {}", "lib/util.lox": "var x = 1;", "extra.lox": ""}},
+		{"removed file", map[string]string{"format.lox": "export fun pad() {}"}},
+		{"swapped contents", map[string]string{"format.lox": "var x = 1;", "lib/util.lox": "export fun pad() {}"}},
+	}
+
+	for _, test := range tests {
+		if got := hashFiles(t, test.files); got == hash {
+			t.Errorf("%s: hash didn't change", test.name)
+		}
+	}
+}
+
+// vendoredPackage makes a package requiring "strings" from a directory and
+// "vectors" from an archive, and vendors them.
+func vendoredPackage(t *testing.T) *Manifest {
+	t.Helper()
+
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"app/lox.mod":             "package app\nrequire strings ../libs/strings\nrequire vectors ../libs/vectors.tar.gz\n",
+		"app/main.lox":            "import \"strings/format.lox\";",
+		"libs/strings/lox.mod":    "package strings\n",
+		"libs/strings/format.lox": "export fun pad() {}",
+	})
+	writeArchive(t, filepath.Join(root, "libs", "vectors.tar.gz"), "vectors", map[string]string{
+		"vector.lox": "export class Vector {}",
+	})
+
+	m, err := Read(filepath.Join(root, "app"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Vendor(m); err != nil {
+		t.Fatal(err)
+	}
+
+	return m
+}
+
+func TestVendorHashesMatchSources(t *testing.T) {
+	m := vendoredPackage(t)
+	lock, err := ReadLock(m.Dir)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"strings": hashFiles(t, map[string]string{"format.lox": "export fun pad() {}"}),
+		"vectors": hashFiles(t, map[string]string{"vector.lox": "export class Vector {}"}),
+	}
+
+	got := make(map[string]string)
+
+	for _, entry := range lock.Entries {
+		got[entry.Name] = entry.Hash
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("%s records %v, want %v", LOCK_FILE, got, want)
+	}
+}
+
+func TestVerifyDetectsTampering(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper func(t *testing.T, m *Manifest)
+		want   string
+	}{
+		{"nothing", func(t *testing.T, m *Manifest) {}, ""},
+		{"edited file", func(t *testing.T, m *Manifest) {
+			writeFiles(t, m.VendorDir(), map[string]string{"strings/format.lox": "export fun pad() { evil(); }"})
+		}, "strings: vendored files do not match " + LOCK_FILE},
+		{"added file", func(t *testing.T, m *Manifest) {
+			writeFiles(t, m.VendorDir(), map[string]string{"vectors/extra.lox": ""})
+		}, "vectors: vendored files do not match " + LOCK_FILE},
+		{"removed file", func(t *testing.T, m *Manifest) {
+			os.Remove(filepath.Join(m.VendorDir(), "vectors", "vector.lox"))
+		}, "vectors: vendored files do not match " + LOCK_FILE},
+		{"removed dependency", func(t *testing.T, m *Manifest) {
+			os.RemoveAll(filepath.Join(m.VendorDir(), "strings"))
+		}, "strings: not vendored"},
+		{"unlocked dependency", func(t *testing.T, m *Manifest) {
+			writeFiles(t, m.VendorDir(), map[string]string{"extra/extra.lox": ""})
+		}, "extra: vendored but missing from " + LOCK_FILE},
+		{"edited hash", func(t *testing.T, m *Manifest) {
+			lock, _ := ReadLock(m.Dir)
+			lock.Entry("strings").Hash = HASH_PREFIX + "00"
+			lock.Write(m.Dir)
+		}, "strings: vendored files do not match " + LOCK_FILE},
+		{"changed source", func(t *testing.T, m *Manifest) {
+			m.Requires[0].Source = "../elsewhere/strings"
+		}, "strings: " + MANIFEST_FILE + " has source ../elsewhere/strings but " + LOCK_FILE + " has ../libs/strings"},
+		{"new requirement", func(t *testing.T, m *Manifest) {
+			m.Requires = append(m.Requires, Requirement{Name: "colors", Source: "../libs/colors"})
+		}, "colors: required in " + MANIFEST_FILE + " but missing from " + LOCK_FILE},
+		{"missing lock", func(t *testing.T, m *Manifest) {
+			os.Remove(filepath.Join(m.Dir, LOCK_FILE))
+		}, LOCK_FILE + " is missing, run \"lox mod vendor\""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m := vendoredPackage(t)
+			test.tamper(t, m)
+
+			problems, err := Verify(m)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if test.want == "" {
+				if len(problems) != 0 {
+					t.Errorf("unexpected problems %q", problems)
+				}
+				return
+			}
+
+			if len(problems) != 1 || problems[0] != test.want {
+				t.Errorf("got %q, want %q", problems, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,632 @@
+package optimizer
+
+import (
+	"fmt"
+	stm "lox/statement"
+	"lox/tokens"
+	"reflect"
+	"strings"
+)
+
+type Pass int
+
+const (
+	FOLD_CONSTANTS Pass = 1 << iota
+	ELIMINATE_BRANCHES
+	ELIMINATE_DEAD_CODE
+	SIMPLIFY_LOOPS
+
+	NO_PASSES  Pass = 0
+	ALL_PASSES      = FOLD_CONSTANTS | ELIMINATE_BRANCHES | ELIMINATE_DEAD_CODE | SIMPLIFY_LOOPS
+)
+
+var passNames = map[string]Pass{
+	"fold":     FOLD_CONSTANTS,
+	"branches": ELIMINATE_BRANCHES,
+	"deadcode": ELIMINATE_DEAD_CODE,
+	"loops":    SIMPLIFY_LOOPS,
+}
+
+// ParsePasses reads a comma separated list of pass names such as
+// "fold,deadcode". "all" enables every pass and "none" disables them.
+func ParsePasses(names string) (Pass, error) {
+	passes := NO_PASSES
+
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+
+		switch name {
+		case "", "none":
+			continue
+		case "all":
+			passes |= ALL_PASSES
+			continue
+		}
+
+		pass, ok := passNames[name]
+
+		if !ok {
+			return NO_PASSES, fmt.Errorf("unknown optimization pass %q", name)
+		}
+
+		passes |= pass
+	}
+
+	return passes, nil
+}
+
+// Optimizer rewrites resolved statements before they are interpreted. It only
+// removes or replaces nodes, so the slots the resolver assigned stay valid.
+type Optimizer struct {
+	passes Pass
+}
+
+func NewOptimizer(passes Pass) *Optimizer {
+	return &Optimizer{
+		passes: passes,
+	}
+}
+
+func (o *Optimizer) Enabled(pass Pass) bool {
+	return o.passes&pass != 0
+}
+
+func (o *Optimizer) Optimize(statements []stm.Statement) []stm.Statement {
+	if o.passes == NO_PASSES {
+		return statements
+	}
+	return o.statements(statements)
+}
+
+func (o *Optimizer) statements(statements []stm.Statement) []stm.Statement {
+	optimized := make([]stm.Statement, 0, len(statements))
+
+	for _, statement := range statements {
+		statement = o.stmt(statement)
+
+		if statement == nil {
+			continue
+		}
+
+		optimized = append(optimized, statement)
+
+		if o.Enabled(ELIMINATE_DEAD_CODE) && isJump(statement) {
+			break
+		}
+	}
+
+	return optimized
+}
+
+func (o *Optimizer) stmt(statement stm.Statement) stm.Statement {
+	optimized := statement.Accept(o)
+
+	if optimized == nil {
+		return nil
+	}
+	return optimized.(stm.Statement)
+}
+
+func (o *Optimizer) expr(expression stm.Expression) stm.Expression {
+	return expression.Accept(o).(stm.Expression)
+}
+
+func (o *Optimizer) function(function *stm.FunctionStm) {
+	o.defaults(function.Parameters)
+	function.Body = o.statements(function.Body)
+}
+
+func (o *Optimizer) defaults(parameters stm.Parameters) {
+	for index, value := range parameters.Defaults {
+		if value != nil {
+			parameters.Defaults[index] = o.expr(value)
+		}
+	}
+}
+
+func isJump(statement stm.Statement) bool {
+	switch statement.(type) {
+	case *stm.ReturnStmt, *stm.BreakStmt:
+		return true
+	}
+	return false
+}
+
+func literal(expression stm.Expression) (any, bool) {
+	if lit, ok := expression.(*stm.Literal); ok {
+		return lit.Value, true
+	}
+	return nil, false
+}
+
+// sameLiteral is == on the values a literal can hold, as the interpreter
+// compares them: by value, and never equal across types.
+func sameLiteral(left, right any) bool {
+	if left == nil || right == nil {
+		return left == nil && right == nil
+	}
+
+	kind := reflect.TypeOf(left)
+
+	return kind == reflect.TypeOf(right) && kind.Comparable() && left == right
+}
+
+func isTruthy(value any) bool {
+	if value == nil {
+		return false
+	}
+
+	if boolValue, ok := value.(bool); ok {
+		return boolValue
+	}
+	return true
+}
+
+// VisitAnonymousFuncExpr implements stm.ExprVisitor.
+func (o *Optimizer) VisitAnonymousFuncExpr(expr *stm.AnonymousFunction) any {
+	o.defaults(expr.Parameters)
+	expr.Body = o.statements(expr.Body)
+	return expr
+}
+
+// VisitAssignExpr implements stm.ExprVisitor.
+func (o *Optimizer) VisitAssignExpr(expr *stm.Assign) any {
+	expr.Value = o.expr(expr.Value)
+	return expr
+}
+
+// VisitAssignPatternExpr implements stm.ExprVisitor.
+func (o *Optimizer) VisitAssignPatternExpr(expr *stm.AssignPattern) any {
+	expr.Value = o.expr(expr.Value)
+	return expr
+}
+
+// VisitBinaryExpr implements stm.ExprVisitor.
+func (o *Optimizer) VisitBinaryExpr(expr *stm.Binary) any {
+	expr.Left = o.expr(expr.Left)
+	expr.Right = o.expr(expr.Right)
+
+	if !o.Enabled(FOLD_CONSTANTS) {
+		return expr
+	}
+
+	left, okLeft := literal(expr.Left)
+	right, okRight := literal(expr.Right)
+
+	if !okLeft || !okRight {
+		return expr
+	}
+
+	switch expr.Operator.TokenType {
+	case tokens.EQUAL_EQUAL:
+		return stm.NewLiteral(sameLiteral(left, right))
+	case tokens.BANG_EQUAL:
+		return stm.NewLiteral(!sameLiteral(left, right))
+	}
+
+	if a, ok := left.(string); ok {
+		if b, ok := right.(string); ok && expr.Operator.TokenType == tokens.PLUS {
+			return stm.NewLiteral(a + b)
+		}
+		return expr
+	}
+
+	a, okA := left.(float64)
+	b, okB := right.(float64)
+
+	if !okA || !okB {
+		return expr
+	}
+
+	switch expr.Operator.TokenType {
+	case tokens.PLUS:
+		return stm.NewLiteral(a + b)
+	case tokens.MINUS:
+		return stm.NewLiteral(a - b)
+	case tokens.STAR:
+		return stm.NewLiteral(a * b)
+	case tokens.SLASH:
+		return stm.NewLiteral(a / b)
+	case tokens.GREATER:
+		return stm.NewLiteral(a > b)
+	case tokens.GREATER_EQUAL:
+		return stm.NewLiteral(a >= b)
+	case tokens.LESS:
+		return stm.NewLiteral(a < b)
+	case tokens.LESS_EQUAL:
+		return stm.NewLiteral(a <= b)
+	}
+
+	return expr
+}
+
+// VisitCallExpr implements stm.ExprVisitor.
+func (o *Optimizer) VisitCallExpr(expr *stm.Call) any {
+	expr.Callee = o.expr(expr.Callee)
+
+	for i, arg := range expr.Arguments {
+		expr.Arguments[i] = o.expr(arg)
+	}
+
+	return expr
+}
+
+// VisitErrorExpr implements stm.ExprVisitor.
+func (o *Optimizer) VisitErrorExpr(expr *stm.Error) any {
+	return expr
+}
+
+// VisitListExpr implements stm.ExprVisitor.
+func (o *Optimizer) VisitListExpr(expr *stm.List) any {
+	for index, element := range expr.Elements {
+		expr.Elements[index] = o.expr(element)
+	}
+	return expr
+}
+
+// VisitIndexExpr implements stm.ExprVisitor.
+func (o *Optimizer) VisitIndexExpr(expr *stm.Index) any {
+	expr.Object = o.expr(expr.Object)
+	expr.Index = o.expr(expr.Index)
+	return expr
+}
+
+// VisitMatchExpr implements stm.ExprVisitor.
+func (o *Optimizer) VisitMatchExpr(expr *stm.Match) any {
+	expr.Subject = o.expr(expr.Subject)
+
+	for _, matchCase := range expr.Cases {
+		if matchCase.Guard != nil {
+			matchCase.Guard = o.expr(matchCase.Guard)
+		}
+
+		matchCase.Value = o.expr(matchCase.Value)
+	}
+
+	return expr
+}
+
+// VisitAwaitExpr implements stm.ExprVisitor.
+func (o *Optimizer) VisitAwaitExpr(expr *stm.Await) any {
+	expr.Value = o.expr(expr.Value)
+	return expr
+}
+
+// VisitGetExpr implements stm.ExprVisitor.
+func (o *Optimizer) VisitGetExpr(expr *stm.Get) any {
+	expr.Object = o.expr(expr.Object)
+	return expr
+}
+
+// VisitGroupingExpr implements stm.ExprVisitor.
+func (o *Optimizer) VisitGroupingExpr(expr *stm.Grouping) any {
+	expr.Expression = o.expr(expr.Expression)
+
+	if _, ok := literal(expr.Expression); ok && o.Enabled(FOLD_CONSTANTS) {
+		return expr.Expression
+	}
+
+	return expr
+}
+
+// VisitLiteralExpr implements stm.ExprVisitor.
+func (o *Optimizer) VisitLiteralExpr(expr *stm.Literal) any {
+	return expr
+}
+
+// VisitLogicalExpr implements stm.ExprVisitor.
+func (o *Optimizer) VisitLogicalExpr(expr *stm.Logical) any {
+	expr.Left = o.expr(expr.Left)
+	expr.Right = o.expr(expr.Right)
+
+	left, ok := literal(expr.Left)
+
+	if !ok || !o.Enabled(FOLD_CONSTANTS) {
+		return expr
+	}
+
+	if expr.Operator.TokenType == tokens.OR {
+		if isTruthy(left) {
+			return expr.Left
+		}
+		return expr.Right
+	}
+
+	if !isTruthy(left) {
+		return expr.Left
+	}
+	return expr.Right
+}
+
+// VisitSetExpr implements stm.ExprVisitor.
+func (o *Optimizer) VisitSetExpr(expr *stm.Set) any {
+	expr.Object = o.expr(expr.Object)
+	expr.Value = o.expr(expr.Value)
+	return expr
+}
+
+// VisitSuperExpr implements stm.ExprVisitor.
+func (o *Optimizer) VisitSuperExpr(expr *stm.Super) any {
+	return expr
+}
+
+// VisitTernaryExpr implements stm.ExprVisitor.
+func (o *Optimizer) VisitTernaryExpr(expr *stm.Ternary) any {
+	expr.Condition = o.expr(expr.Condition)
+	expr.Consequent = o.expr(expr.Consequent)
+	expr.Alternative = o.expr(expr.Alternative)
+
+	condition, ok := literal(expr.Condition)
+
+	if !ok || !o.Enabled(FOLD_CONSTANTS) {
+		return expr
+	}
+
+	// The ternary only accepts booleans, anything else must still fail at runtime.
+	if value, ok := condition.(bool); ok {
+		if value {
+			return expr.Consequent
+		}
+		return expr.Alternative
+	}
+
+	return expr
+}
+
+// VisitThisExpr implements stm.ExprVisitor.
+func (o *Optimizer) VisitThisExpr(expr *stm.This) any {
+	return expr
+}
+
+// VisitUnaryExpr implements stm.ExprVisitor.
+func (o *Optimizer) VisitUnaryExpr(expr *stm.Unary) any {
+	expr.Right = o.expr(expr.Right)
+
+	right, ok := literal(expr.Right)
+
+	if !ok || !o.Enabled(FOLD_CONSTANTS) {
+		return expr
+	}
+
+	switch expr.Operator.TokenType {
+	case tokens.BANG:
+		return stm.NewLiteral(!isTruthy(right))
+	case tokens.MINUS:
+		if number, ok := right.(float64); ok {
+			return stm.NewLiteral(-number)
+		}
+	}
+
+	return expr
+}
+
+// VisitVariableExpr implements stm.ExprVisitor.
+func (o *Optimizer) VisitVariableExpr(expr *stm.Variable) any {
+	return expr
+}
+
+// VisitBlockStatement implements stm.StmVisitor.
+func (o *Optimizer) VisitBlockStatement(stmt *stm.BlockStmt) any {
+	stmt.Statements = o.statements(stmt.Statements)
+	return stmt
+}
+
+// VisitBreakStatement implements stm.StmVisitor.
+func (o *Optimizer) VisitBreakStatement(stmt *stm.BreakStmt) any {
+	return stmt
+}
+
+// VisitClassStatement implements stm.StmVisitor.
+func (o *Optimizer) VisitClassStatement(stmt *stm.ClassStmt) any {
+	for _, method := range stmt.Methods {
+		o.function(method)
+	}
+
+	for _, method := range stmt.StaticMethods {
+		o.function(method)
+	}
+
+	for _, accessor := range stmt.Accessors() {
+		o.function(accessor)
+	}
+
+	for _, field := range append(stmt.Fields, stmt.StaticFields...) {
+		if field.Initializer != nil {
+			field.Initializer = o.expr(field.Initializer)
+		}
+	}
+
+	return stmt
+}
+
+// VisitTraitStatement implements stm.StmVisitor.
+func (o *Optimizer) VisitTraitStatement(stmt *stm.TraitStmt) any {
+	for _, method := range stmt.Methods {
+		o.function(method)
+	}
+
+	return stmt
+}
+
+// VisitInterfaceStatement implements stm.StmVisitor.
+func (o *Optimizer) VisitInterfaceStatement(stmt *stm.InterfaceStmt) any {
+	return stmt
+}
+
+// VisitEnumStatement implements stm.StmVisitor.
+func (o *Optimizer) VisitEnumStatement(stmt *stm.EnumStmt) any {
+	return stmt
+}
+
+// VisitImportStatement implements stm.StmVisitor.
+func (o *Optimizer) VisitImportStatement(stmt *stm.ImportStmt) any {
+	return stmt
+}
+
+// VisitExportStatement implements stm.StmVisitor.
+func (o *Optimizer) VisitExportStatement(stmt *stm.ExportStmt) any {
+	stmt.Declaration = o.stmt(stmt.Declaration)
+	return stmt
+}
+
+// VisitErrorStatement implements stm.StmVisitor.
+func (o *Optimizer) VisitErrorStatement(stmt *stm.ErrorStmt) any {
+	return stmt
+}
+
+// VisitExprStatement implements stm.StmVisitor.
+func (o *Optimizer) VisitExprStatement(stmt *stm.ExpressionStmt) any {
+	stmt.Expression = o.expr(stmt.Expression)
+	return stmt
+}
+
+// VisitFunctionStatement implements stm.StmVisitor.
+func (o *Optimizer) VisitFunctionStatement(stmt *stm.FunctionStm) any {
+	o.function(stmt)
+	return stmt
+}
+
+// VisitIfStatement implements stm.StmVisitor.
+func (o *Optimizer) VisitIfStatement(stmt *stm.IfStmt) any {
+	stmt.Condition = o.expr(stmt.Condition)
+	stmt.ThenBranch = o.stmt(stmt.ThenBranch)
+
+	if stmt.ElseBranch != nil {
+		stmt.ElseBranch = o.stmt(stmt.ElseBranch)
+	}
+
+	if stmt.ThenBranch == nil {
+		stmt.ThenBranch = stm.NewBlock([]stm.Statement{})
+	}
+
+	condition, ok := literal(stmt.Condition)
+
+	if !ok || !o.Enabled(ELIMINATE_BRANCHES) {
+		return stmt
+	}
+
+	if isTruthy(condition) {
+		return stmt.ThenBranch
+	}
+
+	if stmt.ElseBranch == nil {
+		return nil
+	}
+	return stmt.ElseBranch
+}
+
+// VisitPrintStatement implements stm.StmVisitor.
+func (o *Optimizer) VisitPrintStatement(stmt *stm.PrintStmt) any {
+	stmt.Expression = o.expr(stmt.Expression)
+	return stmt
+}
+
+// VisitReturnStatement implements stm.StmVisitor.
+func (o *Optimizer) VisitReturnStatement(stmt *stm.ReturnStmt) any {
+	if stmt.Value != nil {
+		stmt.Value = o.expr(stmt.Value)
+	}
+	return stmt
+}
+
+// VisitVarStatement implements stm.StmVisitor.
+func (o *Optimizer) VisitVarStatement(stmt *stm.VarStmt) any {
+	if stmt.Initializer != nil {
+		stmt.Initializer = o.expr(stmt.Initializer)
+	}
+	return stmt
+}
+
+// VisitVarPatternStatement implements stm.StmVisitor.
+func (o *Optimizer) VisitVarPatternStatement(stmt *stm.VarPatternStmt) any {
+	stmt.Initializer = o.expr(stmt.Initializer)
+	return stmt
+}
+
+// VisitForInStatement implements stm.StmVisitor.
+func (o *Optimizer) VisitForInStatement(stmt *stm.ForInStmt) any {
+	stmt.Iterable = o.expr(stmt.Iterable)
+	stmt.Body = o.stmt(stmt.Body)
+
+	if stmt.Body == nil {
+		stmt.Body = stm.NewBlock([]stm.Statement{})
+	}
+
+	return stmt
+}
+
+// VisitYieldStatement implements stm.StmVisitor.
+func (o *Optimizer) VisitYieldStatement(stmt *stm.YieldStmt) any {
+	stmt.Value = o.expr(stmt.Value)
+	return stmt
+}
+
+// VisitSpawnStatement implements stm.StmVisitor.
+func (o *Optimizer) VisitSpawnStatement(stmt *stm.SpawnStmt) any {
+	o.VisitCallExpr(stmt.Call)
+	return stmt
+}
+
+// VisitSelectStatement implements stm.StmVisitor.
+func (o *Optimizer) VisitSelectStatement(stmt *stm.SelectStmt) any {
+	for _, selectCase := range stmt.Cases {
+		selectCase.Channel = o.expr(selectCase.Channel)
+
+		if selectCase.Value != nil {
+			selectCase.Value = o.expr(selectCase.Value)
+		}
+
+		selectCase.Body = o.stmt(selectCase.Body)
+
+		if selectCase.Body == nil {
+			selectCase.Body = stm.NewBlock([]stm.Statement{})
+		}
+	}
+
+	if stmt.Default != nil {
+		stmt.Default = o.stmt(stmt.Default)
+
+		if stmt.Default == nil {
+			stmt.Default = stm.NewBlock([]stm.Statement{})
+		}
+	}
+
+	return stmt
+}
+
+// VisitMatchStatement implements stm.StmVisitor.
+func (o *Optimizer) VisitMatchStatement(stmt *stm.MatchStmt) any {
+	stmt.Subject = o.expr(stmt.Subject)
+
+	for _, matchCase := range stmt.Cases {
+		if matchCase.Guard != nil {
+			matchCase.Guard = o.expr(matchCase.Guard)
+		}
+
+		matchCase.Body = o.stmt(matchCase.Body)
+
+		if matchCase.Body == nil {
+			matchCase.Body = stm.NewBlock([]stm.Statement{})
+		}
+	}
+
+	return stmt
+}
+
+// VisitWhileStatement implements stm.StmVisitor.
+func (o *Optimizer) VisitWhileStatement(stmt *stm.WhileStmt) any {
+	stmt.Condition = o.expr(stmt.Condition)
+	stmt.Body = o.stmt(stmt.Body)
+
+	if stmt.Body == nil {
+		stmt.Body = stm.NewBlock([]stm.Statement{})
+	}
+
+	condition, ok := literal(stmt.Condition)
+
+	if ok && !isTruthy(condition) && o.Enabled(SIMPLIFY_LOOPS) {
+		return nil
+	}
+
+	return stmt
+}
@@ -0,0 +1,125 @@
+package optimizer_test
+
+import (
+	"lox/ast"
+	"lox/optimizer"
+	"lox/parser"
+	"lox/scanner"
+	"lox/tokens"
+	"strings"
+	"testing"
+)
+
+// failingLogger fails the test on any error in the source under test.
+type failingLogger struct {
+	t *testing.T
+}
+
+func (l failingLogger) Error(line int, message string) {
+	l.t.Errorf("[line %d] %s", line, message)
+}
+
+func (l failingLogger) Report(line int, where string, message string) {
+	l.t.Errorf("[line %d] %s: %s", line, where, message)
+}
+
+func (l failingLogger) ErrorForToken(token tokens.Token, message string) error {
+	l.t.Errorf("[line %d] at %q: %s", token.Line, token.Lexeme, message)
+	return nil
+}
+
+func (l failingLogger) RuntimeError(message string) {
+	l.t.Error(message)
+}
+
+func (l failingLogger) Warning(token tokens.Token, message string) {}
+
+func optimize(t *testing.T, source string, passes optimizer.Pass) string {
+	t.Helper()
+
+	logger := failingLogger{t}
+	scan := scanner.NewScanner(logger)
+	scan.LoadSource(source)
+
+	parse := parser.NewParser(logger)
+	parse.LoadTokens(scan.ScanTokens())
+
+	statements := optimizer.NewOptimizer(passes).Optimize(parse.Parse())
+
+	return strings.TrimSuffix(ast.NewPrinter().PrintProgram(statements), "\n")
+}
+
+func TestPasses(t *testing.T) {
+	tests := []struct {
+		name   string
+		passes optimizer.Pass
+		source string
+		want   string
+	}{
+		{"arithmetic", optimizer.FOLD_CONSTANTS, "print 1 + 2 * 3;", "(print 7)"},
+		{"strings", optimizer.FOLD_CONSTANTS, `print "a" + "b";`, `(print "ab")`},
+		{"comparison", optimizer.FOLD_CONSTANTS, "print -(4 - 1) > 2 and !nil;", "(print false)"},
+		{"equal numbers", optimizer.FOLD_CONSTANTS, "print 1 == 1;", "(print true)"},
+		{"equal across types", optimizer.FOLD_CONSTANTS, `print 1 == "1";`, "(print false)"},
+		{"nil equals nil", optimizer.FOLD_CONSTANTS, "print nil != nil;", "(print false)"},
+		{"ternary", optimizer.FOLD_CONSTANTS, `print true ? "t" : "f";`, `(print "t")`},
+		{"logical", optimizer.FOLD_CONSTANTS, "print nil or 2;", "(print 2)"},
+		{"mixed operands left alone", optimizer.FOLD_CONSTANTS, `print 1 + "a";`, `(print (+ 1 "a"))`},
+		{"variables left alone", optimizer.FOLD_CONSTANTS, "var a = 1; print a + 2;", "(var a 1)\n(print (+ a 2))"},
+		{"folding off", optimizer.NO_PASSES, "print 1 + 2;", "(print (+ 1 2))"},
+
+		{"false branch", optimizer.ELIMINATE_BRANCHES,
+			`if (false) { print "never"; } else { print "else"; }`,
+			"(block\n  (print \"else\"))"},
+		{"true branch", optimizer.ELIMINATE_BRANCHES, `if (true) print "yes";`, `(print "yes")`},
+		{"branches need literal conditions", optimizer.ELIMINATE_BRANCHES,
+			"if (1 < 2) print 1;", "(if (< 1 2) (print 1))"},
+		{"folded branch condition", optimizer.FOLD_CONSTANTS | optimizer.ELIMINATE_BRANCHES,
+			"if (1 < 2) print 1;", "(print 1)"},
+
+		{"after return", optimizer.ELIMINATE_DEAD_CODE,
+			`fun f(x) { return x; print "dead"; }`,
+			"(fun f (x)\n  (return x))"},
+		{"after break", optimizer.ELIMINATE_DEAD_CODE,
+			"while (true) { break; print 1; }",
+			"(while true (block\n  (break)))"},
+
+		{"while false", optimizer.SIMPLIFY_LOOPS, `while (false) { print "loop"; }`, ""},
+		{"for with false condition", optimizer.SIMPLIFY_LOOPS,
+			"for (var i = 0; false; i = i + 1) print i;",
+			"(block\n  (var i 0))"},
+		{"loops off", optimizer.NO_PASSES, "while (false) print 1;", "(while false (print 1))"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := optimize(t, test.source, test.passes); got != test.want {
+				t.Errorf("got\n%s\nwant\n%s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParsePasses(t *testing.T) {
+	tests := []struct {
+		names string
+		want  optimizer.Pass
+	}{
+		{"all", optimizer.ALL_PASSES},
+		{"none", optimizer.NO_PASSES},
+		{"fold", optimizer.FOLD_CONSTANTS},
+		{"fold,loops", optimizer.FOLD_CONSTANTS | optimizer.SIMPLIFY_LOOPS},
+		{"branches,deadcode", optimizer.ELIMINATE_BRANCHES | optimizer.ELIMINATE_DEAD_CODE},
+		{"fold,branches,deadcode,loops", optimizer.ALL_PASSES},
+	}
+
+	for _, test := range tests {
+		if got, err := optimizer.ParsePasses(test.names); err != nil || got != test.want {
+			t.Errorf("ParsePasses(%q) = %v, %v, want %v", test.names, got, err, test.want)
+		}
+	}
+
+	if _, err := optimizer.ParsePasses("fold,unknown"); err == nil {
+		t.Error("ParsePasses accepted an unknown pass")
+	}
+}
@@ -18,6 +18,12 @@ type Parser struct {
 	errorLogger  interfaces.ErrorLogger
 	current      int
 	errorHandler chan ErrorHandlerEvent
+	// inGuard is set while parsing a match guard, which ends in "=>" and so
+	// can't itself be an arrow lambda outside of call arguments.
+	inGuard bool
+	// closers maps the index of every '(' to the index of its ')', so
+	// arrowAhead can look past a parameter list without scanning it.
+	closers map[int]int
 }
 
 func NewParser(errorLogger interfaces.ErrorLogger) *Parser {
@@ -35,6 +41,26 @@ func NewParser(errorLogger interfaces.ErrorLogger) *Parser {
 func (p *Parser) LoadTokens(tokens []tokens.Token) {
 	p.tokens = tokens
 	p.current = 0
+	p.closers = matchParentheses(tokens)
+}
+
+func matchParentheses(list []tokens.Token) map[int]int {
+	closers := make(map[int]int)
+	open := make([]int, 0)
+
+	for index, token := range list {
+		switch token.TokenType {
+		case tokens.LEFT_PAREN:
+			open = append(open, index)
+		case tokens.RIGHT_PAREN:
+			if len(open) > 0 {
+				closers[open[len(open)-1]] = index
+				open = open[:len(open)-1]
+			}
+		}
+	}
+
+	return closers
 }
 
 func (p *Parser) handleError() {
@@ -70,10 +96,146 @@ func (p *Parser) declaration() stm.Statement {
 		return p.varDeclaration()
 	}
 
+	if p.match(tokens.CONST) {
+		return p.constDeclaration()
+	}
+
+	if p.match(tokens.LET) {
+		return p.letDeclaration()
+	}
+
+	if p.match(tokens.IMPORT) {
+		return p.importStatement()
+	}
+
+	if p.match(tokens.FROM) {
+		return p.fromImportStatement()
+	}
+
+	if p.match(tokens.EXPORT) {
+		return p.exportDeclaration()
+	}
+
+	if p.match(tokens.TRAIT) {
+		return p.traitDeclaration()
+	}
+
+	if p.match(tokens.INTERFACE) {
+		return p.interfaceDeclaration()
+	}
+
+	if p.match(tokens.ENUM) {
+		return p.enumDeclaration()
+	}
+
+	return p.statement()
+}
+
+func (p *Parser) importStatement() stm.Statement {
+	keyword := p.previous()
+	path, err := p.consume(tokens.STRING, "Expect module path after 'import'.")
+
+	if err != nil {
+		return stm.NewError("Expect module path.")
+	}
+
+	p.consume(tokens.AS, "Expect 'as' after module path.")
+	alias, err := p.consume(tokens.IDENTIFIER, "Expect module name after 'as'.")
+
+	if err != nil {
+		return stm.NewError("Expect module name.")
+	}
+
+	p.consume(tokens.SEMICOLON, "Expect ';' after import.")
+
+	return stm.NewImport(keyword, path.Literal.(string), alias, nil)
+}
+
+func (p *Parser) fromImportStatement() stm.Statement {
+	keyword := p.previous()
+	path, err := p.consume(tokens.STRING, "Expect module path after 'from'.")
+
+	if err != nil {
+		return stm.NewError("Expect module path.")
+	}
+
+	p.consume(tokens.IMPORT, "Expect 'import' after module path.")
+
+	names := make([]tokens.Token, 0)
+
+	for {
+		name, err := p.consume(tokens.IDENTIFIER, "Expect name to import.")
+
+		if err != nil {
+			return stm.NewError("Expect name to import.")
+		}
+
+		names = append(names, *name)
+
+		if !p.match(tokens.COMMA) {
+			break
+		}
+	}
+
+	p.consume(tokens.SEMICOLON, "Expect ';' after import.")
+
+	return stm.NewImport(keyword, path.Literal.(string), nil, names)
+}
+
+func (p *Parser) exportDeclaration() stm.Statement {
+	keyword := p.previous()
+
+	if p.match(tokens.VAR) {
+		return stm.NewExport(keyword, p.varDeclaration())
+	}
+
+	if p.match(tokens.CONST) {
+		return stm.NewExport(keyword, p.constDeclaration())
+	}
+
+	if p.match(tokens.LET) {
+		return stm.NewExport(keyword, p.letDeclaration())
+	}
+
+	if p.match(tokens.FUN) {
+		return stm.NewExport(keyword, p.functionStatement("function"))
+	}
+
+	if p.match(tokens.ASYNC) {
+		return stm.NewExport(keyword, p.asyncFunction())
+	}
+
+	if p.match(tokens.CLASS) {
+		return stm.NewExport(keyword, p.classStatement())
+	}
+
+	if p.match(tokens.TRAIT) {
+		return stm.NewExport(keyword, p.traitDeclaration())
+	}
+
+	if p.match(tokens.INTERFACE) {
+		return stm.NewExport(keyword, p.interfaceDeclaration())
+	}
+
+	if p.match(tokens.ENUM) {
+		return stm.NewExport(keyword, p.enumDeclaration())
+	}
+
+	p.errorLogger.ErrorForToken(keyword, "Expect declaration after 'export'.")
+
 	return p.statement()
 }
 
 func (p *Parser) varDeclaration() stm.Statement {
+	if p.check(tokens.LEFT_BRACKET) || p.check(tokens.LEFT_BRACE) {
+		pattern := p.destructuringPattern()
+		p.consume(tokens.EQUAL, "Expect '=' after destructuring pattern.")
+		initializer := p.expression()
+		p.consume(tokens.SEMICOLON, "Expect ';' after variable declaration.")
+
+		return stm.NewVarPattern(pattern, initializer)
+	}
+
 	name, err := p.consume(tokens.IDENTIFIER, "Expect variable name.")
 
 	if err != nil {
@@ -91,6 +253,41 @@ func (p *Parser) varDeclaration() stm.Statement {
 
 }
 
+// constDeclaration parses "const name = value;", which must have a value.
+func (p *Parser) constDeclaration() stm.Statement {
+	keyword := p.previous()
+	declaration := p.varDeclaration()
+
+	switch declaration := declaration.(type) {
+	case *stm.VarStmt:
+		if declaration.Initializer == nil {
+			p.errorLogger.ErrorForToken(declaration.Name, "Expect '=' after constant name.")
+		}
+		declaration.Const = true
+	case *stm.VarPatternStmt:
+		declaration.Const = true
+	default:
+		p.errorLogger.ErrorForToken(keyword, "Expect constant name.")
+	}
+
+	return declaration
+}
+
+// letDeclaration parses "let name = value;", a variable that can be assigned
+// to but not declared again.
+func (p *Parser) letDeclaration() stm.Statement {
+	declaration := p.varDeclaration()
+
+	switch declaration := declaration.(type) {
+	case *stm.VarStmt:
+		declaration.Let = true
+	case *stm.VarPatternStmt:
+		declaration.Let = true
+	}
+
+	return declaration
+}
+
 func (p *Parser) statement() stm.Statement {
 	if p.match(tokens.FOR) {
 		return p.forStatement()
@@ -119,160 +316,831 @@ func (p *Parser) statement() stm.Statement {
 		return p.functionStatement("function")
 	}
 
+	if p.match(tokens.ASYNC) {
+		return p.asyncFunction()
+	}
+
 	if p.match(tokens.CLASS) {
 		return p.classStatement()
 	}
 
-	if p.match(tokens.RETURN) {
-		return p.returnStatement()
+	if p.match(tokens.RETURN) {
+		return p.returnStatement()
+	}
+
+	if p.match(tokens.YIELD) {
+		return p.yieldStatement()
+	}
+
+	if p.match(tokens.SPAWN) {
+		return p.spawnStatement()
+	}
+
+	if p.match(tokens.SELECT) {
+		return p.selectStatement()
+	}
+
+	if p.match(tokens.MATCH) {
+		keyword := p.previous()
+		subject, cases := p.matchCases(true)
+		return stm.NewMatchStmt(keyword, subject, cases)
+	}
+
+	return p.expressionStatement()
+}
+
+func (p *Parser) forStatement() stm.Statement {
+	keyword := p.previous()
+	p.consume(tokens.LEFT_PAREN, "Expect '(' after 'for'.")
+
+	if p.checkForIn() {
+		return p.forInStatement(keyword)
+	}
+
+	var initializer stm.Statement
+	var condition stm.Expression = nil
+	var increment stm.Expression = nil
+
+	if p.match(tokens.SEMICOLON) {
+		initializer = nil
+	} else if p.match(tokens.VAR) {
+		initializer = p.varDeclaration()
+	} else {
+		initializer = p.expressionStatement()
+	}
+
+	if !p.check(tokens.SEMICOLON) {
+		condition = p.expression()
+	}
+
+	p.consume(tokens.SEMICOLON, "Expect ';' after loop condition.")
+
+	if !p.check(tokens.RIGHT_PAREN) {
+		increment = p.expression()
+	}
+
+	p.consume(tokens.RIGHT_PAREN, "Expect ')' after for clauses.")
+
+	body := p.statement()
+
+	if increment != nil {
+		body = stm.NewBlock([]stm.Statement{body, stm.NewExpression(increment)})
+	}
+
+	if condition == nil {
+		condition = stm.NewLiteral(true)
+	}
+
+	body = stm.NewWhile(condition, body)
+
+	if initializer != nil {
+		body = stm.NewBlock([]stm.Statement{initializer, body})
+	}
+
+	return body
+
+}
+
+// checkForIn reports whether the loop header is "name in" or "name, name".
+func (p *Parser) checkForIn() bool {
+	if !p.check(tokens.IDENTIFIER) || p.current+1 >= len(p.tokens) {
+		return false
+	}
+
+	next := p.tokens[p.current+1].TokenType
+
+	return next == tokens.IN || next == tokens.COMMA
+}
+
+func (p *Parser) forInStatement(keyword tokens.Token) stm.Statement {
+	names := make([]tokens.Token, 0)
+
+	for {
+		name, err := p.consume(tokens.IDENTIFIER, "Expect loop variable name.")
+
+		if err != nil {
+			return stm.NewError("Expect loop variable name.")
+		}
+
+		names = append(names, *name)
+
+		if !p.match(tokens.COMMA) {
+			break
+		}
+	}
+
+	p.consume(tokens.IN, "Expect 'in' after loop variables.")
+	iterable := p.expression()
+	p.consume(tokens.RIGHT_PAREN, "Expect ')' after for clauses.")
+
+	return stm.NewForIn(keyword, names, iterable, p.statement())
+}
+
+func (p *Parser) classStatement() stm.Statement {
+	name, err := p.consume(tokens.IDENTIFIER, "Expect class name.")
+	var superClass *stm.Variable = nil
+
+	if err != nil {
+		return stm.NewError("Expect class name.")
+	}
+
+	if p.match(tokens.LESS) {
+		p.consume(tokens.IDENTIFIER, "Expect superclass name.")
+		superClass = stm.NewVariable(p.previous())
+	}
+
+	traits := make([]*stm.Variable, 0)
+
+	if p.match(tokens.WITH) {
+		for {
+			p.consume(tokens.IDENTIFIER, "Expect trait name.")
+			traits = append(traits, stm.NewVariable(p.previous()))
+
+			if !p.match(tokens.COMMA) {
+				break
+			}
+		}
+	}
+
+	interfaces := make([]*stm.Variable, 0)
+
+	if p.match(tokens.IMPLEMENTS) {
+		for {
+			p.consume(tokens.IDENTIFIER, "Expect interface name.")
+			interfaces = append(interfaces, stm.NewVariable(p.previous()))
+
+			if !p.match(tokens.COMMA) {
+				break
+			}
+		}
+	}
+
+	p.consume(tokens.LEFT_BRACE, "Expect '{' before class body.")
+
+	methods := make([]*stm.FunctionStm, 0)
+	staticMethods := make([]*stm.FunctionStm, 0)
+	class := stm.NewClass(*name, methods, staticMethods, superClass)
+	class.Traits = traits
+	class.Interfaces = interfaces
+
+	for {
+		if p.check(tokens.RIGHT_BRACE) || p.isAtEnd() {
+			break
+		}
+
+		if p.match(tokens.VAR) {
+			field, ok := p.varDeclaration().(*stm.VarStmt)
+
+			if !ok {
+				return &stm.ErrorStmt{}
+			}
+
+			class.Fields = append(class.Fields, field)
+			continue
+		}
+
+		if p.match(tokens.ABSTRACT) {
+			method := p.methodSignature("abstract method")
+
+			if method == nil {
+				return &stm.ErrorStmt{}
+			}
+
+			class.Abstract = append(class.Abstract, method)
+			continue
+		}
+
+		if p.match(tokens.CLASS) {
+			if p.match(tokens.VAR) {
+				field, ok := p.varDeclaration().(*stm.VarStmt)
+
+				if !ok {
+					return &stm.ErrorStmt{}
+				}
+
+				class.StaticFields = append(class.StaticFields, field)
+				continue
+			}
+
+			if p.checkAccessor() {
+				accessor, setter := p.accessor()
+
+				if accessor == nil {
+					return &stm.ErrorStmt{}
+				}
+
+				if setter {
+					class.StaticSetters = append(class.StaticSetters, accessor)
+				} else {
+					class.StaticGetters = append(class.StaticGetters, accessor)
+				}
+				continue
+			}
+
+			async := p.match(tokens.ASYNC)
+			function := p.functionStatement("static method")
+			s, ok := function.(*stm.FunctionStm)
+
+			if !ok {
+				return &stm.ErrorStmt{}
+			}
+
+			s.Async = async
+
+			staticMethods = append(staticMethods, s)
+
+		} else if p.checkAccessor() {
+			accessor, setter := p.accessor()
+
+			if accessor == nil {
+				return &stm.ErrorStmt{}
+			}
+
+			if setter {
+				class.Setters = append(class.Setters, accessor)
+			} else {
+				class.Getters = append(class.Getters, accessor)
+			}
+		} else {
+			async := p.match(tokens.ASYNC)
+			function := p.functionStatement("method")
+			s, ok := function.(*stm.FunctionStm)
+
+			if !ok {
+				return &stm.ErrorStmt{}
+			}
+
+			s.Async = async
+
+			methods = append(methods, s)
+		}
+	}
+
+	p.consume(tokens.RIGHT_BRACE, "Expect '}' after class body.")
+
+	class.Methods = methods
+	class.StaticMethods = staticMethods
+
+	return class
+
+}
+
+func (p *Parser) traitDeclaration() stm.Statement {
+	name, err := p.consume(tokens.IDENTIFIER, "Expect trait name.")
+
+	if err != nil {
+		return stm.NewError("Expect trait name.")
+	}
+
+	p.consume(tokens.LEFT_BRACE, "Expect '{' before trait body.")
+
+	methods := make([]*stm.FunctionStm, 0)
+
+	for !p.check(tokens.RIGHT_BRACE) && !p.isAtEnd() {
+		if p.check(tokens.VAR) || p.check(tokens.CLASS) || p.checkAccessor() {
+			p.errorLogger.ErrorForToken(p.peek(), "Traits can only declare methods.")
+			return stm.NewError("Traits can only declare methods.")
+		}
+
+		method, ok := p.functionStatement("method").(*stm.FunctionStm)
+
+		if !ok {
+			return &stm.ErrorStmt{}
+		}
+
+		methods = append(methods, method)
+	}
+
+	p.consume(tokens.RIGHT_BRACE, "Expect '}' after trait body.")
+
+	return stm.NewTrait(*name, methods)
+}
+
+func (p *Parser) interfaceDeclaration() stm.Statement {
+	name, err := p.consume(tokens.IDENTIFIER, "Expect interface name.")
+
+	if err != nil {
+		return stm.NewError("Expect interface name.")
+	}
+
+	p.consume(tokens.LEFT_BRACE, "Expect '{' before interface body.")
+
+	methods := make([]*stm.FunctionStm, 0)
+
+	for !p.check(tokens.RIGHT_BRACE) && !p.isAtEnd() {
+		method := p.methodSignature("method")
+
+		if method == nil {
+			return &stm.ErrorStmt{}
+		}
+
+		methods = append(methods, method)
+	}
+
+	p.consume(tokens.RIGHT_BRACE, "Expect '}' after interface body.")
+
+	return stm.NewInterface(*name, methods)
+}
+
+func (p *Parser) enumDeclaration() stm.Statement {
+	name, err := p.consume(tokens.IDENTIFIER, "Expect enum name.")
+
+	if err != nil {
+		return stm.NewError("Expect enum name.")
+	}
+
+	p.consume(tokens.LEFT_BRACE, "Expect '{' before enum body.")
+
+	variants := make([]*stm.EnumVariant, 0)
+
+	for !p.check(tokens.RIGHT_BRACE) && !p.isAtEnd() {
+		variantName, err := p.consume(tokens.IDENTIFIER, "Expect variant name.")
+
+		if err != nil {
+			return stm.NewError("Expect variant name.")
+		}
+
+		variant := &stm.EnumVariant{Name: *variantName, Params: []tokens.Token{}}
+
+		if p.check(tokens.LEFT_PAREN) {
+			params, prologue, err := p.parameters("variant")
+
+			if err != nil {
+				return stm.NewError("Expect variant parameters.")
+			}
+
+			if len(prologue) > 0 || params.Variadic || params.Required() < len(params.Params) {
+				p.errorLogger.ErrorForToken(*variantName, "Only functions can destructure their parameters or give them default values.")
+			}
+			variant.Params = params.Params
+		}
+
+		variants = append(variants, variant)
+
+		if !p.match(tokens.COMMA) {
+			break
+		}
+	}
+
+	p.consume(tokens.RIGHT_BRACE, "Expect '}' after enum body.")
+
+	return stm.NewEnum(*name, variants)
+}
+
+// methodSignature parses "name(params);", a method without a body.
+func (p *Parser) methodSignature(kind string) *stm.FunctionStm {
+	name, err := p.consume(tokens.IDENTIFIER, fmt.Sprintf("Expect %s name\n", kind))
+
+	if err != nil {
+		return nil
+	}
+
+	parameters, prologue, err := p.parameters(kind)
+
+	if err != nil {
+		return nil
+	}
+
+	if len(prologue) > 0 || parameters.Variadic || parameters.Required() < len(parameters.Params) {
+		p.errorLogger.ErrorForToken(*name, "Only functions can destructure their parameters or give them default values.")
+	}
+
+	p.consume(tokens.SEMICOLON, fmt.Sprintf("Expect ';' after %s.", kind))
+
+	return stm.NewFunction(*name, parameters, nil)
+}
+
+// checkAccessor reports whether a class member starts with the contextual
+// keyword "get" or "set". "get()" is still an ordinary method named get.
+func (p *Parser) checkAccessor() bool {
+	if !p.check(tokens.IDENTIFIER) || p.current+1 >= len(p.tokens) {
+		return false
+	}
+
+	lexeme := p.peek().Lexeme
+
+	return (lexeme == "get" || lexeme == "set") && p.tokens[p.current+1].TokenType == tokens.IDENTIFIER
+}
+
+// accessor parses "get name { ... }" or "set name(value) { ... }". Either
+// can have a "=> expression;" body instead.
+func (p *Parser) accessor() (*stm.FunctionStm, bool) {
+	setter := p.advance().Lexeme == "set"
+	name, _ := p.consume(tokens.IDENTIFIER, "Expect property name.")
+
+	if !setter && p.match(tokens.ARROW) {
+		body := stm.NewReturn(p.previous(), p.expression())
+		p.consume(tokens.SEMICOLON, "Expect ';' after getter body.")
+
+		return stm.NewFunction(*name, stm.NewParameters([]tokens.Token{}), []stm.Statement{body}), false
+	}
+
+	if !setter {
+		_, err := p.consume(tokens.LEFT_BRACE, "Expect '{' before getter body.")
+
+		if err != nil {
+			return nil, false
+		}
+
+		return stm.NewFunction(*name, stm.NewParameters([]tokens.Token{}), p.block()), false
+	}
+
+	functionComponents, err := p.parseFunctionComponents("setter")
+
+	if err != nil {
+		return nil, true
+	}
+
+	if functionComponents.arrow {
+		p.consume(tokens.SEMICOLON, "Expect ';' after setter body.")
+	}
+
+	if len(functionComponents.parameters.Params) != 1 || functionComponents.parameters.Required() != 1 {
+		p.errorLogger.ErrorForToken(*name, "Setter must take exactly one parameter.")
+	}
+
+	return stm.NewFunction(*name, functionComponents.parameters, functionComponents.body), true
+}
+
+func (p *Parser) whileStatement() *stm.WhileStmt {
+	p.consume(tokens.LEFT_PAREN, "Expect '(' after 'while'.")
+	condition := p.expression()
+	p.consume(tokens.RIGHT_PAREN, "Expect ')' after condition.")
+	body := p.statement()
+
+	return stm.NewWhile(condition, body)
+}
+
+func (p *Parser) ifStatement() *stm.IfStmt {
+	p.consume(tokens.LEFT_PAREN, "Expect '(' after 'if'.")
+	condition := p.expression()
+	p.consume(tokens.RIGHT_PAREN, "Expect ')' after if condition.")
+
+	thenBranch := p.statement()
+	var elseBranch stm.Statement = nil
+
+	if p.match(tokens.ELSE) {
+		elseBranch = p.statement()
+	}
+
+	return stm.NewIf(condition, thenBranch, elseBranch)
+}
+
+func (p *Parser) breakStatement() *stm.BreakStmt {
+	p.consume(tokens.SEMICOLON, "Expect ';' after break.\n")
+
+	return stm.NewBreak()
+}
+
+func (p *Parser) functionStatement(kind string) stm.Statement {
+	generator := p.match(tokens.STAR)
+
+	name, err := p.consume(tokens.IDENTIFIER, fmt.Sprintf("Expect %s name\n", kind))
+
+	if err != nil {
+		return stm.NewError("error creating function statement")
+	}
+
+	functionComponents, err := p.parseFunctionComponents(kind)
+
+	if err != nil {
+		return &stm.ErrorStmt{}
+	}
+
+	if functionComponents.arrow {
+		p.consume(tokens.SEMICOLON, fmt.Sprintf("Expect ';' after %s body.", kind))
+	}
+
+	function := stm.NewFunction(*name, functionComponents.parameters, functionComponents.body)
+	function.Generator = generator
+
+	return function
+}
+
+func (p *Parser) asyncFunction() stm.Statement {
+	p.consume(tokens.FUN, "Expect 'fun' after 'async'.")
+	function := p.functionStatement("function")
+
+	if declaration, ok := function.(*stm.FunctionStm); ok {
+		declaration.Async = true
+	}
+
+	return function
+}
+
+func (p *Parser) yieldStatement() stm.Statement {
+	keyword := p.previous()
+	value := p.expression()
+	p.consume(tokens.SEMICOLON, "Expect ';' after yielded value.")
+
+	return stm.NewYield(keyword, value)
+}
+
+func (p *Parser) spawnStatement() stm.Statement {
+	keyword := p.previous()
+	call, ok := p.call().(*stm.Call)
+
+	if !ok {
+		p.errorLogger.ErrorForToken(keyword, "Expect a call after 'spawn'.")
+		return stm.NewError("Expect a call after 'spawn'.")
+	}
+
+	p.consume(tokens.SEMICOLON, "Expect ';' after spawned call.")
+
+	return stm.NewSpawn(keyword, call)
+}
+
+func (p *Parser) selectStatement() stm.Statement {
+	keyword := p.previous()
+	p.consume(tokens.LEFT_BRACE, "Expect '{' after 'select'.")
+
+	cases := make([]*stm.SelectCase, 0)
+	var defaultCase stm.Statement
+
+	for !p.check(tokens.RIGHT_BRACE) && !p.isAtEnd() {
+		if p.match(tokens.DEFAULT) {
+			if defaultCase != nil {
+				p.errorLogger.ErrorForToken(p.previous(), "A select can only have one default case.")
+			}
+
+			p.consume(tokens.ARROW, "Expect '=>' after 'default'.")
+			defaultCase = p.statement()
+			continue
+		}
+
+		caseKeyword, err := p.consume(tokens.CASE, "Expect 'case' or 'default' in select.")
+
+		if err != nil {
+			return stm.NewError("Expect 'case' or 'default' in select.")
+		}
+
+		selectCase := p.selectCase(*caseKeyword)
+
+		if selectCase == nil {
+			return stm.NewError("Expect a channel's send() or recv() after 'case'.")
+		}
+
+		cases = append(cases, selectCase)
+	}
+
+	p.consume(tokens.RIGHT_BRACE, "Expect '}' after select cases.")
+
+	if len(cases) == 0 {
+		p.errorLogger.ErrorForToken(keyword, "A select needs at least one case.")
+	}
+
+	return stm.NewSelect(keyword, cases, defaultCase)
+}
+
+// selectCase parses "[name =] channel.recv() => body" or
+// "channel.send(value) => body".
+func (p *Parser) selectCase(keyword tokens.Token) *stm.SelectCase {
+	var name *tokens.Token
+
+	if p.check(tokens.IDENTIFIER) && p.current+1 < len(p.tokens) && p.tokens[p.current+1].TokenType == tokens.EQUAL {
+		variable := p.advance()
+		name = &variable
+		p.advance()
+	}
+
+	selectCase := &stm.SelectCase{Keyword: keyword, Name: name}
+
+	call, ok := p.call().(*stm.Call)
+	var get *stm.Get
+
+	if ok {
+		get, ok = call.Callee.(*stm.Get)
+	}
+
+	switch {
+	case ok && get.Name.Lexeme == "recv" && len(call.Arguments) == 0:
+		selectCase.Channel = get.Object
+	case ok && get.Name.Lexeme == "send" && len(call.Arguments) == 1 && name == nil:
+		selectCase.Channel = get.Object
+		selectCase.Value = call.Arguments[0]
+	default:
+		p.errorLogger.ErrorForToken(keyword, "Expect a channel's send() or recv() after 'case'.")
+		return nil
 	}
 
-	return p.expressionStatement()
+	p.consume(tokens.ARROW, "Expect '=>' after select case.")
+	selectCase.Body = p.statement()
+
+	return selectCase
 }
 
-func (p *Parser) forStatement() stm.Statement {
-	p.consume(tokens.LEFT_PAREN, "Expect '(' after 'for'.")
+// matchCases parses "(subject) { case pattern when guard => ... }". The cases
+// of a match statement end in a statement, those of a match expression in an
+// expression and a ';'.
+func (p *Parser) matchCases(statement bool) (stm.Expression, []*stm.MatchCase) {
+	p.consume(tokens.LEFT_PAREN, "Expect '(' after 'match'.")
+	subject := p.expression()
+	p.consume(tokens.RIGHT_PAREN, "Expect ')' after matched value.")
+	p.consume(tokens.LEFT_BRACE, "Expect '{' before match cases.")
 
-	var initializer stm.Statement
-	var condition stm.Expression = nil
-	var increment stm.Expression = nil
+	cases := make([]*stm.MatchCase, 0)
 
-	if p.match(tokens.SEMICOLON) {
-		initializer = nil
-	} else if p.match(tokens.VAR) {
-		initializer = p.varDeclaration()
-	} else {
-		initializer = p.expressionStatement()
-	}
+	for !p.check(tokens.RIGHT_BRACE) && !p.isAtEnd() {
+		keyword, err := p.consume(tokens.CASE, "Expect 'case' in match.")
 
-	if !p.check(tokens.SEMICOLON) {
-		condition = p.expression()
-	}
+		if err != nil {
+			break
+		}
 
-	p.consume(tokens.SEMICOLON, "Expect ';' after loop condition.")
+		matchCase := &stm.MatchCase{Keyword: *keyword, Pattern: p.pattern()}
 
-	if !p.check(tokens.RIGHT_PAREN) {
-		increment = p.expression()
+		if p.match(tokens.WHEN) {
+			p.inGuard = true
+			matchCase.Guard = p.expression()
+			p.inGuard = false
+		}
+
+		p.consume(tokens.ARROW, "Expect '=>' after pattern.")
+
+		if statement {
+			matchCase.Body = p.statement()
+		} else {
+			matchCase.Value = p.expression()
+			p.consume(tokens.SEMICOLON, "Expect ';' after case value.")
+		}
+
+		cases = append(cases, matchCase)
 	}
 
-	p.consume(tokens.RIGHT_PAREN, "Expect ')' after for clauses.")
+	p.consume(tokens.RIGHT_BRACE, "Expect '}' after match cases.")
 
-	body := p.statement()
+	return subject, cases
+}
 
-	if increment != nil {
-		body = stm.NewBlock([]stm.Statement{body, stm.NewExpression(increment)})
+func (p *Parser) pattern() stm.Pattern {
+	if p.match(tokens.TRUE) {
+		return &stm.LiteralPattern{Token: p.previous(), Value: true}
 	}
 
-	if condition == nil {
-		condition = stm.NewLiteral(true)
+	if p.match(tokens.FALSE) {
+		return &stm.LiteralPattern{Token: p.previous(), Value: false}
 	}
 
-	body = stm.NewWhile(condition, body)
+	if p.match(tokens.NIL) {
+		return &stm.LiteralPattern{Token: p.previous(), Value: nil}
+	}
 
-	if initializer != nil {
-		body = stm.NewBlock([]stm.Statement{initializer, body})
+	if p.match(tokens.NUMBER, tokens.STRING) {
+		return &stm.LiteralPattern{Token: p.previous(), Value: p.previous().Literal}
 	}
 
-	return body
+	if p.match(tokens.MINUS) {
+		number, err := p.consume(tokens.NUMBER, "Expect number after '-' in pattern.")
 
-}
+		if err != nil {
+			return &stm.WildcardPattern{Token: p.previous()}
+		}
 
-func (p *Parser) classStatement() stm.Statement {
-	name, err := p.consume(tokens.IDENTIFIER, "Expect class name.")
-	var superClass *stm.Variable = nil
+		return &stm.LiteralPattern{Token: *number, Value: -number.Literal.(float64)}
+	}
 
-	if err != nil {
-		return stm.NewError("Expect class name.")
+	if p.match(tokens.LEFT_BRACKET) {
+		return p.listPattern()
 	}
 
-	if p.match(tokens.LESS) {
-		p.consume(tokens.IDENTIFIER, "Expect superclass name.")
-		superClass = stm.NewVariable(p.previous())
+	if p.match(tokens.LEFT_BRACE) {
+		return p.mapPattern()
 	}
 
-	p.consume(tokens.LEFT_BRACE, "Expect '{' before class body.")
+	if p.match(tokens.IDENTIFIER) {
+		name := p.previous()
 
-	methods := make([]*stm.FunctionStm, 0)
-	staticMethods := make([]*stm.FunctionStm, 0)
+		if !p.check(tokens.DOT) && !p.check(tokens.LEFT_PAREN) {
+			if name.Lexeme == "_" {
+				return &stm.WildcardPattern{Token: name}
+			}
 
-	for {
-		if p.check(tokens.RIGHT_BRACE) || p.isAtEnd() {
-			break
+			return &stm.BindingPattern{Name: name}
 		}
 
-		if p.match(tokens.CLASS) {
-			function := p.functionStatement("static method")
-			s, ok := function.(*stm.FunctionStm)
-
-			if !ok {
-				return &stm.ErrorStmt{}
-			}
-
-			staticMethods = append(staticMethods, s)
+		var value stm.Expression = stm.NewVariable(name)
 
-		} else {
-			function := p.functionStatement("method")
-			s, ok := function.(*stm.FunctionStm)
+		for p.match(tokens.DOT) {
+			property, err := p.consume(tokens.IDENTIFIER, "Expect property name after '.'.")
 
-			if !ok {
-				return &stm.ErrorStmt{}
+			if err != nil {
+				return &stm.WildcardPattern{Token: name}
 			}
 
-			methods = append(methods, s)
+			value = stm.NewGet(value, *property)
 		}
-	}
 
-	p.consume(tokens.RIGHT_BRACE, "Expect '}' after class body.")
+		if p.match(tokens.LEFT_PAREN) {
+			return p.classPattern(value)
+		}
 
-	return stm.NewClass(*name, methods, staticMethods, superClass)
+		return &stm.ValuePattern{Value: value}
+	}
+
+	p.errorLogger.ErrorForToken(p.peek(), "Expect pattern.")
 
+	return &stm.WildcardPattern{Token: p.peek()}
 }
 
-func (p *Parser) whileStatement() *stm.WhileStmt {
-	p.consume(tokens.LEFT_PAREN, "Expect '(' after 'while'.")
-	condition := p.expression()
-	p.consume(tokens.RIGHT_PAREN, "Expect ')' after condition.")
-	body := p.statement()
+// destructuringPattern parses the list or map pattern of a destructuring
+// declaration or parameter.
+func (p *Parser) destructuringPattern() stm.Pattern {
+	if p.match(tokens.LEFT_BRACKET) {
+		return p.listPattern()
+	}
 
-	return stm.NewWhile(condition, body)
+	p.consume(tokens.LEFT_BRACE, "Expect '[' or '{' to start a destructuring pattern.")
+
+	return p.mapPattern()
 }
 
-func (p *Parser) ifStatement() *stm.IfStmt {
-	p.consume(tokens.LEFT_PAREN, "Expect '(' after 'if'.")
-	condition := p.expression()
-	p.consume(tokens.RIGHT_PAREN, "Expect ')' after if condition.")
+func (p *Parser) listPattern() stm.Pattern {
+	pattern := &stm.ListPattern{Bracket: p.previous()}
 
-	thenBranch := p.statement()
-	var elseBranch stm.Statement = nil
+	if !p.check(tokens.RIGHT_BRACKET) {
+		for {
+			if p.match(tokens.ELLIPSIS) {
+				name, err := p.consume(tokens.IDENTIFIER, "Expect name after '...'.")
+
+				if err == nil && name.Lexeme == "_" {
+					pattern.Rest = &stm.WildcardPattern{Token: *name}
+				} else if err == nil {
+					pattern.Rest = &stm.BindingPattern{Name: *name}
+				}
+				break
+			}
 
-	if p.match(tokens.ELSE) {
-		elseBranch = p.statement()
-	}
+			pattern.Elements = append(pattern.Elements, p.pattern())
 
-	return stm.NewIf(condition, thenBranch, elseBranch)
-}
+			if !p.match(tokens.COMMA) {
+				break
+			}
+		}
+	}
 
-func (p *Parser) breakStatement() *stm.BreakStmt {
-	p.consume(tokens.SEMICOLON, "Expect ';' after break.\n")
+	p.consume(tokens.RIGHT_BRACKET, "Expect ']' after list pattern.")
 
-	return stm.NewBreak()
+	return pattern
 }
 
-func (p *Parser) functionStatement(kind string) stm.Statement {
+func (p *Parser) mapPattern() stm.Pattern {
+	pattern := &stm.MapPattern{Brace: p.previous()}
 
-	name, err := p.consume(tokens.IDENTIFIER, fmt.Sprintf("Expect %s name\n", kind))
+	if !p.check(tokens.RIGHT_BRACE) {
+		for {
+			if p.match(tokens.STRING, tokens.NUMBER) {
+				pattern.Keys = append(pattern.Keys, p.previous().Literal)
+				p.consume(tokens.COLON, "Expect ':' after key.")
+				pattern.Values = append(pattern.Values, p.pattern())
+			} else if name, err := p.consume(tokens.IDENTIFIER, "Expect key in map pattern."); err == nil {
+				pattern.Keys = append(pattern.Keys, name.Lexeme)
+
+				if p.match(tokens.COLON) {
+					pattern.Values = append(pattern.Values, p.pattern())
+				} else {
+					pattern.Values = append(pattern.Values, &stm.BindingPattern{Name: *name})
+				}
+			} else {
+				break
+			}
 
-	if err != nil {
-		return stm.NewError("error creating function statement")
+			if !p.match(tokens.COMMA) {
+				break
+			}
+		}
 	}
 
-	functionComponents, err := p.parseFunctionComponents(kind)
+	p.consume(tokens.RIGHT_BRACE, "Expect '}' after map pattern.")
 
-	if err != nil {
-		return &stm.ErrorStmt{}
+	return pattern
+}
+
+func (p *Parser) classPattern(class stm.Expression) stm.Pattern {
+	pattern := &stm.ClassPattern{Class: class, Paren: p.previous()}
+
+	if !p.check(tokens.RIGHT_PAREN) {
+		for {
+			if p.check(tokens.IDENTIFIER) && p.current+1 < len(p.tokens) && p.tokens[p.current+1].TokenType == tokens.COLON {
+				name := p.advance()
+				p.advance()
+				pattern.Names = append(pattern.Names, name)
+				pattern.Named = append(pattern.Named, p.pattern())
+			} else if len(pattern.Named) > 0 {
+				p.errorLogger.ErrorForToken(p.peek(), "Positional patterns must come before named ones.")
+				pattern.Positional = append(pattern.Positional, p.pattern())
+			} else {
+				pattern.Positional = append(pattern.Positional, p.pattern())
+			}
+
+			if !p.match(tokens.COMMA) {
+				break
+			}
+		}
 	}
 
-	return stm.NewFunction(*name, functionComponents.parameters, functionComponents.body)
+	p.consume(tokens.RIGHT_PAREN, "Expect ')' after class pattern.")
+
+	return pattern
 }
 
 func (p *Parser) returnStatement() *stm.ReturnStmt {
@@ -323,18 +1191,31 @@ func (p *Parser) expression() stm.Expression {
 }
 
 func (p *Parser) assignemt() stm.Expression {
+	if p.arrowAhead() {
+		return p.arrowFunction()
+	}
+
 	expr := p.ternary()
 
-	if p.match(tokens.EQUAL) {
+	if p.match(tokens.EQUAL, tokens.PLUS_EQUAL, tokens.MINUS_EQUAL, tokens.STAR_EQUAL, tokens.SLASH_EQUAL) {
 		equals := p.previous()
 		value := p.assignemt()
+		operator := compoundOperator(equals)
 
 		if v, ok := expr.(*stm.Variable); ok {
-			name := v.Name
+			assign := stm.NewAssign(v.Name, value)
+			assign.Operator = operator
 
-			return stm.NewAssign(name, value)
+			return assign
 		} else if get, ok := expr.(*stm.Get); ok {
-			return stm.NewSet(get.Object, get.Name, value)
+			set := stm.NewSet(get.Object, get.Name, value)
+			set.Operator = operator
+
+			return set
+		} else if list, ok := expr.(*stm.List); ok && operator == nil {
+			if pattern, ok := p.assignmentPattern(list); ok {
+				return stm.NewAssignPattern(pattern, value)
+			}
 		}
 		p.errorLogger.ErrorForToken(equals, "Invalid assignment target.")
 	}
@@ -342,6 +1223,57 @@ func (p *Parser) assignemt() stm.Expression {
 
 }
 
+// compoundOperator returns the operator a compound assignment such as "+="
+// applies, or nil for "=".
+func compoundOperator(equals tokens.Token) *tokens.Token {
+	operators := map[tokens.TokenType]tokens.TokenType{
+		tokens.PLUS_EQUAL:  tokens.PLUS,
+		tokens.MINUS_EQUAL: tokens.MINUS,
+		tokens.STAR_EQUAL:  tokens.STAR,
+		tokens.SLASH_EQUAL: tokens.SLASH,
+	}
+
+	tokenType, ok := operators[equals.TokenType]
+
+	if !ok {
+		return nil
+	}
+
+	operator := equals
+	operator.TokenType = tokenType
+	operator.Lexeme = equals.Lexeme[:1]
+
+	return &operator
+}
+
+// assignmentPattern turns the list literal on the left of "[a, b] = value"
+// into the pattern it assigns through. Its elements must be variables or
+// nested lists of them.
+func (p *Parser) assignmentPattern(target stm.Expression) (stm.Pattern, bool) {
+	switch target := target.(type) {
+	case *stm.Variable:
+		if target.Name.Lexeme == "_" {
+			return &stm.WildcardPattern{Token: target.Name}, true
+		}
+		return &stm.BindingPattern{Name: target.Name}, true
+	case *stm.List:
+		pattern := &stm.ListPattern{Bracket: target.Bracket}
+
+		for _, element := range target.Elements {
+			elementPattern, ok := p.assignmentPattern(element)
+
+			if !ok {
+				return nil, false
+			}
+
+			pattern.Elements = append(pattern.Elements, elementPattern)
+		}
+		return pattern, true
+	}
+
+	return nil, false
+}
+
 func (p *Parser) ternary() stm.Expression {
 	expression := p.or()
 	for {
@@ -349,14 +1281,14 @@ func (p *Parser) ternary() stm.Expression {
 			break
 		}
 		operator := p.previous()
-		consequent := p.ternary()
+		consequent := p.ternaryBranch()
 		_, err := p.consume(tokens.COLON, "expected alternative expression in ternary\n")
 
 		if err != nil {
 			return stm.NewErrorExpr("Invalid ternary expression\n")
 		}
 
-		alternative := p.ternary()
+		alternative := p.ternaryBranch()
 
 		expression = stm.NewTernary(operator, expression, consequent, alternative)
 	}
@@ -364,6 +1296,16 @@ func (p *Parser) ternary() stm.Expression {
 	return expression
 }
 
+// ternaryBranch parses either branch of a ternary, which can be an arrow
+// lambda as well as another ternary.
+func (p *Parser) ternaryBranch() stm.Expression {
+	if p.arrowAhead() {
+		return p.arrowFunction()
+	}
+
+	return p.ternary()
+}
+
 func (p *Parser) or() stm.Expression {
 	expr := p.and()
 
@@ -463,10 +1405,15 @@ func (p *Parser) factor() stm.Expression {
 func (p *Parser) unary() stm.Expression {
 	if p.match(tokens.MINUS, tokens.BANG) {
 		operator := p.previous()
-		right := p.primary()
+		right := p.unary()
 		return stm.NewUnary(operator, right)
 	}
 
+	if p.match(tokens.AWAIT) {
+		keyword := p.previous()
+		return stm.NewAwait(keyword, p.unary())
+	}
+
 	return p.call()
 }
 
@@ -484,6 +1431,15 @@ func (p *Parser) call() stm.Expression {
 			}
 
 			expr = stm.NewGet(expr, *name)
+		} else if p.match(tokens.LEFT_BRACKET) {
+			index := p.expression()
+			bracket, err := p.consume(tokens.RIGHT_BRACKET, "Expect ']' after index.")
+
+			if err != nil {
+				return stm.NewErrorExpr("error")
+			}
+
+			expr = stm.NewIndex(expr, *bracket, index)
 		} else {
 			break
 		}
@@ -511,9 +1467,31 @@ func (p *Parser) anonymousFunction() stm.Expression {
 
 func (p *Parser) finishCall(expr stm.Expression) stm.Expression {
 	arguments := make([]stm.Expression, 0)
+	names := make([]tokens.Token, 0)
+	inGuard := p.inGuard
+	p.inGuard = false
+
+	defer func() {
+		p.inGuard = inGuard
+	}()
 
 	if !p.check(tokens.RIGHT_PAREN) {
 		for {
+			if p.check(tokens.IDENTIFIER) && p.current+1 < len(p.tokens) && p.tokens[p.current+1].TokenType == tokens.COLON {
+				name := p.advance()
+				p.advance()
+
+				for _, other := range names {
+					if other.Lexeme == name.Lexeme {
+						p.errorLogger.ErrorForToken(name, "Argument passed twice.")
+					}
+				}
+
+				names = append(names, name)
+			} else if len(names) > 0 {
+				p.errorLogger.ErrorForToken(p.peek(), "Positional arguments must come before named ones.")
+			}
+
 			arguments = append(arguments, p.expression())
 
 			if len(arguments) > 255 {
@@ -531,7 +1509,10 @@ func (p *Parser) finishCall(expr stm.Expression) stm.Expression {
 		return stm.NewErrorExpr("Error calling function")
 	}
 
-	return stm.NewCall(expr, *paren, arguments)
+	call := stm.NewCall(expr, *paren, arguments)
+	call.Names = names
+
+	return call
 
 }
 
@@ -573,6 +1554,16 @@ func (p *Parser) primary() stm.Expression {
 		return stm.NewVariable(p.previous())
 	}
 
+	if p.match(tokens.LEFT_BRACKET) {
+		return p.listLiteral()
+	}
+
+	if p.match(tokens.MATCH) {
+		keyword := p.previous()
+		subject, cases := p.matchCases(false)
+		return stm.NewMatch(keyword, subject, cases)
+	}
+
 	if p.match(tokens.LEFT_PAREN) {
 		expr := p.expression()
 		_, err := p.consume(tokens.RIGHT_PAREN, "Expect ')' after expression.\n")
@@ -587,6 +1578,29 @@ func (p *Parser) primary() stm.Expression {
 
 }
 
+func (p *Parser) listLiteral() stm.Expression {
+	bracket := p.previous()
+	elements := make([]stm.Expression, 0)
+
+	if !p.check(tokens.RIGHT_BRACKET) {
+		for {
+			elements = append(elements, p.expression())
+
+			if !p.match(tokens.COMMA) {
+				break
+			}
+		}
+	}
+
+	_, err := p.consume(tokens.RIGHT_BRACKET, "Expect ']' after list elements.")
+
+	if err != nil {
+		return stm.NewErrorExpr("Expect ']' after list elements.")
+	}
+
+	return stm.NewList(bracket, elements)
+}
+
 func (p *Parser) match(tokenTypes ...tokens.TokenType) bool {
 	for _, token := range tokenTypes {
 		if p.check(token) {
@@ -643,7 +1657,7 @@ func (p *Parser) synchronize() {
 			return
 		}
 		switch p.peek().TokenType {
-		case tokens.CLASS, tokens.FUN, tokens.VAR, tokens.FOR, tokens.IF, tokens.WHILE, tokens.PRINT, tokens.RETURN:
+		case tokens.CLASS, tokens.FUN, tokens.VAR, tokens.FOR, tokens.IF, tokens.WHILE, tokens.PRINT, tokens.RETURN, tokens.IMPORT, tokens.FROM, tokens.EXPORT, tokens.TRAIT, tokens.INTERFACE, tokens.YIELD, tokens.SPAWN, tokens.SELECT, tokens.ASYNC, tokens.MATCH, tokens.ENUM, tokens.CONST, tokens.LET:
 			return
 		}
 		p.advance()
@@ -651,20 +1665,126 @@ func (p *Parser) synchronize() {
 }
 
 func (p *Parser) parseFunctionComponents(kind string) (*FunctionComponents, error) {
-	parameters := make([]tokens.Token, 0)
+	parameters, prologue, err := p.parameters(kind)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if p.match(tokens.ARROW) {
+		body := stm.NewReturn(p.previous(), p.expression())
+		components := NewFunctionComponents(parameters, append(prologue, body))
+		components.arrow = true
+
+		return components, nil
+	}
+
+	p.consume(tokens.LEFT_BRACE, fmt.Sprintf("Expect { before %s body\n", kind))
+	body := p.block()
+
+	return NewFunctionComponents(parameters, append(prologue, body...)), nil
+}
+
+// arrowBody parses what follows the "=>" of a lambda: a block, or an
+// expression the lambda returns.
+func (p *Parser) arrowBody() []stm.Statement {
+	arrow := p.previous()
+
+	if p.match(tokens.LEFT_BRACE) {
+		return p.block()
+	}
+
+	return []stm.Statement{stm.NewReturn(arrow, p.expression())}
+}
+
+// arrowAhead reports whether an arrow lambda starts at the current token: a
+// name or a parenthesized parameter list followed by "=>".
+func (p *Parser) arrowAhead() bool {
+	if p.inGuard {
+		return false
+	}
+
+	if p.check(tokens.IDENTIFIER) {
+		return p.current+1 < len(p.tokens) && p.tokens[p.current+1].TokenType == tokens.ARROW
+	}
+
+	if !p.check(tokens.LEFT_PAREN) {
+		return false
+	}
+
+	closer, ok := p.closers[p.current]
+
+	return ok && closer+1 < len(p.tokens) && p.tokens[closer+1].TokenType == tokens.ARROW
+}
+
+// arrowFunction parses "name => body" or "(params) => body".
+func (p *Parser) arrowFunction() stm.Expression {
+	var parameters stm.Parameters
+	var prologue []stm.Statement
+
+	if p.check(tokens.IDENTIFIER) {
+		parameters = stm.NewParameters([]tokens.Token{p.advance()})
+	} else {
+		var err error
+		parameters, prologue, err = p.parameters("lambda")
+
+		if err != nil {
+			return stm.NewErrorExpr("error building lambda")
+		}
+	}
+
+	p.consume(tokens.ARROW, "Expect '=>' after lambda parameters.")
+
+	return stm.NewAnonymousFunction(parameters, append(prologue, p.arrowBody()...))
+}
+
+// parameters parses a parameter list. A parameter written as a list or map
+// pattern gets a hidden name, and the returned prologue destructures it at
+// the start of the body. "name = value" gives a parameter a default value
+// and "...name" collects the remaining arguments.
+func (p *Parser) parameters(kind string) (stm.Parameters, []stm.Statement, error) {
+	parameters := stm.NewParameters(make([]tokens.Token, 0))
+	prologue := make([]stm.Statement, 0)
 	p.consume(tokens.LEFT_PAREN, fmt.Sprintf("Expect ( after %s name \n", kind))
 
 	if !p.check(tokens.RIGHT_PAREN) {
 		for {
-			param, err := p.consume(tokens.IDENTIFIER, "Expect parameter name.\n")
+			if parameters.Variadic {
+				p.errorLogger.ErrorForToken(p.peek(), "The rest parameter must be the last one.")
+			}
 
-			if err != nil {
-				return nil, errors.New("error building function")
+			var param tokens.Token
+
+			if p.check(tokens.LEFT_BRACKET) || p.check(tokens.LEFT_BRACE) {
+				pattern := p.destructuringPattern()
+				param = p.previous()
+				param.TokenType = tokens.IDENTIFIER
+				param.Lexeme = fmt.Sprintf("(parameter %d)", len(parameters.Params))
+
+				prologue = append(prologue, stm.NewVarPattern(pattern, stm.NewVariable(param)))
+			} else {
+				parameters.Variadic = p.match(tokens.ELLIPSIS)
+				name, err := p.consume(tokens.IDENTIFIER, "Expect parameter name.\n")
+
+				if err != nil {
+					return stm.Parameters{}, nil, errors.New("error building function")
+				}
+
+				param = *name
+			}
+
+			var value stm.Expression
+
+			if !parameters.Variadic && p.match(tokens.EQUAL) {
+				value = p.expression()
+			} else if len(parameters.Params) > parameters.Required() && !parameters.Variadic {
+				p.errorLogger.ErrorForToken(param, "A parameter without a default value can't follow one with a default value.")
 			}
 
-			parameters = append(parameters, *param)
+			parameters.Params = append(parameters.Params, param)
+			parameters.Defaults = append(parameters.Defaults, value)
 
-			if len(parameters) > 255 {
+			if len(parameters.Params) > 255 {
 				p.errorLogger.ErrorForToken(p.peek(), "Can't have more than 255 arguments.\n")
 			}
 
@@ -674,8 +1794,6 @@ func (p *Parser) parseFunctionComponents(kind string) (*FunctionComponents, erro
 		}
 	}
 	p.consume(tokens.RIGHT_PAREN, "Expect ')' after arguments.")
-	p.consume(tokens.LEFT_BRACE, fmt.Sprintf("Expect { before %s body\n", kind))
-	body := p.block()
 
-	return NewFunctionComponents(parameters, body), nil
+	return parameters, prologue, nil
 }
@@ -2,15 +2,16 @@ package parser
 
 import (
 	stm "lox/statement"
-	"lox/tokens"
 )
 
 type FunctionComponents struct {
-	parameters []tokens.Token
+	parameters stm.Parameters
 	body       []stm.Statement
+	// arrow is set when the body is written "=> expression".
+	arrow bool
 }
 
-func NewFunctionComponents(parameters []tokens.Token, body []stm.Statement) *FunctionComponents {
+func NewFunctionComponents(parameters stm.Parameters, body []stm.Statement) *FunctionComponents {
 	return &FunctionComponents{
 		parameters: parameters,
 		body:       body,
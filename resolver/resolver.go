@@ -1,10 +1,13 @@
 package resolver
 
 import (
+	"fmt"
 	"lox/interfaces"
 	"lox/interpreter"
 	stm "lox/statement"
 	"lox/tokens"
+	"sort"
+	"strings"
 )
 
 type FunctionType int
@@ -23,11 +26,36 @@ const (
 	NONE_CLASS ClassType = iota
 	CLASS
 	SUBCLASS
+	TRAIT
 )
 
 type LocalVariable struct {
-	index   int
-	defined bool
+	index    int
+	frame    int
+	defined  bool
+	constant bool
+}
+
+// frameSlots counts the slots of one runtime frame: the top-level script, a
+// function body, or the frame holding "super" for a subclass's methods.
+type frameSlots struct {
+	count int
+}
+
+// arities maps method names to the range of arguments the methods take.
+type arities map[string]interpreter.Arity
+
+// classMembers are the instance properties a class declares, including the
+// inherited ones. methods and abstract map the concrete and the still
+// unimplemented abstract methods to the arguments they take. complete is
+// false when the superclass or a trait wasn't declared in the same file,
+// since then its members are unknown.
+type classMembers struct {
+	names     map[string]bool
+	methods   arities
+	abstract  arities
+	hasFields bool
+	complete  bool
 }
 
 type Resolver struct {
@@ -36,9 +64,25 @@ type Resolver struct {
 	ErrorLogger     interfaces.ErrorLogger
 	currentFunction FunctionType
 	currentClass    ClassType
-	localIndex      int
-	thisIndex       int
-	superIndex      int
+	frames          []*frameSlots
+	classes         map[string]*classMembers
+	traits          map[string]arities
+	interfaces      map[string]arities
+	enums           map[string][]string
+	members         *classMembers
+	privates        []map[string]string
+	// constants are the global variables declared with "const", lets those
+	// declared with "let", and globals every global name declared so far.
+	constants map[string]bool
+	lets      map[string]bool
+	globals   map[string]bool
+	// static is set inside static methods, accessors and field initializers,
+	// including the functions nested in them, where this is the class.
+	static bool
+	// generator and async are set in the body of a generator or an async
+	// function, but not in the functions nested in it.
+	generator bool
+	async     bool
 }
 
 func NewResolver(interpreter *interpreter.Interpreter, errorLogger interfaces.ErrorLogger) *Resolver {
@@ -47,9 +91,14 @@ func NewResolver(interpreter *interpreter.Interpreter, errorLogger interfaces.Er
 		ErrorLogger:     errorLogger,
 		currentFunction: NONE,
 		currentClass:    NONE_CLASS,
-		localIndex:      0,
-		thisIndex:       -1,
-		superIndex:      -1,
+		frames:          []*frameSlots{{}},
+		classes:         make(map[string]*classMembers),
+		traits:          make(map[string]arities),
+		interfaces:      make(map[string]arities),
+		enums:           make(map[string][]string),
+		constants:       make(map[string]bool),
+		lets:            make(map[string]bool),
+		globals:         make(map[string]bool),
 	}
 }
 
@@ -60,17 +109,105 @@ func (r *Resolver) ResolveBlock(statements []stm.Statement) {
 }
 
 func (r *Resolver) resolveLocal(expr stm.Expression, name tokens.Token) {
+	variable, ok := r.lookup(name.Lexeme)
+
+	if ok {
+		r.Interpreter.Resolve(name, r.depth(variable), variable.index)
+	}
+}
+
+func (r *Resolver) lookup(name string) (*LocalVariable, bool) {
 	for i := len(r.Scopes) - 1; i >= 0; i-- {
-		variable, ok := r.Scopes[i][name.Lexeme]
+		variable, ok := r.Scopes[i][name]
 
 		if ok {
-			r.Interpreter.Resolve(name, len(r.Scopes)-1-i, variable.index)
-			return
+			return variable, true
+		}
+	}
+	return nil, false
+}
+
+// depth is the number of frames between the current code and the frame
+// holding variable.
+// resolveTraits resolves the traits a class includes and reports methods that
+// two of them provide when the class doesn't override them. Traits declared
+// in another file are only checked when the class is created.
+func (r *Resolver) resolveTraits(stmt *stm.ClassStmt) {
+	declared := make(map[string]bool)
+
+	for _, method := range stmt.Methods {
+		declared[method.Name.Lexeme] = true
+	}
+
+	providers := make(map[string]string)
+	included := make(map[string]bool)
+
+	for _, trait := range stmt.Traits {
+		r.resolveExpr(trait)
+
+		if included[trait.Name.Lexeme] {
+			r.ErrorLogger.ErrorForToken(trait.Name, "Trait is already included in this class.")
+			continue
+		}
+		included[trait.Name.Lexeme] = true
+
+		for _, name := range sortedNames(r.traits[trait.Name.Lexeme]) {
+			if provider, ok := providers[name]; ok && !declared[name] {
+				r.ErrorLogger.ErrorForToken(trait.Name, fmt.Sprintf("Method \"%s\" is provided by both %s and %s. Override it in the class.", name, provider, trait.Name.Lexeme))
+			}
+			providers[name] = trait.Name.Lexeme
+		}
+	}
+}
+
+// checkInterfaces reports the methods of the interfaces a class implements
+// that it lacks or declares with another arity. Abstract methods count as
+// declared, so an abstract class can leave them to its subclasses.
+func (r *Resolver) checkInterfaces(stmt *stm.ClassStmt, members *classMembers) {
+	implemented := make(map[string]bool)
+
+	for _, iface := range stmt.Interfaces {
+		r.resolveExpr(iface)
+
+		if implemented[iface.Name.Lexeme] {
+			r.ErrorLogger.ErrorForToken(iface.Name, "Interface is already implemented by this class.")
+			continue
 		}
+		implemented[iface.Name.Lexeme] = true
+
+		required := r.interfaces[iface.Name.Lexeme]
 
+		for _, name := range sortedNames(required) {
+			arity, ok := members.methods[name]
+
+			if !ok {
+				arity, ok = members.abstract[name]
+			}
+
+			if !ok && members.complete {
+				r.ErrorLogger.ErrorForToken(iface.Name, fmt.Sprintf("Class %s does not implement method \"%s\" of %s.", stmt.Name.Lexeme, name, iface.Name.Lexeme))
+			} else if ok && !arity.Covers(required[name]) {
+				r.ErrorLogger.ErrorForToken(iface.Name, fmt.Sprintf("Method \"%s\" must take %s parameters to implement %s.", name, required[name], iface.Name.Lexeme))
+			}
+		}
 	}
 }
 
+func sortedNames[V any](names map[string]V) []string {
+	sorted := make([]string, 0, len(names))
+
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	return sorted
+}
+
+func (r *Resolver) depth(variable *LocalVariable) int {
+	return len(r.frames) - 1 - variable.frame
+}
+
 func (r *Resolver) resolveStm(statement stm.Statement) {
 	statement.Accept(r)
 }
@@ -81,43 +218,76 @@ func (r *Resolver) resolveExpr(expression stm.Expression) {
 
 func (r *Resolver) resolveFunction(function *stm.FunctionStm, funcType FunctionType) {
 	enclosingFunc := r.currentFunction
+	enclosingStatic := r.static
+	enclosingGenerator, enclosingAsync := r.generator, r.async
 	r.currentFunction = funcType
+	r.generator = function.Generator
+	r.async = function.Async
 
-	r.beginScope()
+	if function.Generator && funcType == INITIALIZER {
+		r.ErrorLogger.ErrorForToken(function.Name, "An initializer can't be a generator.")
+	}
 
-	for _, token := range function.Params {
-		r.declare(token)
-		r.define(token)
-		r.Interpreter.Resolve(token, 0, r.localIndex-1)
-		r.Interpreter.LocalVariables = append(r.Interpreter.LocalVariables, nil)
+	if function.Async && funcType == INITIALIZER {
+		r.ErrorLogger.ErrorForToken(function.Name, "An initializer can't be async.")
+	}
+
+	if function.Async && function.Generator {
+		r.ErrorLogger.ErrorForToken(function.Name, "A function can't be both async and a generator.")
+	}
+
+	if funcType == METHOD || funcType == INITIALIZER || funcType == STATIC_METHOD {
+		r.static = funcType == STATIC_METHOD
 	}
 
-	if funcType == METHOD || funcType == INITIALIZER {
-		function.ThisIndex = r.thisIndex
+	r.beginFrame()
+
+	// Methods get their receiver in the first slot of their frame.
+	if funcType == METHOD || funcType == INITIALIZER || funcType == STATIC_METHOD {
+		r.declareHidden("this")
 	}
 
+	r.resolveParameters(function.Parameters)
 	r.ResolveBlock(function.Body)
-	r.endScope()
+	function.Slots = r.endFrame()
 
 	r.currentFunction = enclosingFunc
+	r.static = enclosingStatic
+	r.generator, r.async = enclosingGenerator, enclosingAsync
 }
 
 func (r *Resolver) resolveAnonymousFunction(function *stm.AnonymousFunction) {
 	enclosingFunc := r.currentFunction
+	enclosingGenerator, enclosingAsync := r.generator, r.async
 	r.currentFunction = ANONYMOUS_FUNCTION
+	r.generator, r.async = false, false
 
-	r.beginScope()
-
-	for _, token := range function.Params {
-		r.declare(token)
-		r.define(token)
-	}
+	r.beginFrame()
 
+	r.resolveParameters(function.Parameters)
 	r.ResolveBlock(function.Body)
 
-	r.endScope()
+	function.Slots = r.endFrame()
 
 	r.currentFunction = enclosingFunc
+	r.generator, r.async = enclosingGenerator, enclosingAsync
+}
+
+// resolveParameters gives the parameters consecutive slots, then resolves
+// their default values in order. A default value can read the parameters
+// before its own.
+func (r *Resolver) resolveParameters(parameters stm.Parameters) {
+	for _, token := range parameters.Params {
+		r.declare(token)
+	}
+
+	for index, token := range parameters.Params {
+		if parameters.Defaults[index] != nil {
+			r.resolveExpr(parameters.Defaults[index])
+		}
+
+		r.define(token)
+	}
 }
 
 func (r *Resolver) beginScope() {
@@ -128,8 +298,50 @@ func (r *Resolver) endScope() {
 	r.Scopes = r.Scopes[:len((r.Scopes))-1]
 }
 
+func (r *Resolver) beginFrame() {
+	r.frames = append(r.frames, &frameSlots{})
+	r.beginScope()
+}
+
+func (r *Resolver) endFrame() int {
+	r.endScope()
+	frame := r.frames[len(r.frames)-1]
+	r.frames = r.frames[:len(r.frames)-1]
+	return frame.count
+}
+
+func (r *Resolver) allocateSlot() (int, int) {
+	level := len(r.frames) - 1
+	frame := r.frames[level]
+	index := frame.count
+	frame.count++
+
+	if level == 0 {
+		r.Interpreter.ReserveSlots(frame.count)
+	}
+
+	return index, level
+}
+
+// declareHidden declares a defined variable the user can't write themselves,
+// such as "this" or "super".
+func (r *Resolver) declareHidden(name string) {
+	index, frame := r.allocateSlot()
+
+	r.Scopes[len(r.Scopes)-1][name] = &LocalVariable{
+		index:   index,
+		frame:   frame,
+		defined: true,
+	}
+}
+
 func (r *Resolver) declare(name tokens.Token) {
+	if stm.IsPrivate(name.Lexeme) {
+		r.ErrorLogger.ErrorForToken(name, "Private names can only be used for class members.")
+	}
+
 	if len(r.Scopes) == 0 {
+		r.globals[name.Lexeme] = true
 		return
 	}
 	scope := r.Scopes[len(r.Scopes)-1]
@@ -140,13 +352,14 @@ func (r *Resolver) declare(name tokens.Token) {
 		r.ErrorLogger.ErrorForToken(name, "Already variable with this name in this scope.")
 	}
 
+	index, frame := r.allocateSlot()
+
 	scope[name.Lexeme] = &LocalVariable{
-		index:   r.localIndex,
+		index:   index,
+		frame:   frame,
 		defined: false,
 	}
 
-	r.localIndex++
-
 }
 func (r *Resolver) define(name tokens.Token) {
 	if len(r.Scopes) == 0 {
@@ -156,7 +369,6 @@ func (r *Resolver) define(name tokens.Token) {
 	scope[name.Lexeme].defined = true
 
 	r.Interpreter.Resolve(name, 0, scope[name.Lexeme].index)
-	r.Interpreter.LocalVariables = append(r.Interpreter.LocalVariables, nil)
 }
 
 // VisitAnonymousFuncExpr implements stm.ExprVisitor.
@@ -166,14 +378,67 @@ func (r *Resolver) VisitAnonymousFuncExpr(expr *stm.AnonymousFunction) any {
 	return nil
 }
 
+// declareConstant marks the variable name was just declared as a constant.
+func (r *Resolver) declareConstant(name tokens.Token) {
+	if len(r.Scopes) == 0 {
+		r.constants[name.Lexeme] = true
+		return
+	}
+
+	r.Scopes[len(r.Scopes)-1][name.Lexeme].constant = true
+}
+
+// checkRedeclaration reports a global declaration reusing the name of a
+// global constant or let binding, and a let binding reusing any global name.
+// Locals can't be declared twice in one scope anyway.
+func (r *Resolver) checkRedeclaration(name tokens.Token, let bool) {
+	if len(r.Scopes) > 0 {
+		return
+	}
+
+	if r.constants[name.Lexeme] {
+		r.ErrorLogger.ErrorForToken(name, fmt.Sprintf("Can't redeclare constant %s.", name.Lexeme))
+	} else if r.lets[name.Lexeme] || (let && r.globals[name.Lexeme]) {
+		r.ErrorLogger.ErrorForToken(name, fmt.Sprintf("Can't redeclare %s.", name.Lexeme))
+	}
+
+	if let {
+		r.lets[name.Lexeme] = true
+	}
+}
+
+// checkAssignment reports an assignment to the variable name if it is a
+// constant.
+func (r *Resolver) checkAssignment(name tokens.Token) {
+	variable, ok := r.lookup(name.Lexeme)
+
+	if (ok && variable.constant) || (!ok && r.constants[name.Lexeme]) {
+		r.ErrorLogger.ErrorForToken(name, fmt.Sprintf("Can't assign to constant %s.", name.Lexeme))
+	}
+}
+
 // VisitAssignExpr implements stm.ExprVisitor.
 func (r *Resolver) VisitAssignExpr(expr *stm.Assign) any {
 	r.resolveExpr(expr.Value)
+	r.checkAssignment(expr.Name)
 	r.resolveLocal(expr, expr.Name)
 
 	return nil
 }
 
+// VisitAssignPatternExpr implements stm.ExprVisitor.
+func (r *Resolver) VisitAssignPatternExpr(expr *stm.AssignPattern) any {
+	r.resolveExpr(expr.Value)
+	r.checkDestructuring(expr.Pattern)
+
+	for _, name := range stm.Bindings(expr.Pattern) {
+		r.checkAssignment(name)
+		r.resolveLocal(expr, name)
+	}
+
+	return nil
+}
+
 // VisitBinaryExpr implements stm.ExprVisitor.
 func (r *Resolver) VisitBinaryExpr(expr *stm.Binary) any {
 	r.resolveExpr(expr.Left)
@@ -193,20 +458,202 @@ func (r *Resolver) VisitCallExpr(expr *stm.Call) any {
 	return nil
 }
 
+// VisitListExpr implements stm.ExprVisitor.
+func (r *Resolver) VisitListExpr(expr *stm.List) any {
+	for _, element := range expr.Elements {
+		r.resolveExpr(element)
+	}
+	return nil
+}
+
+// VisitIndexExpr implements stm.ExprVisitor.
+func (r *Resolver) VisitIndexExpr(expr *stm.Index) any {
+	r.resolveExpr(expr.Object)
+	r.resolveExpr(expr.Index)
+	return nil
+}
+
+// VisitMatchExpr implements stm.ExprVisitor.
+func (r *Resolver) VisitMatchExpr(expr *stm.Match) any {
+	r.resolveExpr(expr.Subject)
+	r.resolveCases(expr.Keyword, expr.Cases, true)
+	return nil
+}
+
+// VisitAwaitExpr implements stm.ExprVisitor.
+func (r *Resolver) VisitAwaitExpr(expr *stm.Await) any {
+	if !r.async {
+		r.ErrorLogger.ErrorForToken(expr.Keyword, "Can't use 'await' outside of an async function.")
+	}
+
+	r.resolveExpr(expr.Value)
+	return nil
+}
+
 func (r *Resolver) VisitGetExpr(expr *stm.Get) any {
 	r.resolveExpr(expr.Object)
+	r.resolvePrivate(&expr.Name)
+	r.checkProperty(expr.Object, expr.Name)
 	return nil
 }
 
 func (r *Resolver) VisitSetExpr(expr *stm.Set) any {
 	r.resolveExpr(expr.Object)
 	r.resolveExpr(expr.Value)
+	r.resolvePrivate(&expr.Name)
+	r.checkProperty(expr.Object, expr.Name)
 	return nil
 }
 
+// resolvePrivate replaces a private name with the key of the innermost
+// enclosing class that declares it. Code outside that class can't name the
+// member at all.
+func (r *Resolver) resolvePrivate(name *tokens.Token) {
+	if !stm.IsPrivate(name.Lexeme) {
+		return
+	}
+
+	for i := len(r.privates) - 1; i >= 0; i-- {
+		if key, ok := r.privates[i][name.Lexeme]; ok {
+			name.Lexeme = key
+			return
+		}
+	}
+
+	r.ErrorLogger.ErrorForToken(*name, fmt.Sprintf("Private name %s is not declared in an enclosing class.", name.Lexeme))
+}
+
+// declarePrivates renames the private members of stmt to their keys and
+// returns the keys by name.
+func (r *Resolver) declarePrivates(stmt *stm.ClassStmt) map[string]string {
+	keys := make(map[string]string)
+
+	rename := func(name *tokens.Token) {
+		if stm.IsPrivate(name.Lexeme) {
+			key := stm.PrivateKey(name.Lexeme, stmt.Name)
+			keys[name.Lexeme] = key
+			name.Lexeme = key
+		}
+	}
+
+	for _, field := range append(stmt.Fields, stmt.StaticFields...) {
+		rename(&field.Name)
+	}
+
+	for _, functions := range [][]*stm.FunctionStm{stmt.Methods, stmt.StaticMethods, stmt.Accessors()} {
+		for _, function := range functions {
+			rename(&function.Name)
+		}
+	}
+
+	return keys
+}
+
+// checkProperty warns about "this.name" when the class declares its fields
+// but none of its fields, methods or accessors is called name.
+func (r *Resolver) checkProperty(object stm.Expression, name tokens.Token) {
+	if _, ok := object.(*stm.This); !ok || r.members == nil || r.static || stm.IsPrivate(name.Lexeme) {
+		return
+	}
+
+	if r.members.hasFields && r.members.complete && !r.members.names[name.Lexeme] {
+		r.ErrorLogger.Warning(name, fmt.Sprintf("Class has no field, method or accessor named \"%s\".", name.Lexeme))
+	}
+}
+
+func (r *Resolver) declareMembers(stmt *stm.ClassStmt) *classMembers {
+	members := &classMembers{
+		names:     make(map[string]bool),
+		methods:   make(arities),
+		abstract:  make(arities),
+		hasFields: len(stmt.Fields) > 0,
+		complete:  true,
+	}
+
+	if stmt.SuperClass != nil {
+		super, ok := r.classes[stmt.SuperClass.Name.Lexeme]
+
+		if ok {
+			for name := range super.names {
+				members.names[name] = true
+			}
+
+			for name, arity := range super.methods {
+				members.methods[name] = arity
+			}
+
+			for name, arity := range super.abstract {
+				members.abstract[name] = arity
+			}
+			members.hasFields = members.hasFields || super.hasFields
+			members.complete = super.complete
+		} else {
+			members.complete = false
+		}
+	}
+
+	declared := make(map[string]bool)
+
+	for _, field := range stmt.Fields {
+		if declared[field.Name.Lexeme] {
+			r.ErrorLogger.ErrorForToken(field.Name, "Already a field with this name in this class.")
+		}
+		declared[field.Name.Lexeme] = true
+		members.names[field.Name.Lexeme] = true
+	}
+
+	for _, functions := range [][]*stm.FunctionStm{stmt.Methods, stmt.Getters, stmt.Setters} {
+		for _, function := range functions {
+			members.names[function.Name.Lexeme] = true
+		}
+	}
+
+	for _, trait := range stmt.Traits {
+		methods, ok := r.traits[trait.Name.Lexeme]
+
+		for name, arity := range methods {
+			members.names[name] = true
+			members.methods[name] = arity
+			delete(members.abstract, name)
+		}
+		members.complete = members.complete && ok
+	}
+
+	for _, method := range stmt.Methods {
+		arity := interpreter.ParameterArity(method.Parameters)
+
+		if abstract, ok := members.abstract[method.Name.Lexeme]; ok && !arity.Covers(abstract) {
+			r.ErrorLogger.ErrorForToken(method.Name, fmt.Sprintf("Method must take %s parameters to implement the abstract method.", abstract))
+		}
+
+		delete(members.abstract, method.Name.Lexeme)
+		members.methods[method.Name.Lexeme] = arity
+	}
+
+	for _, method := range stmt.Abstract {
+		if stm.IsPrivate(method.Name.Lexeme) {
+			r.ErrorLogger.ErrorForToken(method.Name, "An abstract method can't be private.")
+		}
+
+		for _, concrete := range stmt.Methods {
+			if concrete.Name.Lexeme == method.Name.Lexeme {
+				r.ErrorLogger.ErrorForToken(method.Name, "Method is declared both abstract and with a body.")
+			}
+		}
+
+		delete(members.methods, method.Name.Lexeme)
+		members.abstract[method.Name.Lexeme] = interpreter.ParameterArity(method.Parameters)
+		members.names[method.Name.Lexeme] = true
+	}
+
+	r.classes[stmt.Name.Lexeme] = members
+
+	return members
+}
+
 func (r *Resolver) VisitThisExpr(expr *stm.This) any {
-	if r.currentClass == NONE_CLASS || r.currentFunction == STATIC_METHOD {
-		r.ErrorLogger.ErrorForToken(expr.Keyword, "Can't use 'this' outside of a class or in static method.\n")
+	if r.currentClass == NONE_CLASS {
+		r.ErrorLogger.ErrorForToken(expr.Keyword, "Can't use 'this' outside of a class.\n")
 		return nil
 	}
 
@@ -216,12 +663,22 @@ func (r *Resolver) VisitThisExpr(expr *stm.This) any {
 
 func (r *Resolver) VisitSuperExpr(expr *stm.Super) any {
 	if r.currentClass == NONE_CLASS {
-		r.ErrorLogger.ErrorForToken(expr.Keyword, "Can't use 'super' outside of a class or in static method.\n")
+		r.ErrorLogger.ErrorForToken(expr.Keyword, "Can't use 'super' outside of a class.\n")
 		return nil
+	} else if r.currentClass == TRAIT {
+		r.ErrorLogger.ErrorForToken(expr.Keyword, "Can't use 'super' in a trait.\n")
 	} else if r.currentClass != SUBCLASS {
 		r.ErrorLogger.ErrorForToken(expr.Keyword, "Can't use 'super' in a class with no superclass.\n")
 	}
-	expr.ThisIndex = r.thisIndex
+
+	if stm.IsPrivate(expr.Method.Lexeme) {
+		r.ErrorLogger.ErrorForToken(expr.Method, "Private members of a superclass can't be accessed.")
+	}
+	if this, ok := r.lookup("this"); ok {
+		expr.ThisDepth = r.depth(this)
+	}
+	expr.Static = r.static
+
 	r.resolveLocal(expr, expr.Keyword)
 	return nil
 }
@@ -269,6 +726,10 @@ func (r *Resolver) VisitUnaryExpr(expr *stm.Unary) any {
 
 // VisitVariableExpr implements stm.ExprVisitor.
 func (r *Resolver) VisitVariableExpr(expr *stm.Variable) any {
+	if stm.IsPrivate(expr.Name.Lexeme) {
+		r.ErrorLogger.ErrorForToken(expr.Name, "Private members must be accessed through an object, as in this.#name.")
+	}
+
 	if len(r.Scopes) != 0 {
 		variable, ok := r.Scopes[len(r.Scopes)-1][expr.Name.Lexeme]
 		if ok && !variable.defined {
@@ -307,6 +768,7 @@ func (r *Resolver) VisitExprStatement(stmt *stm.ExpressionStmt) any {
 
 // VisitFunctionStatement implements stm.StmVisitor.
 func (r *Resolver) VisitFunctionStatement(stmt *stm.FunctionStm) any {
+	r.checkRedeclaration(stmt.Name, false)
 	r.declare(stmt.Name)
 	r.define(stmt.Name)
 
@@ -333,6 +795,87 @@ func (r *Resolver) VisitPrintStatement(stmt *stm.PrintStmt) any {
 	return nil
 }
 
+// VisitTraitStatement implements stm.StmVisitor.
+func (r *Resolver) VisitTraitStatement(stmt *stm.TraitStmt) any {
+	enclosingClass := r.currentClass
+	enclosingMembers := r.members
+	r.currentClass = TRAIT
+	r.members = nil
+
+	r.checkRedeclaration(stmt.Name, false)
+	r.declare(stmt.Name)
+	r.define(stmt.Name)
+
+	methods := make(arities)
+
+	for _, method := range stmt.Methods {
+		if method.Name.Lexeme == "init" {
+			r.ErrorLogger.ErrorForToken(method.Name, "A trait can't declare an initializer.")
+		}
+
+		if stm.IsPrivate(method.Name.Lexeme) {
+			r.ErrorLogger.ErrorForToken(method.Name, "A trait can't declare private members.")
+		}
+
+		if _, ok := methods[method.Name.Lexeme]; ok {
+			r.ErrorLogger.ErrorForToken(method.Name, "Already a method with this name in this trait.")
+		}
+		methods[method.Name.Lexeme] = interpreter.ParameterArity(method.Parameters)
+
+		r.resolveFunction(method, METHOD)
+	}
+
+	r.traits[stmt.Name.Lexeme] = methods
+	r.currentClass = enclosingClass
+	r.members = enclosingMembers
+
+	return nil
+}
+
+// VisitInterfaceStatement implements stm.StmVisitor.
+func (r *Resolver) VisitInterfaceStatement(stmt *stm.InterfaceStmt) any {
+	r.checkRedeclaration(stmt.Name, false)
+	r.declare(stmt.Name)
+	r.define(stmt.Name)
+
+	methods := make(arities)
+
+	for _, method := range stmt.Methods {
+		if stm.IsPrivate(method.Name.Lexeme) {
+			r.ErrorLogger.ErrorForToken(method.Name, "An interface can't require private members.")
+		}
+
+		if _, ok := methods[method.Name.Lexeme]; ok {
+			r.ErrorLogger.ErrorForToken(method.Name, "Already a method with this name in this interface.")
+		}
+		methods[method.Name.Lexeme] = interpreter.ParameterArity(method.Parameters)
+	}
+
+	r.interfaces[stmt.Name.Lexeme] = methods
+
+	return nil
+}
+
+// VisitImportStatement implements stm.StmVisitor.
+func (r *Resolver) VisitImportStatement(stmt *stm.ImportStmt) any {
+	if len(r.Scopes) != 0 {
+		r.ErrorLogger.ErrorForToken(stmt.Keyword, "Imports must be at the top level.")
+	}
+
+	return nil
+}
+
+// VisitExportStatement implements stm.StmVisitor.
+func (r *Resolver) VisitExportStatement(stmt *stm.ExportStmt) any {
+	if len(r.Scopes) != 0 {
+		r.ErrorLogger.ErrorForToken(stmt.Keyword, "Can only export top-level declarations.")
+	}
+
+	r.resolveStm(stmt.Declaration)
+
+	return nil
+}
+
 // VisitReturnStatement implements stm.StmVisitor.
 func (r *Resolver) VisitReturnStatement(stmt *stm.ReturnStmt) any {
 
@@ -346,14 +889,41 @@ func (r *Resolver) VisitReturnStatement(stmt *stm.ReturnStmt) any {
 			r.ErrorLogger.ErrorForToken(stmt.Keyword, "Can't return a value from an initializer.")
 		}
 
+		if r.generator {
+			r.ErrorLogger.ErrorForToken(stmt.Keyword, "Can't return a value from a generator.")
+		}
+
 		r.resolveExpr(stmt.Value)
+
+		// The result of an async function settles its promise, so it can't
+		// be handed over to a tail call.
+		if !r.generator && !r.async && (r.currentFunction == FUNCTION || r.currentFunction == METHOD || r.currentFunction == STATIC_METHOD) {
+			r.markTailCalls(stmt.Value)
+		}
 	}
 
 	return nil
 }
 
+// markTailCalls flags the calls whose result is directly returned, so the
+// interpreter can run them without growing the stack.
+func (r *Resolver) markTailCalls(expr stm.Expression) {
+	switch e := expr.(type) {
+	case *stm.Call:
+		e.Tail = true
+	case *stm.Grouping:
+		r.markTailCalls(e.Expression)
+	case *stm.Ternary:
+		r.markTailCalls(e.Consequent)
+		r.markTailCalls(e.Alternative)
+	case *stm.Logical:
+		r.markTailCalls(e.Right)
+	}
+}
+
 // VisitVarStatement implements stm.StmVisitor.
 func (r *Resolver) VisitVarStatement(stmt *stm.VarStmt) any {
+	r.checkRedeclaration(stmt.Name, stmt.Let)
 	r.declare(stmt.Name)
 
 	if stmt.Initializer != nil {
@@ -362,6 +932,10 @@ func (r *Resolver) VisitVarStatement(stmt *stm.VarStmt) any {
 
 	r.define(stmt.Name)
 
+	if stmt.Const {
+		r.declareConstant(stmt.Name)
+	}
+
 	if len(r.Scopes) > 0 {
 		stmt.Local = true
 	}
@@ -369,6 +943,314 @@ func (r *Resolver) VisitVarStatement(stmt *stm.VarStmt) any {
 	return nil
 }
 
+// VisitVarPatternStatement implements stm.StmVisitor.
+func (r *Resolver) VisitVarPatternStatement(stmt *stm.VarPatternStmt) any {
+	r.checkDestructuring(stmt.Pattern)
+	names := stm.Bindings(stmt.Pattern)
+
+	for _, name := range names {
+		r.checkRedeclaration(name, stmt.Let)
+		r.declare(name)
+	}
+
+	r.resolveExpr(stmt.Initializer)
+
+	for _, name := range names {
+		r.define(name)
+
+		if stmt.Const {
+			r.declareConstant(name)
+		}
+	}
+
+	return nil
+}
+
+// checkDestructuring reports the parts of a destructuring pattern that
+// aren't names, wildcards, lists or maps.
+func (r *Resolver) checkDestructuring(pattern stm.Pattern) {
+	switch p := pattern.(type) {
+	case *stm.BindingPattern, *stm.WildcardPattern:
+	case *stm.ListPattern:
+		for _, element := range p.Elements {
+			r.checkDestructuring(element)
+		}
+	case *stm.MapPattern:
+		for _, value := range p.Values {
+			r.checkDestructuring(value)
+		}
+	case *stm.LiteralPattern:
+		r.ErrorLogger.ErrorForToken(p.Token, "Can only destructure into names, lists and maps.")
+	case *stm.ClassPattern:
+		r.ErrorLogger.ErrorForToken(p.Paren, "Can only destructure into names, lists and maps.")
+	case *stm.ValuePattern:
+		r.ErrorLogger.ErrorForToken(p.Value.(*stm.Get).Name, "Can only destructure into names, lists and maps.")
+	}
+}
+
+// VisitForInStatement implements stm.StmVisitor.
+func (r *Resolver) VisitForInStatement(stmt *stm.ForInStmt) any {
+	r.resolveExpr(stmt.Iterable)
+
+	r.beginScope()
+
+	for _, name := range stmt.Names {
+		r.declare(name)
+		r.define(name)
+	}
+
+	r.resolveStm(stmt.Body)
+	r.endScope()
+
+	return nil
+}
+
+// VisitYieldStatement implements stm.StmVisitor.
+func (r *Resolver) VisitYieldStatement(stmt *stm.YieldStmt) any {
+	if !r.generator {
+		r.ErrorLogger.ErrorForToken(stmt.Keyword, "Can't yield outside of a generator.")
+	}
+
+	r.resolveExpr(stmt.Value)
+
+	return nil
+}
+
+// VisitSpawnStatement implements stm.StmVisitor.
+func (r *Resolver) VisitSpawnStatement(stmt *stm.SpawnStmt) any {
+	r.resolveExpr(stmt.Call)
+	return nil
+}
+
+// VisitSelectStatement implements stm.StmVisitor.
+func (r *Resolver) VisitSelectStatement(stmt *stm.SelectStmt) any {
+	for _, selectCase := range stmt.Cases {
+		r.resolveExpr(selectCase.Channel)
+
+		if selectCase.Value != nil {
+			r.resolveExpr(selectCase.Value)
+		}
+
+		r.beginScope()
+
+		if selectCase.Name != nil {
+			r.declare(*selectCase.Name)
+			r.define(*selectCase.Name)
+		}
+
+		r.resolveStm(selectCase.Body)
+		r.endScope()
+	}
+
+	if stmt.Default != nil {
+		r.resolveStm(stmt.Default)
+	}
+
+	return nil
+}
+
+// VisitMatchStatement implements stm.StmVisitor.
+func (r *Resolver) VisitMatchStatement(stmt *stm.MatchStmt) any {
+	r.resolveExpr(stmt.Subject)
+	r.resolveCases(stmt.Keyword, stmt.Cases, false)
+	return nil
+}
+
+// resolveCases resolves each case of a match in a scope holding the names
+// its pattern binds, and warns about the cases an earlier one always
+// matches first. A match over the variants of an enum without a case
+// matching every value must cover all of them: leaving some out is an error
+// for a match expression and a warning for a match statement.
+func (r *Resolver) resolveCases(keyword tokens.Token, cases []*stm.MatchCase, expression bool) {
+	exhausted := false
+	literals := make(map[any]bool)
+	covered := make(map[string]map[string]bool)
+
+	for _, matchCase := range cases {
+		enum, variant, isVariant := r.variantCase(matchCase.Pattern)
+
+		if exhausted {
+			r.ErrorLogger.Warning(matchCase.Keyword, "Unreachable case: an earlier case matches every value.")
+		} else if literal, ok := matchCase.Pattern.(*stm.LiteralPattern); ok && literals[literal.Value] {
+			r.ErrorLogger.Warning(matchCase.Keyword, "Unreachable case: an earlier case matches the same value.")
+		} else if isVariant && covered[enum][variant] {
+			r.ErrorLogger.Warning(matchCase.Keyword, "Unreachable case: an earlier case matches the same value.")
+		}
+
+		r.beginScope()
+		r.resolvePattern(matchCase.Pattern)
+
+		if matchCase.Guard != nil {
+			r.resolveExpr(matchCase.Guard)
+		}
+
+		if matchCase.Body != nil {
+			r.resolveStm(matchCase.Body)
+		} else {
+			r.resolveExpr(matchCase.Value)
+		}
+
+		r.endScope()
+
+		if matchCase.Guard != nil {
+			continue
+		}
+
+		switch pattern := matchCase.Pattern.(type) {
+		case *stm.WildcardPattern, *stm.BindingPattern:
+			exhausted = true
+		case *stm.LiteralPattern:
+			literals[pattern.Value] = true
+		}
+
+		if isVariant {
+			if covered[enum] == nil {
+				covered[enum] = make(map[string]bool)
+			}
+			covered[enum][variant] = true
+		}
+	}
+
+	if exhausted || len(covered) != 1 {
+		return
+	}
+
+	for enum, variants := range covered {
+		missing := make([]string, 0)
+
+		for _, variant := range r.enums[enum] {
+			if !variants[variant] {
+				missing = append(missing, variant)
+			}
+		}
+
+		if len(missing) == 0 {
+			return
+		}
+
+		message := fmt.Sprintf("Match over %s doesn't cover %s.", enum, strings.Join(missing, ", "))
+
+		if expression {
+			r.ErrorLogger.ErrorForToken(keyword, message)
+		} else {
+			r.ErrorLogger.Warning(keyword, message)
+		}
+	}
+}
+
+// variantCase returns the enum and the variant a pattern names when it
+// matches every value of that variant: "Enum.Variant", or
+// "Enum.Variant(...)" with only names and wildcards inside.
+func (r *Resolver) variantCase(pattern stm.Pattern) (string, string, bool) {
+	var variant stm.Expression
+
+	switch p := pattern.(type) {
+	case *stm.ValuePattern:
+		variant = p.Value
+	case *stm.ClassPattern:
+		for _, field := range append(append([]stm.Pattern{}, p.Positional...), p.Named...) {
+			switch field.(type) {
+			case *stm.WildcardPattern, *stm.BindingPattern:
+			default:
+				return "", "", false
+			}
+		}
+		variant = p.Class
+	}
+
+	get, ok := variant.(*stm.Get)
+
+	if !ok {
+		return "", "", false
+	}
+
+	enum, ok := get.Object.(*stm.Variable)
+
+	if !ok {
+		return "", "", false
+	}
+
+	for _, name := range r.enums[enum.Name.Lexeme] {
+		if name == get.Name.Lexeme {
+			return enum.Name.Lexeme, name, true
+		}
+	}
+
+	return "", "", false
+}
+
+func (r *Resolver) resolvePattern(pattern stm.Pattern) {
+	switch p := pattern.(type) {
+	case *stm.BindingPattern:
+		r.declare(p.Name)
+		r.define(p.Name)
+	case *stm.ValuePattern:
+		r.resolveExpr(p.Value)
+	case *stm.ListPattern:
+		for _, element := range p.Elements {
+			r.resolvePattern(element)
+		}
+
+		if p.Rest != nil {
+			r.resolvePattern(p.Rest)
+		}
+	case *stm.MapPattern:
+		for _, value := range p.Values {
+			r.resolvePattern(value)
+		}
+	case *stm.ClassPattern:
+		r.resolveExpr(p.Class)
+
+		for _, positional := range p.Positional {
+			r.resolvePattern(positional)
+		}
+
+		for _, named := range p.Named {
+			r.resolvePattern(named)
+		}
+	}
+}
+
+// VisitEnumStatement implements stm.StmVisitor.
+func (r *Resolver) VisitEnumStatement(stmt *stm.EnumStmt) any {
+	r.checkRedeclaration(stmt.Name, false)
+	r.declare(stmt.Name)
+	r.define(stmt.Name)
+
+	variants := make([]string, 0, len(stmt.Variants))
+	declared := make(map[string]bool)
+
+	for _, variant := range stmt.Variants {
+		if declared[variant.Name.Lexeme] {
+			r.ErrorLogger.ErrorForToken(variant.Name, "Already a variant with this name in this enum.")
+		}
+
+		if variant.Name.Lexeme == "values" {
+			r.ErrorLogger.ErrorForToken(variant.Name, "A variant can't be named \"values\".")
+		}
+
+		params := make(map[string]bool)
+
+		for _, param := range variant.Params {
+			if params[param.Lexeme] {
+				r.ErrorLogger.ErrorForToken(param, "Already a parameter with this name in this variant.")
+			}
+
+			if param.Lexeme == "name" || param.Lexeme == "ordinal" {
+				r.ErrorLogger.ErrorForToken(param, fmt.Sprintf("A variant parameter can't be named \"%s\".", param.Lexeme))
+			}
+			params[param.Lexeme] = true
+		}
+
+		declared[variant.Name.Lexeme] = true
+		variants = append(variants, variant.Name.Lexeme)
+	}
+
+	r.enums[stmt.Name.Lexeme] = variants
+
+	return nil
+}
+
 // VisitWhileStatement implements stm.StmVisitor.
 func (r *Resolver) VisitWhileStatement(stmt *stm.WhileStmt) any {
 	r.resolveExpr(stmt.Condition)
@@ -381,6 +1263,7 @@ func (r *Resolver) VisitClassStatement(stmt *stm.ClassStmt) any {
 	enclosingClass := r.currentClass
 	r.currentClass = CLASS
 
+	r.checkRedeclaration(stmt.Name, false)
 	r.declare(stmt.Name)
 	r.define(stmt.Name)
 
@@ -388,37 +1271,54 @@ func (r *Resolver) VisitClassStatement(stmt *stm.ClassStmt) any {
 		r.ErrorLogger.ErrorForToken(stmt.SuperClass.Name, "A class can't inherit from itself.")
 	}
 
+	r.resolveTraits(stmt)
+
+	r.privates = append(r.privates, r.declarePrivates(stmt))
+	enclosingMembers := r.members
+	r.members = r.declareMembers(stmt)
+	r.checkInterfaces(stmt, r.members)
+	enclosingFunction := r.currentFunction
+	enclosingStatic := r.static
+
 	if stmt.SuperClass != nil {
 		r.currentClass = SUBCLASS
 		r.resolveExpr(stmt.SuperClass)
 
-		r.beginScope()
-
-		scope := r.Scopes[len(r.Scopes)-1]
-		scope["super"] = &LocalVariable{
-			index:   r.localIndex,
-			defined: true,
-		}
+		// Methods of a subclass close over a frame holding only "super".
+		r.beginFrame()
+		r.declareHidden("super")
+	}
 
-		r.Interpreter.LocalVariables = append(r.Interpreter.LocalVariables, nil)
-		r.superIndex = r.localIndex
-		stmt.SuperIndex = r.localIndex
-		r.localIndex++
+	// Static fields are initialized in a frame holding the class as "this".
+	r.currentFunction = STATIC_METHOD
+	r.static = true
+	r.beginFrame()
+	r.declareHidden("this")
 
+	for _, field := range stmt.StaticFields {
+		if field.Initializer != nil {
+			r.resolveExpr(field.Initializer)
+		}
 	}
 
-	r.beginScope()
+	stmt.StaticFieldSlots = r.endFrame()
 
-	scope := r.Scopes[len(r.Scopes)-1]
-	scope["this"] = &LocalVariable{
-		index:   r.localIndex,
-		defined: true,
-	}
+	// Instance fields are initialized in a frame of their own, holding the
+	// new instance as "this".
+	r.currentFunction = METHOD
+	r.static = false
+	r.beginFrame()
+	r.declareHidden("this")
 
-	r.Interpreter.LocalVariables = append(r.Interpreter.LocalVariables, nil)
+	for _, field := range stmt.Fields {
+		if field.Initializer != nil {
+			r.resolveExpr(field.Initializer)
+		}
+	}
 
-	r.thisIndex = r.localIndex
-	r.localIndex++
+	stmt.FieldSlots = r.endFrame()
+	r.currentFunction = enclosingFunction
+	r.static = enclosingStatic
 
 	for _, method := range stmt.Methods {
 		functionType := METHOD
@@ -433,13 +1333,25 @@ func (r *Resolver) VisitClassStatement(stmt *stm.ClassStmt) any {
 		r.resolveFunction(method, STATIC_METHOD)
 	}
 
-	r.endScope()
+	for _, accessor := range stmt.Getters {
+		r.resolveFunction(accessor, METHOD)
+	}
+
+	for _, accessor := range stmt.Setters {
+		r.resolveFunction(accessor, METHOD)
+	}
+
+	for _, accessor := range append(stmt.StaticGetters, stmt.StaticSetters...) {
+		r.resolveFunction(accessor, STATIC_METHOD)
+	}
 
 	if stmt.SuperClass != nil {
-		r.endScope()
+		r.endFrame()
 	}
 
 	r.currentClass = enclosingClass
+	r.members = enclosingMembers
+	r.privates = r.privates[:len(r.privates)-1]
 
 	return nil
 }
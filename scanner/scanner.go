@@ -9,6 +9,7 @@ import (
 
 type Scanner struct {
 	source      string
+	sourceId    int
 	tokens      []tokens.Token
 	start       int
 	current     int
@@ -30,23 +31,45 @@ func NewScanner(errorLogger interfaces.ErrorLogger) *Scanner {
 		line:        1,
 		errorLogger: errorLogger,
 		keywords: map[string]tokens.TokenType{
-			"and":    tokens.AND,
-			"class":  tokens.CLASS,
-			"else":   tokens.ELSE,
-			"false":  tokens.FALSE,
-			"for":    tokens.FOR,
-			"fun":    tokens.FUN,
-			"if":     tokens.IF,
-			"nil":    tokens.NIL,
-			"or":     tokens.OR,
-			"print":  tokens.PRINT,
-			"return": tokens.RETURN,
-			"super":  tokens.SUPER,
-			"this":   tokens.THIS,
-			"true":   tokens.TRUE,
-			"var":    tokens.VAR,
-			"while":  tokens.WHILE,
-			"break":  tokens.BREAK,
+			"and":        tokens.AND,
+			"class":      tokens.CLASS,
+			"else":       tokens.ELSE,
+			"false":      tokens.FALSE,
+			"for":        tokens.FOR,
+			"fun":        tokens.FUN,
+			"if":         tokens.IF,
+			"nil":        tokens.NIL,
+			"or":         tokens.OR,
+			"print":      tokens.PRINT,
+			"return":     tokens.RETURN,
+			"super":      tokens.SUPER,
+			"this":       tokens.THIS,
+			"true":       tokens.TRUE,
+			"var":        tokens.VAR,
+			"while":      tokens.WHILE,
+			"break":      tokens.BREAK,
+			"import":     tokens.IMPORT,
+			"from":       tokens.FROM,
+			"as":         tokens.AS,
+			"export":     tokens.EXPORT,
+			"trait":      tokens.TRAIT,
+			"with":       tokens.WITH,
+			"abstract":   tokens.ABSTRACT,
+			"interface":  tokens.INTERFACE,
+			"implements": tokens.IMPLEMENTS,
+			"in":         tokens.IN,
+			"yield":      tokens.YIELD,
+			"spawn":      tokens.SPAWN,
+			"select":     tokens.SELECT,
+			"case":       tokens.CASE,
+			"default":    tokens.DEFAULT,
+			"async":      tokens.ASYNC,
+			"await":      tokens.AWAIT,
+			"match":      tokens.MATCH,
+			"when":       tokens.WHEN,
+			"enum":       tokens.ENUM,
+			"const":      tokens.CONST,
+			"let":        tokens.LET,
 		},
 	}
 }
@@ -58,6 +81,7 @@ func (sc *Scanner) LoadSource(source string) {
 	sc.line = 1
 	sc.tokens = tokensInit
 	sc.source = source
+	sc.sourceId++
 }
 
 func (sc *Scanner) ScanTokens() []tokens.Token {
@@ -89,18 +113,28 @@ func (sc *Scanner) scanToken() {
 		sc.addToken(tokens.LEFT_BRACE)
 	case '}':
 		sc.addToken(tokens.RIGHT_BRACE)
+	case '[':
+		sc.addToken(tokens.LEFT_BRACKET)
+	case ']':
+		sc.addToken(tokens.RIGHT_BRACKET)
 	case ',':
 		sc.addToken(tokens.COMMA)
 	case '.':
+		if sc.peek() == '.' && sc.peekNext() == '.' {
+			sc.advance()
+			sc.advance()
+			sc.addToken(tokens.ELLIPSIS)
+			break
+		}
 		sc.addToken(tokens.DOT)
 	case '-':
-		sc.addToken(tokens.MINUS)
+		sc.addConditionalToken(sc.match('='), tokens.MINUS_EQUAL, tokens.MINUS)
 	case '+':
-		sc.addToken(tokens.PLUS)
+		sc.addConditionalToken(sc.match('='), tokens.PLUS_EQUAL, tokens.PLUS)
 	case ';':
 		sc.addToken(tokens.SEMICOLON)
 	case '*':
-		sc.addToken(tokens.STAR)
+		sc.addConditionalToken(sc.match('='), tokens.STAR_EQUAL, tokens.STAR)
 	case '?':
 		sc.addToken(tokens.QUESTION_MARK)
 	case ':':
@@ -108,6 +142,10 @@ func (sc *Scanner) scanToken() {
 	case '!':
 		sc.addConditionalToken(sc.match('='), tokens.BANG_EQUAL, tokens.BANG)
 	case '=':
+		if sc.match('>') {
+			sc.addToken(tokens.ARROW)
+			break
+		}
 		sc.addConditionalToken(sc.match('='), tokens.EQUAL_EQUAL, tokens.EQUAL)
 	case '<':
 		sc.addConditionalToken(sc.match('='), tokens.LESS_EQUAL, tokens.LESS)
@@ -127,10 +165,16 @@ func (sc *Scanner) scanToken() {
 		} else if sc.match('*') {
 			sc.multiLineComment()
 		} else {
-			sc.addToken(tokens.SLASH)
+			sc.addConditionalToken(sc.match('='), tokens.SLASH_EQUAL, tokens.SLASH)
 		}
 	case '"':
 		sc.string()
+	case '#':
+		if sc.isAlpha(sc.peek()) {
+			sc.identifier()
+		} else {
+			sc.errorLogger.Error(sc.line, "Expect name after '#'.")
+		}
 	default:
 		if unicode.IsDigit(c) {
 			sc.number()
@@ -154,7 +198,11 @@ func (sc *Scanner) addToken(tokenType tokens.TokenType) {
 func (sc *Scanner) addTokenWithLiteral(tokenType tokens.TokenType, literal interface{}) {
 	text := sc.source[sc.start:sc.current]
 
-	sc.tokens = append(sc.tokens, tokens.NewToken(tokenType, text, literal, sc.line))
+	token := tokens.NewToken(tokenType, text, literal, sc.line)
+	token.Source = sc.sourceId
+	token.Offset = sc.start
+
+	sc.tokens = append(sc.tokens, token)
 }
 
 func (sc *Scanner) match(expected rune) bool {
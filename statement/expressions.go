@@ -13,9 +13,14 @@ type ExprVisitor[T any] interface {
 	VisitTernaryExpr(expr *Ternary) T
 	VisitVariableExpr(expr *Variable) T
 	VisitAssignExpr(expr *Assign) T
+	VisitAssignPatternExpr(expr *AssignPattern) T
 	VisitLogicalExpr(expr *Logical) T
 	VisitCallExpr(expr *Call) T
 	VisitGetExpr(expr *Get) T
+	VisitIndexExpr(expr *Index) T
+	VisitAwaitExpr(expr *Await) T
+	VisitMatchExpr(expr *Match) T
+	VisitListExpr(expr *List) T
 	VisitSetExpr(expr *Set) T
 	VisitThisExpr(expr *This) T
 	VisitSuperExpr(expr *Super) T
@@ -26,6 +31,14 @@ type Expression interface {
 	Accept(visitor ExprVisitor[any]) any
 }
 
+// InlineCache remembers the outcome of the last lookup at a property access
+// or method call site. The interpreter decides what Shape and Value hold.
+type InlineCache struct {
+	Shape any
+	Slot  int
+	Value any
+}
+
 type Grouping struct {
 	Expression Expression
 }
@@ -40,9 +53,12 @@ func (g *Grouping) Accept(visitor ExprVisitor[any]) any {
 	return visitor.VisitGroupingExpr(g)
 }
 
+// Assign is "name = value". A compound assignment such as "name += value"
+// has the operator it applies, here PLUS, as Operator.
 type Assign struct {
-	Name  tokens.Token
-	Value Expression
+	Name     tokens.Token
+	Value    Expression
+	Operator *tokens.Token
 }
 
 func NewAssign(name tokens.Token, value Expression) *Assign {
@@ -88,6 +104,24 @@ func (l *Literal) Accept(visitor ExprVisitor[any]) any {
 	return visitor.VisitLiteralExpr(l)
 }
 
+// AssignPattern is "[a, b] = value", which assigns each variable the pattern
+// binds.
+type AssignPattern struct {
+	Pattern Pattern
+	Value   Expression
+}
+
+func NewAssignPattern(pattern Pattern, value Expression) *AssignPattern {
+	return &AssignPattern{
+		Pattern: pattern,
+		Value:   value,
+	}
+}
+
+func (a *AssignPattern) Accept(visitor ExprVisitor[any]) any {
+	return visitor.VisitAssignPatternExpr(a)
+}
+
 type Logical struct {
 	Left     Expression
 	Operator tokens.Token
@@ -168,10 +202,15 @@ func (v *Variable) Accept(visitor ExprVisitor[any]) any {
 	return visitor.VisitVariableExpr(v)
 }
 
+// Call is "callee(arguments)". The last len(Names) arguments are named ones,
+// "name: value".
 type Call struct {
 	Callee    Expression
 	Paren     tokens.Token
 	Arguments []Expression
+	Names     []tokens.Token
+	Tail      bool
+	Cache     InlineCache
 }
 
 func NewCall(callee Expression, paren tokens.Token, arguments []Expression) *Call {
@@ -189,6 +228,7 @@ func (c *Call) Accept(visitor ExprVisitor[any]) any {
 type Get struct {
 	Object Expression
 	Name   tokens.Token
+	Cache  InlineCache
 }
 
 func NewGet(object Expression, name tokens.Token) *Get {
@@ -202,10 +242,86 @@ func (g *Get) Accept(visitor ExprVisitor[any]) any {
 	return visitor.VisitGetExpr(g)
 }
 
+// Index is "object[index]".
+type Index struct {
+	Object  Expression
+	Bracket tokens.Token
+	Index   Expression
+}
+
+func NewIndex(object Expression, bracket tokens.Token, index Expression) *Index {
+	return &Index{
+		Object:  object,
+		Bracket: bracket,
+		Index:   index,
+	}
+}
+
+func (i *Index) Accept(visitor ExprVisitor[any]) any {
+	return visitor.VisitIndexExpr(i)
+}
+
+// Await is "await value" in the body of an async function.
+type Await struct {
+	Keyword tokens.Token
+	Value   Expression
+}
+
+func NewAwait(keyword tokens.Token, value Expression) *Await {
+	return &Await{
+		Keyword: keyword,
+		Value:   value,
+	}
+}
+
+func (a *Await) Accept(visitor ExprVisitor[any]) any {
+	return visitor.VisitAwaitExpr(a)
+}
+
+// Match evaluates to the value of the first case whose pattern matches
+// Subject and whose guard holds. It is a runtime error when none does.
+type Match struct {
+	Keyword tokens.Token
+	Subject Expression
+	Cases   []*MatchCase
+}
+
+func NewMatch(keyword tokens.Token, subject Expression, cases []*MatchCase) *Match {
+	return &Match{
+		Keyword: keyword,
+		Subject: subject,
+		Cases:   cases,
+	}
+}
+
+func (m *Match) Accept(visitor ExprVisitor[any]) any {
+	return visitor.VisitMatchExpr(m)
+}
+
+// List is a list literal, "[a, b, c]".
+type List struct {
+	Bracket  tokens.Token
+	Elements []Expression
+}
+
+func NewList(bracket tokens.Token, elements []Expression) *List {
+	return &List{
+		Bracket:  bracket,
+		Elements: elements,
+	}
+}
+
+func (l *List) Accept(visitor ExprVisitor[any]) any {
+	return visitor.VisitListExpr(l)
+}
+
+// Set is "object.name = value". Operator is set for a compound assignment,
+// as in Assign.
 type Set struct {
-	Object Expression
-	Name   tokens.Token
-	Value  Expression
+	Object   Expression
+	Name     tokens.Token
+	Value    Expression
+	Operator *tokens.Token
 }
 
 func NewSet(object Expression, name tokens.Token, value Expression) *Set {
@@ -234,17 +350,20 @@ func (t *This) Accept(visitor ExprVisitor[any]) any {
 	return visitor.VisitThisExpr(t)
 }
 
+// Super is "super.method". In a static method Static is set, and method is
+// looked up among the superclass's static members.
 type Super struct {
 	Keyword   tokens.Token
 	Method    tokens.Token
-	ThisIndex int
+	ThisDepth int
+	Static    bool
 }
 
 func NewSuper(keyword tokens.Token, method tokens.Token) *Super {
 	return &Super{
 		Keyword:   keyword,
 		Method:    method,
-		ThisIndex: -1,
+		ThisDepth: -1,
 	}
 }
 
@@ -253,14 +372,15 @@ func (s *Super) Accept(visitor ExprVisitor[any]) any {
 }
 
 type AnonymousFunction struct {
-	Params []tokens.Token
-	Body   []Statement
+	Parameters
+	Body  []Statement
+	Slots int
 }
 
-func NewAnonymousFunction(params []tokens.Token, body []Statement) *AnonymousFunction {
+func NewAnonymousFunction(parameters Parameters, body []Statement) *AnonymousFunction {
 	return &AnonymousFunction{
-		Params: params,
-		Body:   body,
+		Parameters: parameters,
+		Body:       body,
 	}
 }
 
@@ -0,0 +1,116 @@
+package stm
+
+import "lox/tokens"
+
+// Pattern is what a case of a match compares the matched value with. The
+// interpreter and the resolver switch over the pattern types below.
+type Pattern interface {
+	pattern()
+}
+
+// MatchCase is "case pattern when guard => body". Guard is optional. The
+// cases of a match statement have a Body, those of a match expression a
+// Value. The names bound by Pattern are local to the case.
+type MatchCase struct {
+	Keyword tokens.Token
+	Pattern Pattern
+	Guard   Expression
+	Body    Statement
+	Value   Expression
+}
+
+// LiteralPattern matches a value equal to a number, string, true, false or
+// nil.
+type LiteralPattern struct {
+	Token tokens.Token
+	Value any
+}
+
+// WildcardPattern is "_", which matches anything.
+type WildcardPattern struct {
+	Token tokens.Token
+}
+
+// BindingPattern is a name, which matches anything and binds it to the value.
+type BindingPattern struct {
+	Name tokens.Token
+}
+
+// ValuePattern is a dotted name like "Color.Red", which matches a value equal
+// to what the name refers to.
+type ValuePattern struct {
+	Value Expression
+}
+
+// ListPattern is "[a, b]", which matches a list with one element per pattern.
+// With "[a, b, ...rest]" the list may be longer, and Rest matches a list of
+// the remaining elements.
+type ListPattern struct {
+	Bracket  tokens.Token
+	Elements []Pattern
+	Rest     Pattern
+}
+
+// MapPattern is `{"key": pattern, name}`, which matches a map that has each of
+// the keys, or an instance that has each of them as a field. A bare name is
+// short for `"name": name`.
+type MapPattern struct {
+	Brace  tokens.Token
+	Keys   []any
+	Values []Pattern
+}
+
+// ClassPattern is "Point(x, y: 0)", which matches instances of Point and its
+// subclasses, or of the classes including a trait or implementing an
+// interface. A positional pattern must be a name, which binds the field of
+// that name; "field: pattern" matches the field against a pattern.
+type ClassPattern struct {
+	Class      Expression
+	Paren      tokens.Token
+	Positional []Pattern
+	Names      []tokens.Token
+	Named      []Pattern
+}
+
+func (*LiteralPattern) pattern()  {}
+func (*WildcardPattern) pattern() {}
+func (*BindingPattern) pattern()  {}
+func (*ValuePattern) pattern()    {}
+func (*ListPattern) pattern()     {}
+func (*MapPattern) pattern()      {}
+func (*ClassPattern) pattern()    {}
+
+// Bindings returns the names a pattern binds, in order.
+func Bindings(pattern Pattern) []tokens.Token {
+	switch p := pattern.(type) {
+	case *BindingPattern:
+		return []tokens.Token{p.Name}
+	case *ListPattern:
+		names := make([]tokens.Token, 0)
+
+		for _, element := range p.Elements {
+			names = append(names, Bindings(element)...)
+		}
+
+		if p.Rest != nil {
+			names = append(names, Bindings(p.Rest)...)
+		}
+		return names
+	case *MapPattern:
+		names := make([]tokens.Token, 0)
+
+		for _, value := range p.Values {
+			names = append(names, Bindings(value)...)
+		}
+		return names
+	case *ClassPattern:
+		names := make([]tokens.Token, 0)
+
+		for _, field := range append(append([]Pattern{}, p.Positional...), p.Named...) {
+			names = append(names, Bindings(field)...)
+		}
+		return names
+	}
+
+	return nil
+}
@@ -1,21 +1,34 @@
 package stm
 
 import (
+	"fmt"
 	"lox/tokens"
+	"strings"
 )
 
 type StmVisitor[T any] interface {
 	VisitExprStatement(stmt *ExpressionStmt) T
 	VisitPrintStatement(stmt *PrintStmt) T
 	VisitVarStatement(stmt *VarStmt) T
+	VisitVarPatternStatement(stmt *VarPatternStmt) T
 	VisitErrorStatement(stmt *ErrorStmt) T
 	VisitBlockStatement(stmt *BlockStmt) T
 	VisitIfStatement(stmt *IfStmt) T
 	VisitWhileStatement(stmt *WhileStmt) T
+	VisitForInStatement(stmt *ForInStmt) T
+	VisitYieldStatement(stmt *YieldStmt) T
+	VisitSpawnStatement(stmt *SpawnStmt) T
+	VisitSelectStatement(stmt *SelectStmt) T
+	VisitMatchStatement(stmt *MatchStmt) T
 	VisitBreakStatement(stmt *BreakStmt) T
 	VisitFunctionStatement(stmt *FunctionStm) T
 	VisitReturnStatement(stmt *ReturnStmt) T
 	VisitClassStatement(stmt *ClassStmt) T
+	VisitImportStatement(stmt *ImportStmt) T
+	VisitExportStatement(stmt *ExportStmt) T
+	VisitTraitStatement(stmt *TraitStmt) T
+	VisitInterfaceStatement(stmt *InterfaceStmt) T
+	VisitEnumStatement(stmt *EnumStmt) T
 }
 
 type Statement interface {
@@ -84,10 +97,36 @@ func (w *WhileStmt) Accept(visitor StmVisitor[any]) any {
 	return visitor.VisitWhileStatement(w)
 }
 
+// ForInStmt is "for (name in iterable) body". With several names each
+// element is a list that gets unpacked into them.
+type ForInStmt struct {
+	Keyword  tokens.Token
+	Names    []tokens.Token
+	Iterable Expression
+	Body     Statement
+}
+
+func NewForIn(keyword tokens.Token, names []tokens.Token, iterable Expression, body Statement) *ForInStmt {
+	return &ForInStmt{
+		Keyword:  keyword,
+		Names:    names,
+		Iterable: iterable,
+		Body:     body,
+	}
+}
+
+func (f *ForInStmt) Accept(visitor StmVisitor[any]) any {
+	return visitor.VisitForInStatement(f)
+}
+
+// VarStmt is "var name = value;", or "const name = value;" when Const is
+// set, which can't be assigned to afterwards.
 type VarStmt struct {
 	Name        tokens.Token
 	Initializer Expression
 	Local       bool
+	Const       bool
+	Let         bool
 }
 
 func NewVar(name tokens.Token, expr Expression) *VarStmt {
@@ -102,6 +141,26 @@ func (v *VarStmt) Accept(visitior StmVisitor[any]) any {
 	return visitior.VisitVarStatement(v)
 }
 
+// VarPatternStmt is "var [a, b] = value;" or "var {x, y} = value;", which
+// declares each name the pattern binds. With "const" the names are Const.
+type VarPatternStmt struct {
+	Pattern     Pattern
+	Initializer Expression
+	Const       bool
+	Let         bool
+}
+
+func NewVarPattern(pattern Pattern, initializer Expression) *VarPatternStmt {
+	return &VarPatternStmt{
+		Pattern:     pattern,
+		Initializer: initializer,
+	}
+}
+
+func (v *VarPatternStmt) Accept(visitor StmVisitor[any]) any {
+	return visitor.VisitVarPatternStatement(v)
+}
+
 type BlockStmt struct {
 	Statements []Statement
 }
@@ -127,19 +186,50 @@ func (b *BreakStmt) Accept(visitor StmVisitor[any]) any {
 	return visitor.VisitBreakStatement(b)
 }
 
+// FunctionStm is a function or method declaration. Generator is set for
+// "fun* name()" and "*name()", whose body runs one yield at a time. Async is
+// set for "async fun name()" and "async name()", which return a promise.
+// Parameters are the parameters of a function. Defaults holds the default
+// value of each parameter, nil for the required ones, which come first. When
+// Variadic is set, the last parameter receives the remaining arguments as a
+// list.
+type Parameters struct {
+	Params   []tokens.Token
+	Defaults []Expression
+	Variadic bool
+}
+
+func NewParameters(params []tokens.Token) Parameters {
+	return Parameters{
+		Params:   params,
+		Defaults: make([]Expression, len(params)),
+	}
+}
+
+// Required returns the number of parameters without a default value.
+func (p Parameters) Required() int {
+	for index, value := range p.Defaults {
+		if value != nil || p.Variadic && index == len(p.Params)-1 {
+			return index
+		}
+	}
+	return len(p.Params)
+}
+
 type FunctionStm struct {
-	Name      tokens.Token
-	Params    []tokens.Token
+	Name tokens.Token
+	Parameters
 	Body      []Statement
-	ThisIndex int
+	Slots     int
+	Generator bool
+	Async     bool
 }
 
-func NewFunction(name tokens.Token, params []tokens.Token, body []Statement) *FunctionStm {
+func NewFunction(name tokens.Token, parameters Parameters, body []Statement) *FunctionStm {
 	return &FunctionStm{
-		Name:      name,
-		Params:    params,
-		Body:      body,
-		ThisIndex: -1,
+		Name:       name,
+		Parameters: parameters,
+		Body:       body,
 	}
 }
 
@@ -147,6 +237,93 @@ func (f *FunctionStm) Accept(visitor StmVisitor[any]) any {
 	return visitor.VisitFunctionStatement(f)
 }
 
+// SpawnStmt is "spawn f(args);". The callee and arguments are evaluated
+// right away, and the call runs on a new fiber.
+type SpawnStmt struct {
+	Keyword tokens.Token
+	Call    *Call
+}
+
+func NewSpawn(keyword tokens.Token, call *Call) *SpawnStmt {
+	return &SpawnStmt{
+		Keyword: keyword,
+		Call:    call,
+	}
+}
+
+func (s *SpawnStmt) Accept(visitor StmVisitor[any]) any {
+	return visitor.VisitSpawnStatement(s)
+}
+
+// SelectStmt waits until one of its cases can send or receive, and runs that
+// case. With a Default it doesn't wait, and runs Default when no case is
+// ready.
+type SelectStmt struct {
+	Keyword tokens.Token
+	Cases   []*SelectCase
+	Default Statement
+}
+
+// SelectCase is "case channel.send(value) => body" or
+// "case name = channel.recv() => body", where Name is optional and Value is
+// nil for a recv. Name is local to Body.
+type SelectCase struct {
+	Keyword tokens.Token
+	Name    *tokens.Token
+	Channel Expression
+	Value   Expression
+	Body    Statement
+}
+
+func NewSelect(keyword tokens.Token, cases []*SelectCase, defaultCase Statement) *SelectStmt {
+	return &SelectStmt{
+		Keyword: keyword,
+		Cases:   cases,
+		Default: defaultCase,
+	}
+}
+
+func (s *SelectStmt) Accept(visitor StmVisitor[any]) any {
+	return visitor.VisitSelectStatement(s)
+}
+
+// MatchStmt runs the body of the first case whose pattern matches Subject
+// and whose guard holds. Nothing runs when no case matches.
+type MatchStmt struct {
+	Keyword tokens.Token
+	Subject Expression
+	Cases   []*MatchCase
+}
+
+func NewMatchStmt(keyword tokens.Token, subject Expression, cases []*MatchCase) *MatchStmt {
+	return &MatchStmt{
+		Keyword: keyword,
+		Subject: subject,
+		Cases:   cases,
+	}
+}
+
+func (m *MatchStmt) Accept(visitor StmVisitor[any]) any {
+	return visitor.VisitMatchStatement(m)
+}
+
+// YieldStmt is "yield value;" in the body of a generator.
+type YieldStmt struct {
+	Keyword tokens.Token
+	Value   Expression
+}
+
+func NewYield(keyword tokens.Token, value Expression) *YieldStmt {
+	return &YieldStmt{
+		Keyword: keyword,
+		Value:   value,
+	}
+}
+
+func (y *YieldStmt) Accept(visitor StmVisitor[any]) any {
+	return visitor.VisitYieldStatement(y)
+}
+
 type ErrorStmt struct {
 	Message string
 }
@@ -177,12 +354,24 @@ func (r *ReturnStmt) Accept(visitor StmVisitor[any]) any {
 	return visitor.VisitReturnStatement(r)
 }
 
+// ClassStmt is a class declaration. Abstract holds the methods declared with
+// "abstract", which have no body.
 type ClassStmt struct {
-	Name          tokens.Token
-	Methods       []*FunctionStm
-	StaticMethods []*FunctionStm
-	SuperClass    *Variable
-	SuperIndex    int
+	Name             tokens.Token
+	Methods          []*FunctionStm
+	Abstract         []*FunctionStm
+	StaticMethods    []*FunctionStm
+	Getters          []*FunctionStm
+	Setters          []*FunctionStm
+	StaticGetters    []*FunctionStm
+	StaticSetters    []*FunctionStm
+	Fields           []*VarStmt
+	StaticFields     []*VarStmt
+	FieldSlots       int
+	StaticFieldSlots int
+	SuperClass       *Variable
+	Traits           []*Variable
+	Interfaces       []*Variable
 }
 
 func NewClass(name tokens.Token, methods []*FunctionStm, staticMethods []*FunctionStm, superClass *Variable) *ClassStmt {
@@ -191,10 +380,155 @@ func NewClass(name tokens.Token, methods []*FunctionStm, staticMethods []*Functi
 		Methods:       methods,
 		StaticMethods: staticMethods,
 		SuperClass:    superClass,
-		SuperIndex:    -1,
 	}
 }
 
 func (c *ClassStmt) Accept(visitor StmVisitor[any]) any {
 	return visitor.VisitClassStatement(c)
 }
+
+// Accessors returns the getters and setters, static ones last.
+func (c *ClassStmt) Accessors() []*FunctionStm {
+	accessors := make([]*FunctionStm, 0, len(c.Getters)+len(c.Setters)+len(c.StaticGetters)+len(c.StaticSetters))
+	accessors = append(accessors, c.Getters...)
+	accessors = append(accessors, c.Setters...)
+	accessors = append(accessors, c.StaticGetters...)
+	return append(accessors, c.StaticSetters...)
+}
+
+// TraitStmt is "trait Name { methods }". Classes include it with "with Name",
+// which copies its methods into theirs.
+type TraitStmt struct {
+	Name    tokens.Token
+	Methods []*FunctionStm
+}
+
+func NewTrait(name tokens.Token, methods []*FunctionStm) *TraitStmt {
+	return &TraitStmt{
+		Name:    name,
+		Methods: methods,
+	}
+}
+
+func (t *TraitStmt) Accept(visitor StmVisitor[any]) any {
+	return visitor.VisitTraitStatement(t)
+}
+
+// InterfaceStmt is "interface Name { method(params); }". Its methods have no
+// body; only their names and arities matter.
+type InterfaceStmt struct {
+	Name    tokens.Token
+	Methods []*FunctionStm
+}
+
+func NewInterface(name tokens.Token, methods []*FunctionStm) *InterfaceStmt {
+	return &InterfaceStmt{
+		Name:    name,
+		Methods: methods,
+	}
+}
+
+func (i *InterfaceStmt) Accept(visitor StmVisitor[any]) any {
+	return visitor.VisitInterfaceStatement(i)
+}
+
+// EnumStmt is "enum Name { A, B(x, y) }". A variant without parameters is a
+// single value; one with parameters constructs a value from its arguments.
+type EnumStmt struct {
+	Name     tokens.Token
+	Variants []*EnumVariant
+}
+
+type EnumVariant struct {
+	Name   tokens.Token
+	Params []tokens.Token
+}
+
+func NewEnum(name tokens.Token, variants []*EnumVariant) *EnumStmt {
+	return &EnumStmt{
+		Name:     name,
+		Variants: variants,
+	}
+}
+
+func (e *EnumStmt) Accept(visitor StmVisitor[any]) any {
+	return visitor.VisitEnumStatement(e)
+}
+
+// ImportStmt is either "import "path" as Alias;" or
+// "from "path" import Names;".
+type ImportStmt struct {
+	Keyword tokens.Token
+	Path    string
+	Alias   *tokens.Token
+	Names   []tokens.Token
+}
+
+func NewImport(keyword tokens.Token, path string, alias *tokens.Token, names []tokens.Token) *ImportStmt {
+	return &ImportStmt{
+		Keyword: keyword,
+		Path:    path,
+		Alias:   alias,
+		Names:   names,
+	}
+}
+
+func (i *ImportStmt) Accept(visitor StmVisitor[any]) any {
+	return visitor.VisitImportStatement(i)
+}
+
+type ExportStmt struct {
+	Keyword     tokens.Token
+	Declaration Statement
+}
+
+func NewExport(keyword tokens.Token, declaration Statement) *ExportStmt {
+	return &ExportStmt{
+		Keyword:     keyword,
+		Declaration: declaration,
+	}
+}
+
+func (e *ExportStmt) Accept(visitor StmVisitor[any]) any {
+	return visitor.VisitExportStatement(e)
+}
+
+func IsPrivate(name string) bool {
+	return strings.HasPrefix(name, "#")
+}
+
+// PrivateKey is the name the private member "#name" of the class declared at
+// owner is stored under. Classes using the same private name, such as a class
+// and its subclass, each get their own member.
+func PrivateKey(name string, owner tokens.Token) string {
+	return fmt.Sprintf("%s@%d:%d", name, owner.Source, owner.Offset)
+}
+
+// MemberName returns the name of a member as written in the source, without
+// what PrivateKey added.
+func MemberName(key string) string {
+	name, _, _ := strings.Cut(key, "@")
+	return name
+}
+
+// DeclaredNames returns the names a var, fun, class, trait, interface or
+// enum declaration binds.
+func DeclaredNames(stmt Statement) []tokens.Token {
+	switch declaration := stmt.(type) {
+	case *VarStmt:
+		return []tokens.Token{declaration.Name}
+	case *VarPatternStmt:
+		return Bindings(declaration.Pattern)
+	case *FunctionStm:
+		return []tokens.Token{declaration.Name}
+	case *ClassStmt:
+		return []tokens.Token{declaration.Name}
+	case *TraitStmt:
+		return []tokens.Token{declaration.Name}
+	case *InterfaceStmt:
+		return []tokens.Token{declaration.Name}
+	case *EnumStmt:
+		return []tokens.Token{declaration.Name}
+	}
+	return nil
+}
@@ -0,0 +1,2 @@
+package example
+require strings libs/strings
@@ -4,11 +4,16 @@ import (
 	"fmt"
 )
 
+// Source and Offset tell apart tokens with the same text on the same line,
+// including tokens of different files, so resolved variables can be keyed by
+// token.
 type Token struct {
 	TokenType TokenType
 	Lexeme    string
 	Literal   interface{}
 	Line      int
+	Source    int
+	Offset    int
 }
 
 func NewToken(tokenType TokenType, lexeme string, literal interface{}, line int) Token {
@@ -8,6 +8,8 @@ const (
 	RIGHT_PAREN
 	LEFT_BRACE
 	RIGHT_BRACE
+	LEFT_BRACKET
+	RIGHT_BRACKET
 	COMMA
 	DOT
 	MINUS
@@ -27,6 +29,12 @@ const (
 	GREATER_EQUAL
 	LESS
 	LESS_EQUAL
+	ARROW
+	ELLIPSIS
+	PLUS_EQUAL
+	MINUS_EQUAL
+	STAR_EQUAL
+	SLASH_EQUAL
 
 	// Literals.
 	IDENTIFIER
@@ -51,6 +59,28 @@ const (
 	VAR
 	WHILE
 	BREAK
+	IMPORT
+	FROM
+	AS
+	EXPORT
+	TRAIT
+	WITH
+	ABSTRACT
+	INTERFACE
+	IMPLEMENTS
+	IN
+	YIELD
+	SPAWN
+	SELECT
+	CASE
+	DEFAULT
+	ASYNC
+	AWAIT
+	MATCH
+	WHEN
+	ENUM
+	CONST
+	LET
 
 	EOF
 )